@@ -0,0 +1,270 @@
+// Package client is a typed gRPC client of sso for other services.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+
+	"github.com/google/uuid"
+	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+// idempotencyKeyHeader must match the header read by the Register handler.
+const idempotencyKeyHeader = "idempotency-key"
+
+// ErrNoApp is returned by ValidateToken when client was created without
+// WithApp.
+var ErrNoApp = errors.New("client: app is not configured, see WithApp")
+
+// Client calls sso Auth service.
+type Client struct {
+	conn   *grpc.ClientConn
+	api    ssov1.AuthClient
+	app    *models.App
+	issuer string
+}
+
+// Claims are verified claims of an access token.
+type Claims struct {
+	UserID    int64
+	Email     string
+	AppID     int
+	ExpiresAt time.Time
+}
+
+type options struct {
+	tls         *tls.Config
+	retries     uint
+	backoff     time.Duration
+	app         *models.App
+	appKeys     []models.AppKey
+	publicKey   string
+	issuer      string
+	dialOptions []grpc.DialOption
+}
+
+// Option configures Client.
+type Option func(*options)
+
+// WithTLS enables TLS. Without it connection is plaintext.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tls = cfg
+	}
+}
+
+// WithRetries sets how many times calls failing with Unavailable or
+// Aborted are retried (default 3) and linear backoff between attempts
+// (default 100ms).
+func WithRetries(retries uint, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithApp sets app whose secret is used by ValidateToken and issuer the
+// tokens are expected to have (auth.issuer of the server).
+func WithApp(id int, name, secret, issuer string) Option {
+	return func(o *options) {
+		o.app = &models.App{ID: id, Name: name, Secret: secret}
+		o.issuer = issuer
+	}
+}
+
+// WithAppKey adds signing key of app set by WithApp, so ValidateToken
+// accepts tokens whose kid header is kid. Repeat it for every key still
+// verifying tokens, including retired ones within their grace period.
+func WithAppKey(kid, secret string) Option {
+	return func(o *options) {
+		o.appKeys = append(o.appKeys, models.AppKey{ID: kid, Secret: secret})
+	}
+}
+
+// WithAppPublicKey sets PEM-encoded public key of app set by WithApp, so
+// ValidateToken accepts ES256 tokens. It's printed by manage-app --key-pair.
+func WithAppPublicKey(pemKey string) Option {
+	return func(o *options) {
+		o.publicKey = pemKey
+	}
+}
+
+// WithDialOptions adds raw gRPC dial options, e.g. a custom dialer.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// New creates client of sso listening on addr. Connection is established
+// lazily on first call.
+func New(addr string, opts ...Option) (*Client, error) {
+	const op = "client.New"
+
+	o := options{
+		retries: 3,
+		backoff: 100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.app != nil {
+		o.app.Keys = o.appKeys
+		o.app.PublicKey = o.publicKey
+	}
+
+	creds := insecure.NewCredentials()
+	if o.tls != nil {
+		creds = credentials.NewTLS(o.tls)
+	}
+
+	dialOptions := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(
+			grpcretry.UnaryClientInterceptor(
+				grpcretry.WithMax(o.retries),
+				grpcretry.WithBackoff(grpcretry.BackoffLinear(o.backoff)),
+				grpcretry.WithCodes(codes.Unavailable, codes.Aborted),
+			),
+			gzipInterceptor,
+		),
+	}, o.dialOptions...)
+
+	conn, err := grpc.NewClient(addr, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Client{
+		conn:   conn,
+		api:    ssov1.NewAuthClient(conn),
+		app:    o.app,
+		issuer: o.issuer,
+	}, nil
+}
+
+type gzipKey struct{}
+
+// UseGzip makes calls with returned ctx send gzip-compressed requests; a
+// server with grpc.enable_gzip then compresses its responses too.
+func UseGzip(ctx context.Context) context.Context {
+	return context.WithValue(ctx, gzipKey{}, true)
+}
+
+// gzipInterceptor compresses calls made with ctx from UseGzip.
+func gzipInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if on, _ := ctx.Value(gzipKey{}).(bool); on {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// Close closes connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Login returns access token for user of app.
+func (c *Client) Login(ctx context.Context, email, password string, appID int) (string, error) {
+	const op = "client.Login"
+
+	resp, err := c.api.Login(ctx, &ssov1.LoginRequest{
+		Email:    email,
+		Password: password,
+		AppId:    int32(appID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.GetToken(), nil
+}
+
+// Register creates user and returns its id. Retries carry the same
+// idempotency key, so a retried call doesn't fail with AlreadyExists.
+func (c *Client) Register(ctx context.Context, email, password string) (int64, error) {
+	const op = "client.Register"
+
+	ctx = metadata.AppendToOutgoingContext(ctx, idempotencyKeyHeader, uuid.NewString())
+
+	resp, err := c.api.Register(ctx, &ssov1.RegisterRequest{
+		Email:    email,
+		Password: password,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.GetUserId(), nil
+}
+
+// IsAdmin reports whether user is admin.
+func (c *Client) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	const op = "client.IsAdmin"
+
+	resp, err := c.api.IsAdmin(ctx, &ssov1.IsAdminRequest{UserId: userID})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp.GetIsAdmin(), nil
+}
+
+// ValidateToken verifies access token locally with keys of app set by
+// WithApp: signature, expiry, issuer and audience. Revocation can't be
+// checked this way.
+//
+// Tokens without kid are verified with the app secret. Tokens with kid need
+// the key passed with WithAppKey and ES256 tokens need WithAppPublicKey;
+// otherwise they fail with jwt.ErrTokenInvalid naming the missing key.
+func (c *Client) ValidateToken(token string) (Claims, error) {
+	const op = "client.ValidateToken"
+
+	if c.app == nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, ErrNoApp)
+	}
+
+	var parseOpts []jwt.ParseOption
+	if c.issuer != "" {
+		parseOpts = append(parseOpts, jwt.WithIssuer(c.issuer))
+	}
+
+	claims, err := jwt.ParseToken(token, *c.app, parseOpts...)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	return Claims{
+		UserID:    claims.UID,
+		Email:     claims.Email,
+		AppID:     claims.AppID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
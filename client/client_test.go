@@ -0,0 +1,200 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"sso/client"
+	"sso/internal/domain/models"
+	authgrpc "sso/internal/grpc/auth"
+	"sso/internal/lib/hasher"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/ratelimit"
+	"sso/internal/services/auth"
+	"sso/internal/storage/memory"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testIssuer   = "sso-test"
+	testEmail    = "user@example.com"
+	testPassword = "Secret123"
+)
+
+type nopAudit struct{}
+
+func (nopAudit) Record(context.Context, models.AuditEvent) {}
+
+// startServer serves Auth backed by store over an in-memory listener and
+// returns dial option connecting to it.
+func startServer(t *testing.T, store *memory.Storage) grpc.DialOption {
+	t.Helper()
+
+	svc := auth.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		ratelimit.NewSlidingWindow(100, time.Minute),
+		nopAudit{},
+		hasher.New(hasher.NewBcrypt(bcrypt.MinCost)),
+		jwt.StorageKeys{},
+		auth.Config{AccessTokenTTL: time.Hour, Issuer: testIssuer},
+	)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	authgrpc.Register(srv, svc)
+
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	})
+}
+
+// login registers test user through c and returns its token for appID.
+func login(t *testing.T, c *client.Client, appID int) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if _, err := c.Register(ctx, testEmail, testPassword); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	token, err := c.Login(ctx, testEmail, testPassword, appID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	return token
+}
+
+func newClient(t *testing.T, dialer grpc.DialOption, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	c, err := client.New("passthrough:///bufnet", append(opts, client.WithDialOptions(dialer))...)
+	if err != nil {
+		t.Fatalf("client.New: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+func TestValidateTokenLegacySecret(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	c := newClient(t, startServer(t, store), client.WithApp(appID, "web", "web-secret", testIssuer))
+	token := login(t, c, appID)
+
+	claims, err := c.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Email != testEmail || claims.AppID != appID {
+		t.Errorf("claims = %+v, want email %q and app %d", claims, testEmail, appID)
+	}
+}
+
+func TestValidateTokenWithKid(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	key := models.AppKey{ID: "k1", Secret: "k1-secret", Active: true, CreatedAt: time.Now()}
+	if err := store.SaveAppKey(ctx, appID, key); err != nil {
+		t.Fatalf("SaveAppKey: %v", err)
+	}
+
+	dialer := startServer(t, store)
+
+	withKey := newClient(t, dialer,
+		client.WithApp(appID, "web", "web-secret", testIssuer),
+		client.WithAppKey(key.ID, key.Secret),
+	)
+	token := login(t, withKey, appID)
+
+	if _, err := withKey.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken with WithAppKey: %v", err)
+	}
+
+	secretOnly := newClient(t, dialer, client.WithApp(appID, "web", "web-secret", testIssuer))
+
+	_, err = secretOnly.ValidateToken(token)
+	if !errors.Is(err, jwt.ErrTokenInvalid) || !strings.Contains(err.Error(), `unknown kid "k1"`) {
+		t.Fatalf("ValidateToken without WithAppKey: got %v, want ErrTokenInvalid naming kid", err)
+	}
+}
+
+func TestValidateTokenES256(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	privateKey, publicKey, err := jwt.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	if err := store.SetAppKeyPair(ctx, appID, privateKey, publicKey); err != nil {
+		t.Fatalf("SetAppKeyPair: %v", err)
+	}
+
+	dialer := startServer(t, store)
+
+	withPublicKey := newClient(t, dialer,
+		client.WithApp(appID, "web", "web-secret", testIssuer),
+		client.WithAppPublicKey(publicKey),
+	)
+	token := login(t, withPublicKey, appID)
+
+	if _, err := withPublicKey.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken with WithAppPublicKey: %v", err)
+	}
+
+	secretOnly := newClient(t, dialer, client.WithApp(appID, "web", "web-secret", testIssuer))
+
+	_, err = secretOnly.ValidateToken(token)
+	if !errors.Is(err, jwt.ErrTokenInvalid) || !strings.Contains(err.Error(), "app has no public key") {
+		t.Fatalf("ValidateToken without WithAppPublicKey: got %v, want ErrTokenInvalid naming public key", err)
+	}
+}
+
+func TestValidateTokenWithoutApp(t *testing.T) {
+	c := newClient(t, startServer(t, memory.New()))
+
+	if _, err := c.ValidateToken("token"); !errors.Is(err, client.ErrNoApp) {
+		t.Fatalf("ValidateToken: got %v, want ErrNoApp", err)
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// compressorOf runs gzipInterceptor with ctx and returns compressor the
+// call was made with.
+func compressorOf(t *testing.T, ctx context.Context) string {
+	t.Helper()
+
+	var compressor string
+
+	err := gzipInterceptor(ctx, "/auth.Auth/Login", nil, nil, nil,
+		func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+			for _, o := range opts {
+				if c, ok := o.(grpc.CompressorCallOption); ok {
+					compressor = c.CompressorType
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("gzipInterceptor: %v", err)
+	}
+
+	return compressor
+}
+
+func TestUseGzip(t *testing.T) {
+	if got := compressorOf(t, UseGzip(context.Background())); got != gzip.Name {
+		t.Errorf("call with UseGzip compressed with %q, want %q", got, gzip.Name)
+	}
+	if got := compressorOf(t, context.Background()); got != "" {
+		t.Errorf("call without UseGzip compressed with %q, want none", got)
+	}
+}
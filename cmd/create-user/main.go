@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/lib/hasher"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"sso/internal/storage/backend"
+	"sso/internal/storage/migrate"
+)
+
+// create-user inserts user directly through the storage layer, e.g. to
+// bootstrap the first admin. Config is loaded the same way as for sso, and
+// the password must satisfy the same policy as on register.
+func main() {
+	var email, password string
+	var isAdmin bool
+
+	flag.StringVar(&email, "email", "", "user email")
+	flag.StringVar(&password, "password", "", "user password")
+	flag.BoolVar(&isAdmin, "admin", false, "grant admin rights")
+
+	// Parses the flags above together with --config.
+	cfg := config.MustLoad()
+
+	if email == "" {
+		panic("email is required")
+	}
+	if password == "" {
+		panic("password is required")
+	}
+
+	store, err := backend.New(cfg.Storage.Driver, cfg.StoragePath, storage.PoolConfig{
+		MaxOpenConns:    cfg.Storage.MaxOpenConns,
+		MaxIdleConns:    cfg.Storage.MaxIdleConns,
+		ConnMaxLifetime: cfg.Storage.ConnMaxLifetime,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer store.Stop()
+
+	if cfg.MigrationsPath != "" {
+		if _, err := migrate.Up(cfg.Storage.Driver, cfg.StoragePath, cfg.MigrationsPath, migrate.DefaultTable); err != nil {
+			panic(err)
+		}
+	}
+
+	id, err := createUser(context.Background(), store, cfg.Auth, email, password, isAdmin)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("user created: id=%d admin=%t\n", id, isAdmin)
+}
+
+type userCreator interface {
+	SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (int64, error)
+}
+
+// createUser checks password against policy of cfg, hashes it the way Auth
+// does and saves user, with admin role if isAdmin.
+func createUser(
+	ctx context.Context,
+	store userCreator,
+	cfg config.AuthConfig,
+	email, password string,
+	isAdmin bool,
+) (int64, error) {
+	const op = "createUser"
+
+	if err := auth.PasswordPolicy(cfg.PasswordPolicy).Validate(password); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	input, err := auth.PasswordInput(password, cfg.PrehashPasswords)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := hasher.New(hasher.NewBcrypt(cfg.BcryptCost)).Hash(string(input))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var roles []string
+	if isAdmin {
+		roles = []string{models.RoleAdmin}
+	}
+
+	// User and roles are saved together, so a failure leaves no user
+	// without the requested role.
+	id, err := store.SaveUserWithRoles(ctx, email, []byte(passHash), roles)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
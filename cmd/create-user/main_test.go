@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/lib/hasher"
+	"sso/internal/services/auth"
+	"sso/internal/storage/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testAuthConfig = config.AuthConfig{
+	BcryptCost:     bcrypt.MinCost,
+	PasswordPolicy: config.PasswordPolicyConfig{MinLength: 8, RequireDigit: true},
+}
+
+func TestCreateUser(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	for _, tc := range []struct {
+		email   string
+		isAdmin bool
+	}{{"admin@example.com", true}, {"user@example.com", false}} {
+		id, err := createUser(ctx, store, testAuthConfig, tc.email, "Secret123", tc.isAdmin)
+		if err != nil {
+			t.Fatalf("createUser(%s): %v", tc.email, err)
+		}
+
+		isAdmin, err := store.HasRole(ctx, id, models.RoleAdmin)
+		if err != nil {
+			t.Fatalf("HasRole: %v", err)
+		}
+		if isAdmin != tc.isAdmin {
+			t.Errorf("%s is admin = %t, want %t", tc.email, isAdmin, tc.isAdmin)
+		}
+
+		// Hash must be one Auth can check on login.
+		user, err := store.User(ctx, tc.email)
+		if err != nil {
+			t.Fatalf("User: %v", err)
+		}
+		if err := hasher.New(hasher.NewBcrypt(bcrypt.MinCost)).Compare(string(user.PassHash), "Secret123"); err != nil {
+			t.Errorf("stored hash doesn't match password: %v", err)
+		}
+	}
+}
+
+func TestCreateUserWeakPassword(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	if _, err := createUser(ctx, store, testAuthConfig, "admin@example.com", "password", true); !errors.Is(err, auth.ErrWeakPassword) {
+		t.Fatalf("createUser with weak password: got %v, want ErrWeakPassword", err)
+	}
+	if _, err := store.User(ctx, "admin@example.com"); err == nil {
+		t.Error("user with weak password was saved")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"sso/internal/config"
+	"sso/internal/lib/logger"
+	"sso/internal/lib/random"
+	"sso/internal/services/app"
+	"sso/internal/storage"
+	"sso/internal/storage/backend"
+	"sso/internal/storage/migrate"
+)
+
+// manage-app creates client apps and manages their signing keys directly
+// through the storage layer. Config is loaded the same way as for sso.
+//
+//	manage-app --name=web [--secret=...] [--token-ttl=15m] [--key-pair]
+//	manage-app --app-id=1 --key-pair
+//	manage-app --app-id=1 --rotate-secret
+//	manage-app --app-id=1 --prune-keys
+func main() {
+	var name, secret string
+	var appID int
+	var tokenTTL time.Duration
+	var keyPair, rotateSecret, pruneKeys bool
+
+	flag.StringVar(&name, "name", "", "name of app to create")
+	flag.StringVar(&secret, "secret", "", "secret of app to create; generated if empty")
+	flag.DurationVar(&tokenTTL, "token-ttl", 0, "access token TTL of app to create; 0 uses auth.access_token_ttl")
+	flag.IntVar(&appID, "app-id", 0, "existing app to manage instead of creating one")
+	flag.BoolVar(&keyPair, "key-pair", false, "generate ES256 key pair for app")
+	flag.BoolVar(&rotateSecret, "rotate-secret", false, "replace secret of existing app")
+	flag.BoolVar(&pruneKeys, "prune-keys", false, "remove keys of existing app whose grace period is over")
+
+	// Parses the flags above together with --config.
+	cfg := config.MustLoad()
+
+	if (name == "") == (appID == 0) {
+		panic("exactly one of name and app-id is required")
+	}
+	if rotateSecret && appID == 0 {
+		panic("rotate-secret requires app-id")
+	}
+	if pruneKeys && appID == 0 {
+		panic("prune-keys requires app-id")
+	}
+	if tokenTTL < 0 {
+		panic("token-ttl must not be negative")
+	}
+
+	store, err := backend.New(cfg.Storage.Driver, cfg.StoragePath, storage.PoolConfig{
+		MaxOpenConns:    cfg.Storage.MaxOpenConns,
+		MaxIdleConns:    cfg.Storage.MaxIdleConns,
+		ConnMaxLifetime: cfg.Storage.ConnMaxLifetime,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer store.Stop()
+
+	if cfg.MigrationsPath != "" {
+		if _, err := migrate.Up(cfg.Storage.Driver, cfg.StoragePath, cfg.MigrationsPath, migrate.DefaultTable); err != nil {
+			panic(err)
+		}
+	}
+
+	log := logger.NewWithFormat(logger.Format(cfg.Env, cfg.LogFormat), os.Stderr, logger.DefaultLevel(cfg.Env))
+
+	// Replaced secrets keep verifying tokens issued before rotation until
+	// those expire.
+	apps := app.New(log, store, cfg.Auth.AccessTokenTTL, cfg.Auth.ClockSkewLeeway)
+
+	ctx := context.Background()
+
+	if name != "" {
+		if secret == "" {
+			if secret, err = random.Token(32); err != nil {
+				panic(err)
+			}
+		}
+
+		if appID, err = apps.CreateApp(ctx, name, secret, tokenTTL); err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("app created: id=%d secret=%s\n", appID, secret)
+	}
+
+	if rotateSecret {
+		secret, err := apps.RotateSecret(ctx, appID)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("secret rotated: id=%d secret=%s\n", appID, secret)
+	}
+
+	if pruneKeys {
+		pruned, err := apps.PruneKeys(ctx, appID)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("keys pruned: id=%d count=%d\n", appID, pruned)
+	}
+
+	if keyPair {
+		publicKey, err := apps.GenerateKeyPair(ctx, appID)
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("key pair generated: id=%d\n%s", appID, publicKey)
+	}
+
+	// Running sso caches apps, so it doesn't see the change right away.
+	if (rotateSecret || keyPair || pruneKeys) && cfg.Auth.AppCacheTTL > 0 {
+		fmt.Printf("running sso picks the change up within auth.app_cache_ttl (%s), or at once on SIGHUP\n",
+			cfg.Auth.AppCacheTTL)
+	}
+}
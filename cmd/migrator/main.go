@@ -1,21 +1,20 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"fmt"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"sso/internal/storage"
+	"sso/internal/storage/migrate"
 )
 
 func main() {
-	var storagePath, migrationsPath, migrationsTable string
+	var driver, storagePath, migrationsPath, migrationsTable string
 
-	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flag.StringVar(&driver, "driver", storage.DriverSQLite, "storage driver: sqlite or postgres")
+	flag.StringVar(&storagePath, "storage-path", "", "path to storage (postgres DSN for postgres driver)")
 	flag.StringVar(&migrationsPath, "migrations-path", "", "path to migrations")
-	flag.StringVar(&migrationsTable, "migrations-table", "migrations", "name of migrations table")
+	flag.StringVar(&migrationsTable, "migrations-table", migrate.DefaultTable, "name of migrations table")
 	flag.Parse()
 
 	if storagePath == "" {
@@ -25,22 +24,15 @@ func main() {
 		panic("migrations-path is required")
 	}
 
-	m, err := migrate.New(
-		"file://"+migrationsPath,
-		fmt.Sprintf("sqlite3://%s?x-migrations-table=%s", storagePath, migrationsTable),
-	)
+	applied, err := migrate.Up(driver, storagePath, migrationsPath, migrationsTable)
 	if err != nil {
 		panic(err)
 	}
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			fmt.Println("no migrations to apply")
+	if !applied {
+		fmt.Println("no migrations to apply")
 
-			return
-		}
-
-		panic(err)
+		return
 	}
 
 	fmt.Println("migrations applied")
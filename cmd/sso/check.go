@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	grpcapp "sso/internal/app/grpc"
+	"sso/internal/config"
+	"sso/internal/storage"
+	"sso/internal/storage/backend"
+)
+
+// checkStorageTimeout bounds storage connectivity check of --check-storage.
+const checkStorageTimeout = 5 * time.Second
+
+// checkConfig loads and validates config from path, runs the checks sso does
+// at startup (TLS files, IP filter) and, if checkStorage is set, pings
+// storage. Summary is written to out. Nothing is listened on.
+//
+// Unlike sso itself, a missing config file is an error rather than a fall
+// back to environment, so a typo in the path isn't reported as OK.
+func checkConfig(out io.Writer, path string, checkStorage bool) error {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("cannot read config: %w", err)
+		}
+	}
+
+	cfg, err := config.LoadPath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := grpcapp.Check(cfg.GRPC); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "config OK: %s\n", configSource(path))
+	fmt.Fprintf(out, "  env:            %s\n", cfg.Env)
+	fmt.Fprintf(out, "  storage driver: %s\n", cfg.Storage.Driver)
+	fmt.Fprintf(out, "  grpc address:   %s\n", net.JoinHostPort(cfg.GRPC.Host, strconv.Itoa(cfg.GRPC.Port)))
+	fmt.Fprintf(out, "  tls:            %t\n", cfg.GRPC.TLS.Enabled())
+	fmt.Fprintf(out, "  apps:           %d\n", len(cfg.Apps))
+
+	if !checkStorage {
+		return nil
+	}
+
+	store, err := backend.New(cfg.Storage.Driver, cfg.StoragePath, storage.PoolConfig{MaxOpenConns: 1})
+	if err != nil {
+		return err
+	}
+	defer store.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkStorageTimeout)
+	defer cancel()
+
+	if err := store.Ping(ctx); err != nil {
+		return fmt.Errorf("storage is unreachable: %w", err)
+	}
+
+	fmt.Fprintln(out, "storage OK")
+
+	return nil
+}
+
+func configSource(path string) string {
+	if path == "" {
+		return "environment"
+	}
+
+	return path
+}
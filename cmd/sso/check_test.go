@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	return path
+}
+
+func TestCheckConfig(t *testing.T) {
+	valid := writeConfig(t, `
+storage_path: ./storage/sso.db
+grpc:
+  host: 127.0.0.1
+  port: 44044
+`)
+
+	var out bytes.Buffer
+	if err := checkConfig(&out, valid, false); err != nil {
+		t.Fatalf("checkConfig of valid config: %v", err)
+	}
+	for _, want := range []string{"config OK: " + valid, "grpc address:   127.0.0.1:44044"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary %q doesn't contain %q", out.String(), want)
+		}
+	}
+
+	invalid := writeConfig(t, `
+storage_path: ./storage/sso.db
+grpc:
+  port: 70000
+`)
+	if err := checkConfig(&bytes.Buffer{}, invalid, false); err == nil || !strings.Contains(err.Error(), "grpc.port") {
+		t.Errorf("checkConfig of invalid config: got %v, want grpc.port error", err)
+	}
+}
+
+func TestCheckConfigStorage(t *testing.T) {
+	memory := writeConfig(t, `
+storage:
+  driver: memory
+grpc:
+  port: 44044
+`)
+
+	var out bytes.Buffer
+	if err := checkConfig(&out, memory, true); err != nil {
+		t.Fatalf("checkConfig with memory storage: %v", err)
+	}
+	if !strings.Contains(out.String(), "storage OK") {
+		t.Errorf("summary %q doesn't report storage OK", out.String())
+	}
+
+	unreachable := writeConfig(t, `
+storage_path: `+filepath.Join(t.TempDir(), "missing", "sso.db")+`
+grpc:
+  port: 44044
+`)
+	if err := checkConfig(&bytes.Buffer{}, unreachable, true); err == nil {
+		t.Error("checkConfig with unreachable storage = nil, want error")
+	}
+}
+
+func TestCheckConfigMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	if err := checkConfig(&bytes.Buffer{}, missing, false); err == nil {
+		t.Error("checkConfig of missing file = nil, want error")
+	}
+}
+
+func TestCheckConfigTLS(t *testing.T) {
+	dir := t.TempDir()
+	broken := writeConfig(t, `
+storage_path: ./storage/sso.db
+grpc:
+  port: 44044
+  tls:
+    cert_file: `+filepath.Join(dir, "cert.pem")+`
+    key_file: `+filepath.Join(dir, "key.pem")+`
+`)
+
+	if err := checkConfig(&bytes.Buffer{}, broken, false); err == nil {
+		t.Error("checkConfig with unreadable TLS files = nil, want error")
+	}
+}
@@ -1,69 +1,129 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
 	"sso/internal/app"
 	"sso/internal/config"
-	"sso/internal/lib/logger/handlers/slogpretty"
+	"sso/internal/lib/buildinfo"
+	"sso/internal/lib/logger"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/tracing"
 	"syscall"
 )
 
-const (
-	envLocal = "local"
-	envDev   = "dev"
-	envProd  = "prod"
-)
-
 func main() {
-	cfg := config.MustLoad()
+	showVersion := flag.Bool("version", false, "print version and exit")
+	checkOnly := flag.Bool("check-config", false, "validate config and exit")
+	checkStorage := flag.Bool("check-storage", false, "with --check-config, also check storage connectivity")
+	storageDriver := flag.String("storage", "", "override storage.driver, e.g. memory for local runs")
+	configPath := config.FetchConfigPath()
+
+	if *storageDriver != "" {
+		// Env overrides config file, so it goes through the same validation.
+		os.Setenv("STORAGE_DRIVER", *storageDriver)
+	}
+
+	if *showVersion {
+		fmt.Println(buildinfo.String())
+
+		return
+	}
+
+	if *checkOnly {
+		if err := checkConfig(os.Stdout, configPath, *checkStorage); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	cfg := config.MustLoadPath(configPath)
+
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(logger.Level(cfg.Env, cfg.LogLevel))
+
+	var logOut io.Writer = os.Stdout
+	if cfg.LogFile.Path != "" {
+		logFile := logger.NewFile(cfg.LogFile.Path, cfg.LogFile.MaxSizeMB, cfg.LogFile.MaxBackups, cfg.LogFile.MaxAgeDays)
+		defer logFile.Close()
+
+		logOut = logFile
+	}
+
+	log := logger.NewWithFormat(logger.Format(cfg.Env, cfg.LogFormat), logOut, logLevel)
 
-	log := setupLogger(cfg.Env)
+	log.Info("starting application", buildinfo.Attr(), slog.Any("config", cfg))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		Insecure:    cfg.Tracing.Insecure,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-	application := app.New(log, cfg.GRPC.Port, cfg.StoragePath, cfg.TokenTTL)
+	application := app.New(log, logLevel, cfg)
 
 	go func() {
 		application.GRPCServer.MustRun()
 	}()
 
+	if application.MetricsServer != nil {
+		go func() {
+			application.MetricsServer.MustRun()
+		}()
+	}
+
 	// Graceful shutdown
 
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 
-	<-stop
+	// SIGHUP reloads config from configPath, other signals stop the app.
+	for sig := range stop {
+		if sig != syscall.SIGHUP {
+			break
+		}
 
-	application.GRPCServer.Stop()
-	log.Info("Gracefully stopped")
-}
+		newCfg, err := config.LoadPath(configPath)
+		if err != nil {
+			log.Error("failed to reload config", sl.Err(err))
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
+			continue
+		}
 
-	switch env {
-	case envLocal:
-		log = setupPrettySlog()
-	case envDev:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case envProd:
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		application.Reload(newCfg)
 	}
 
-	return log
-}
+	if application.MetricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.GRPC.ShutdownTimeout)
+		if err := application.MetricsServer.Stop(ctx); err != nil {
+			log.Warn("failed to stop metrics server", sl.Err(err))
+		}
+		cancel()
+	}
 
-func setupPrettySlog() *slog.Logger {
-	opts := slogpretty.PrettyHandlerOptions{
-		SlogOpts: &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		},
+	if application.GRPCServer.Stop(cfg.GRPC.ShutdownTimeout) {
+		log.Info("Gracefully stopped")
+	} else {
+		log.Warn("Graceful shutdown timed out, server stopped forcibly",
+			slog.Duration("timeout", cfg.GRPC.ShutdownTimeout),
+		)
 	}
-	handler := opts.NewPrettyHandler(os.Stdout)
 
-	return slog.New(handler)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.GRPC.ShutdownTimeout)
+	defer cancel()
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Warn("failed to flush traces", sl.Err(err))
+	}
 }
@@ -1,34 +1,211 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
 	grpcapp "sso/internal/app/grpc"
+	metricsapp "sso/internal/app/metrics"
+	"sso/internal/audit"
+	"sso/internal/config"
+	"sso/internal/lib/hasher"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/ratelimit"
 	"sso/internal/services/auth"
-	"sso/internal/storage/sqlite"
+	"sso/internal/storage"
+	"sso/internal/storage/backend"
+	"sso/internal/storage/migrate"
+)
+
+// storagePingTimeout bounds single startup readiness check of storage.
+const storagePingTimeout = 5 * time.Second
+
+// Backoff between startup readiness checks of storage.
+const (
+	storageStartupBaseDelay = 200 * time.Millisecond
+	storageStartupMaxDelay  = 5 * time.Second
 )
 
 type App struct {
-	GRPCServer *grpcapp.App
+	GRPCServer    *grpcapp.App
+	MetricsServer *metricsapp.App
+
+	log          *slog.Logger
+	logLevel     *slog.LevelVar
+	cfg          *config.Holder
+	loginLimiter *ratelimit.SlidingWindow
+	apps         appInvalidator
+}
+
+// appInvalidator drops cached apps; it's *auth.Auth.
+type appInvalidator interface {
+	InvalidateApps()
 }
 
+// New wires application. logLevel is level of log; it's changed by Reload.
 func New(
 	log *slog.Logger,
-	grpcPort int,
-	storagePath string,
-	tokenTTL time.Duration,
+	logLevel *slog.LevelVar,
+	cfg *config.Config,
 ) *App {
-	storage, err := sqlite.New(storagePath)
+	store, err := backend.New(cfg.Storage.Driver, cfg.StoragePath, storage.PoolConfig{
+		MaxOpenConns:    cfg.Storage.MaxOpenConns,
+		MaxIdleConns:    cfg.Storage.MaxIdleConns,
+		ConnMaxLifetime: cfg.Storage.ConnMaxLifetime,
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	authService := auth.New(log, storage, storage, storage, tokenTTL)
+	// Every attempt is observed by metrics.
+	store = backend.WithRetry(backend.WithMetrics(store), backend.RetryPolicy{
+		MaxAttempts: cfg.Storage.RetryMaxAttempts,
+		BaseDelay:   cfg.Storage.RetryBaseDelay,
+	})
+
+	if err := waitForStorage(log, store, cfg.Storage.StartupTimeout); err != nil {
+		store.Stop()
+		panic(err)
+	}
+
+	// Memory storage has no schema.
+	if cfg.MigrationsPath != "" && cfg.Storage.Driver != storage.DriverMemory {
+		if _, err := migrate.Up(cfg.Storage.Driver, cfg.StoragePath, cfg.MigrationsPath, migrate.DefaultTable); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := seedApps(context.Background(), log, store, cfg.Apps); err != nil {
+		panic(err)
+	}
+
+	if err := checkDefaultApp(context.Background(), store, cfg.Auth.DefaultAppID); err != nil {
+		panic(err)
+	}
+
+	passwordPolicy := auth.PasswordPolicy(cfg.Auth.PasswordPolicy)
+	passwordHasher := hasher.New(hasher.NewBcrypt(cfg.Auth.BcryptCost))
+
+	if err := bootstrapAdmin(context.Background(), log, store, cfg.Bootstrap, passwordPolicy, cfg.Auth.PrehashPasswords, passwordHasher); err != nil {
+		panic(err)
+	}
+
+	loginLimiter := ratelimit.NewSlidingWindow(cfg.Auth.MaxLoginAttempts, cfg.Auth.LockoutWindow)
+
+	var auditLogger auth.AuditLogger = audit.NewSlog(log)
+	if cfg.Audit.Sink == audit.SinkStorage {
+		auditLogger = audit.NewStorage(log, store)
+	}
+
+	authService := auth.New(
+		log,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		store,
+		loginLimiter,
+		auditLogger,
+		passwordHasher,
+		jwt.StorageKeys{},
+		auth.Config{
+			AccessTokenTTL:        cfg.Auth.AccessTokenTTL,
+			RefreshTokenTTL:       cfg.Auth.RefreshTokenTTL,
+			VerificationTokenTTL:  cfg.Auth.VerificationTokenTTL,
+			PasswordResetTokenTTL: cfg.Auth.ResetTokenTTL,
+			IdempotencyKeyTTL:     cfg.Auth.IdempotencyKeyTTL,
+			PasswordPolicy:        passwordPolicy,
+			RequireVerifiedEmail:  cfg.Auth.RequireVerifiedEmail,
+			Issuer:                cfg.Auth.Issuer,
+			ClockSkewLeeway:       cfg.Auth.ClockSkewLeeway,
+			BcryptWorkers:         cfg.Auth.BcryptWorkers,
+			AppCacheTTL:           cfg.Auth.AppCacheTTL,
+			AdminCacheTTL:         cfg.Auth.AdminCacheTTL,
+			AdminCacheSize:        cfg.Auth.AdminCacheSize,
+			PrehashPasswords:      cfg.Auth.PrehashPasswords,
+
+			RevocationFailurePolicy: cfg.Auth.RevocationFailurePolicy,
+			TokenAlgorithms:         cfg.Auth.TokenAlgorithms,
+			LockoutRetryAfter:       cfg.Auth.LockoutRetryAfter,
+			RehashOnLogin:           cfg.Auth.RehashOnLogin,
+			DefaultAppID:            cfg.Auth.DefaultAppID,
+		},
+	)
+
+	grpcApp, err := grpcapp.New(log, authService, authService, cfg.GRPC)
+	if err != nil {
+		panic(err)
+	}
 
-	grpcApp := grpcapp.New(log, authService, grpcPort)
+	var metricsApp *metricsapp.App
+	if cfg.Metrics.Port != 0 {
+		metricsApp = metricsapp.New(log, cfg.Metrics.Port)
+	}
 
 	return &App{
-		GRPCServer: grpcApp,
+		GRPCServer:    grpcApp,
+		MetricsServer: metricsApp,
+		log:           log,
+		logLevel:      logLevel,
+		cfg:           config.NewHolder(cfg),
+		loginLimiter:  loginLimiter,
+		apps:          authService,
 	}
 }
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// waitForStorage pings storage until it answers, backing off between
+// attempts, for up to timeout; zero timeout means a single attempt. It fails
+// when storage stays unreachable, so the gRPC server is never started (and
+// never reports SERVING) without a working database.
+func waitForStorage(log *slog.Logger, store pinger, timeout time.Duration) error {
+	const op = "app.waitForStorage"
+
+	log = log.With(slog.String("op", op))
+
+	deadline := time.Now().Add(timeout)
+	delay := storageStartupBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := pingStorage(store)
+		if err == nil {
+			if attempt > 1 {
+				log.Info("storage is reachable", slog.Int("attempt", attempt))
+			}
+
+			return nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("%s: storage is unreachable after %d attempts: %w", op, attempt, err)
+		}
+
+		log.Warn("storage is unreachable, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			sl.Err(err),
+		)
+
+		time.Sleep(delay)
+		delay = min(delay*2, storageStartupMaxDelay)
+	}
+}
+
+func pingStorage(store pinger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storagePingTimeout)
+	defer cancel()
+
+	return store.Ping(ctx)
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"sso/internal/audit"
+	"sso/internal/config"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger"
+	"sso/internal/services/auth"
+)
+
+// stubPinger answers Ping with errs in turn, then with nil.
+type stubPinger struct {
+	errs  []error
+	calls int
+}
+
+func (p *stubPinger) Ping(context.Context) error {
+	p.calls++
+	if len(p.errs) == 0 {
+		return nil
+	}
+
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+
+	return err
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestWaitForStorage(t *testing.T) {
+	if err := waitForStorage(discardLogger(), &stubPinger{}, 0); err != nil {
+		t.Errorf("waitForStorage of reachable storage: %v", err)
+	}
+
+	errDown := errors.New("connection refused")
+	store := &stubPinger{errs: []error{errDown}}
+
+	err := waitForStorage(discardLogger(), store, 0)
+	if !errors.Is(err, errDown) {
+		t.Fatalf("waitForStorage of unreachable storage: got %v, want %v", err, errDown)
+	}
+	if store.calls != 1 {
+		t.Errorf("Ping called %d times with zero timeout, want 1", store.calls)
+	}
+}
+
+func TestWaitForStorageRetries(t *testing.T) {
+	errDown := errors.New("connection refused")
+
+	// 200ms and 400ms backoff fit in timeout, storage answers third ping.
+	store := &stubPinger{errs: []error{errDown, errDown}}
+	if err := waitForStorage(discardLogger(), store, 5*time.Second); err != nil {
+		t.Fatalf("waitForStorage of storage coming up: %v", err)
+	}
+	if store.calls != 3 {
+		t.Errorf("Ping called %d times, want 3", store.calls)
+	}
+
+	// Second backoff of 400ms doesn't fit in 300ms, so it gives up early.
+	store = &stubPinger{errs: []error{errDown, errDown, errDown}}
+	begin := time.Now()
+
+	err := waitForStorage(discardLogger(), store, 300*time.Millisecond)
+	if !errors.Is(err, errDown) {
+		t.Fatalf("waitForStorage of storage staying down: got %v, want %v", err, errDown)
+	}
+	if store.calls != 2 {
+		t.Errorf("Ping called %d times, want 2", store.calls)
+	}
+	if elapsed := time.Since(begin); elapsed > 300*time.Millisecond {
+		t.Errorf("waitForStorage took %s, want within 300ms timeout", elapsed)
+	}
+}
+
+// Config validates settings against its own copies of allowed values, so
+// they must stay equal to constants of packages using the settings.
+func TestConfigValuesMatchPackages(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		config, pkg string
+	}{
+		{"log format json", config.LogFormatJSON, logger.FormatJSON},
+		{"log format text", config.LogFormatText, logger.FormatText},
+		{"audit sink log", config.AuditSinkLog, audit.SinkLog},
+		{"audit sink storage", config.AuditSinkStorage, audit.SinkStorage},
+		{"revocation fail closed", config.RevocationFailClosed, auth.RevocationFailClosed},
+		{"revocation fail open", config.RevocationFailOpen, auth.RevocationFailOpen},
+	} {
+		if tc.config != tc.pkg {
+			t.Errorf("%s: config has %q, package has %q", tc.name, tc.config, tc.pkg)
+		}
+	}
+
+	for _, alg := range config.TokenAlgorithms {
+		if !jwt.SupportedAlgorithm(alg) {
+			t.Errorf("config allows token algorithm %q jwt doesn't support", alg)
+		}
+	}
+}
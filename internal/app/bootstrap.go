@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+)
+
+// adminBootstrapper is storage used to create the first admin.
+type adminBootstrapper interface {
+	AnyUserHasRole(ctx context.Context, role string) (bool, error)
+	SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (int64, error)
+}
+
+// bootstrapAdmin creates admin from config if no user has admin role yet.
+// The password must satisfy policy, like on register. User and role are
+// saved together. Existing users are never modified: if the email is taken,
+// nothing is done.
+func bootstrapAdmin(
+	ctx context.Context,
+	log *slog.Logger,
+	store adminBootstrapper,
+	cfg config.BootstrapConfig,
+	policy auth.PasswordPolicy,
+	prehash bool,
+	hasher auth.Hasher,
+) error {
+	const op = "app.bootstrapAdmin"
+
+	if cfg.AdminEmail == "" {
+		return nil
+	}
+
+	log = log.With(slog.String("op", op), slog.String("email", cfg.AdminEmail))
+
+	hasAdmin, err := store.AnyUserHasRole(ctx, models.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if hasAdmin {
+		log.Info("admin already exists, skipping bootstrap")
+
+		return nil
+	}
+
+	if err := policy.Validate(cfg.AdminPassword); err != nil {
+		return fmt.Errorf("%s: admin password: %w", op, err)
+	}
+
+	input, err := auth.PasswordInput(cfg.AdminPassword, prehash)
+	if err != nil {
+		return fmt.Errorf("%s: admin password: %w", op, err)
+	}
+
+	passHash, err := hasher.Hash(string(input))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := store.SaveUserWithRoles(ctx, cfg.AdminEmail, []byte(passHash), []string{models.RoleAdmin})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Warn("bootstrap admin email belongs to an existing user, not granting admin")
+
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Warn("bootstrap admin created", slog.Int64("user_id", id))
+
+	return nil
+}
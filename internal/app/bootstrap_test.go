@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/lib/hasher"
+	"sso/internal/services/auth"
+	"sso/internal/storage/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testPolicy = auth.PasswordPolicy{MinLength: 8, RequireDigit: true}
+
+func runBootstrap(store *memory.Storage, cfg config.BootstrapConfig) error {
+	return bootstrapAdmin(
+		context.Background(),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		store,
+		cfg,
+		testPolicy,
+		false,
+		hasher.New(hasher.NewBcrypt(bcrypt.MinCost)),
+	)
+}
+
+func TestBootstrapAdminCreatesAdmin(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	err := runBootstrap(store, config.BootstrapConfig{AdminEmail: "admin@example.com", AdminPassword: "password1"})
+	if err != nil {
+		t.Fatalf("bootstrapAdmin: %v", err)
+	}
+
+	user, err := store.User(ctx, "admin@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+
+	if err := hasher.New(hasher.NewBcrypt(bcrypt.MinCost)).Compare(string(user.PassHash), "password1"); err != nil {
+		t.Errorf("stored hash doesn't match password: %v", err)
+	}
+
+	isAdmin, err := store.HasRole(ctx, user.ID, models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("HasRole: %v", err)
+	}
+	if !isAdmin {
+		t.Error("bootstrapped user isn't admin")
+	}
+}
+
+func TestBootstrapAdminRejectsWeakPassword(t *testing.T) {
+	store := memory.New()
+
+	err := runBootstrap(store, config.BootstrapConfig{AdminEmail: "admin@example.com", AdminPassword: "short"})
+	if !errors.Is(err, auth.ErrWeakPassword) {
+		t.Fatalf("bootstrapAdmin: got %v, want ErrWeakPassword", err)
+	}
+
+	if _, err := store.User(context.Background(), "admin@example.com"); err == nil {
+		t.Error("user saved despite weak password")
+	}
+}
+
+func TestBootstrapAdminKeepsExistingUser(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	id, err := store.SaveUser(ctx, "admin@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	err = runBootstrap(store, config.BootstrapConfig{AdminEmail: "admin@example.com", AdminPassword: "password1"})
+	if err != nil {
+		t.Fatalf("bootstrapAdmin: %v", err)
+	}
+
+	isAdmin, err := store.HasRole(ctx, id, models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("HasRole: %v", err)
+	}
+	if isAdmin {
+		t.Error("existing user was granted admin")
+	}
+}
+
+func TestBootstrapAdminSkipsWhenAdminExists(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	if _, err := store.SaveUserWithRoles(ctx, "first@example.com", []byte("hash"), []string{models.RoleAdmin}); err != nil {
+		t.Fatalf("SaveUserWithRoles: %v", err)
+	}
+
+	// Weak password isn't even checked: nothing is created.
+	err := runBootstrap(store, config.BootstrapConfig{AdminEmail: "admin@example.com", AdminPassword: "short"})
+	if err != nil {
+		t.Fatalf("bootstrapAdmin: %v", err)
+	}
+
+	if _, err := store.User(ctx, "admin@example.com"); err == nil {
+		t.Error("second admin created")
+	}
+}
@@ -2,59 +2,184 @@ package grpcapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/netip"
+	"runtime/debug"
+	"strconv"
+	"time"
 
+	"sso/internal/config"
 	authgrpc "sso/internal/grpc/auth"
+	"sso/internal/lib/requestid"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
 type App struct {
-	log        *slog.Logger
-	gRPCServer *grpc.Server
-	port       int
+	log          *slog.Logger
+	gRPCServer   *grpc.Server
+	healthServer *health.Server
+	addr         string
+	// shutdownTimeout bounds graceful stop in RunContext.
+	shutdownTimeout time.Duration
+}
+
+// Check runs the checks of cfg New does without creating the server: TLS
+// certificate and key are loaded and IP filter networks are parsed.
+func Check(cfg config.GRPCConfig) error {
+	const op = "grpcapp.Check"
+
+	if _, err := loadTLS(cfg.TLS); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, _, err := ipFilterPrefixes(cfg.IPFilter); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// loadTLS returns server credentials of cfg, or nil if TLS is disabled.
+func loadTLS(cfg config.TLSConfig) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("both tls cert_file and key_file must be set")
+	}
+
+	return credentials.NewServerTLSFromFile(cfg.CertFile, cfg.KeyFile)
+}
+
+func ipFilterPrefixes(cfg config.IPFilterConfig) (allow, deny []netip.Prefix, err error) {
+	if allow, err = parsePrefixes(cfg.Allow); err != nil {
+		return nil, nil, fmt.Errorf("ip_filter.allow: %w", err)
+	}
+
+	if deny, err = parsePrefixes(cfg.Deny); err != nil {
+		return nil, nil, fmt.Errorf("ip_filter.deny: %w", err)
+	}
+
+	return allow, deny, nil
 }
 
 // New creates new gRPC server app.
 func New(
 	log *slog.Logger,
 	authService authgrpc.Auth,
-	port int,
-) *App {
+	tokenValidator TokenValidator,
+	cfg config.GRPCConfig,
+) (*App, error) {
+	const op = "grpcapp.New"
+
 	loggingOpts := []logging.Option{
 		logging.WithLogOnEvents(
-			//logging.StartCall, logging.FinishCall,
+			//logging.StartCall,
+			logging.FinishCall,
 			logging.PayloadReceived, logging.PayloadSent,
 		),
+		logging.WithFieldsFromContext(func(ctx context.Context) logging.Fields {
+			if id, ok := requestid.FromContext(ctx); ok {
+				return logging.Fields{"request_id", id}
+			}
+
+			return nil
+		}),
 		// Add any other option (check functions starting with logging.With).
 	}
 
 	recoveryOpts := []recovery.Option{
 		recovery.WithRecoveryHandler(func(p interface{}) (err error) {
-			log.Error("Recovered from panic", slog.Any("panic", p))
+			log.Error("Recovered from panic",
+				slog.Any("panic", p),
+				slog.String("stack", string(debug.Stack())),
+			)
 
 			return status.Errorf(codes.Internal, "internal error")
 		}),
 	}
 
-	gRPCServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+	serverOpts := []grpc.ServerOption{
+		// No-op unless tracing is enabled.
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		// Oversized requests are rejected by grpc with ResourceExhausted.
+		grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     cfg.Keepalive.MaxConnectionIdle,
+			MaxConnectionAge:      cfg.Keepalive.MaxConnectionAge,
+			MaxConnectionAgeGrace: cfg.Keepalive.MaxConnectionAgeGrace,
+			Time:                  cfg.Keepalive.Time,
+			Timeout:               cfg.Keepalive.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.Keepalive.MinTime,
+			PermitWithoutStream: cfg.Keepalive.PermitWithoutStream,
+		}),
+	}
+
+	creds, err := loadTLS(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if creds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+
+	ipAllow, ipDeny, err := ipFilterPrefixes(cfg.IPFilter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(
+		// Recovery must stay the outermost interceptor.
 		recovery.UnaryServerInterceptor(recoveryOpts...),
+		RequestIDInterceptor(),
+		RequestInfoInterceptor(),
+		MetricsInterceptor(),
+		DisabledMethodsInterceptor(cfg.DisabledMethods),
+		IPFilterInterceptor(log, cfg.IPFilter.Methods, ipAllow, ipDeny),
+		ConcurrencyLimitInterceptor(cfg.MaxConcurrent, cfg.MaxConcurrentPerMethod),
+		TimeoutInterceptor(cfg.Timeout),
+		SlowRequestInterceptor(log, cfg.SlowRequestThreshold),
+		CompressionInterceptor(cfg.EnableGzip),
 		logging.UnaryServerInterceptor(InterceptorLogger(log), loggingOpts...),
+		AuthInterceptor(tokenValidator, cfg.ProtectedMethods),
 	))
 
+	gRPCServer := grpc.NewServer(serverOpts...)
+
 	authgrpc.Register(gRPCServer, authService)
 
-	return &App{
-		log:        log,
-		gRPCServer: gRPCServer,
-		port:       port,
+	healthServer := health.NewServer()
+	// Reported as serving only once the listener is up.
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(gRPCServer, healthServer)
+
+	if cfg.EnableReflection {
+		reflection.Register(gRPCServer)
 	}
+
+	return &App{
+		log:             log,
+		gRPCServer:      gRPCServer,
+		healthServer:    healthServer,
+		addr:            net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		shutdownTimeout: cfg.ShutdownTimeout,
+	}, nil
 }
 
 // InterceptorLogger adapts slog logger to interceptor logger.
@@ -76,13 +201,11 @@ func (a *App) MustRun() {
 func (a *App) Run() error {
 	const op = "grpcapp.Run"
 
-	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	l, err := a.listen()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	a.log.Info("grpc server started", slog.String("addr", l.Addr().String()))
-
 	if err := a.gRPCServer.Serve(l); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -90,12 +213,97 @@ func (a *App) Run() error {
 	return nil
 }
 
-// Stop stops gRPC server.
-func (a *App) Stop() {
+// RunContext runs gRPC server until ctx is cancelled, then stops it
+// gracefully (forcibly after shutdown timeout). Returns nil on clean
+// shutdown.
+func (a *App) RunContext(ctx context.Context) error {
+	const op = "grpcapp.RunContext"
+
+	l, err := a.listen()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.gRPCServer.Serve(l)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	case <-ctx.Done():
+	}
+
+	if !a.Stop(a.shutdownTimeout) {
+		a.log.Warn("graceful shutdown timed out, server stopped forcibly",
+			slog.Duration("timeout", a.shutdownTimeout),
+		)
+	}
+
+	// Serve returns nil after GracefulStop/Stop.
+	if err := <-serveErr; err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) listen() (net.Listener, error) {
+	l, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	a.log.Info("grpc server started", slog.String("addr", l.Addr().String()))
+
+	a.SetServing(true)
+
+	return l, nil
+}
+
+// SetServing flips health status reported by the standard grpc.health.v1
+// service, e.g. depending on storage connectivity.
+func (a *App) SetServing(serving bool) {
+	st := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		st = healthpb.HealthCheckResponse_SERVING
+	}
+
+	a.healthServer.SetServingStatus("", st)
+}
+
+// Stop stops gRPC server gracefully. If in-flight RPCs don't finish within
+// timeout, the server is stopped forcibly. Reports whether the graceful stop
+// completed in time.
+func (a *App) Stop(timeout time.Duration) bool {
 	const op = "grpcapp.Stop"
 
 	a.log.With(slog.String("op", op)).
-		Info("stopping gRPC server", slog.Int("port", a.port))
+		Info("stopping gRPC server", slog.String("addr", a.addr))
+
+	// Shutdown sets NOT_SERVING and ignores further status updates.
+	a.healthServer.Shutdown()
 
-	a.gRPCServer.GracefulStop()
+	done := make(chan struct{})
+	go func() {
+		a.gRPCServer.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		a.gRPCServer.Stop()
+
+		return false
+	}
 }
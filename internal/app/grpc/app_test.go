@@ -0,0 +1,260 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"sso/internal/config"
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/services/auth"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeAuth is authgrpc.Auth whose Login runs login, if set.
+type fakeAuth struct {
+	login func(ctx context.Context) (string, error)
+}
+
+func (f *fakeAuth) Login(ctx context.Context, _, _ string, _ int) (string, error) {
+	if f.login == nil {
+		return "token", nil
+	}
+
+	return f.login(ctx)
+}
+
+func (f *fakeAuth) RegisterNewUser(context.Context, string, string) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeAuth) RegisterNewUserIdempotent(context.Context, string, string, string) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeAuth) IsAdmin(context.Context, int64) (bool, error) {
+	return true, nil
+}
+
+// fakeValidator accepts only token "valid" and fails to check token "down".
+type fakeValidator struct{}
+
+func (fakeValidator) Authenticate(_ context.Context, token string) (*jwt.Claims, models.App, error) {
+	switch token {
+	case "valid":
+		return &jwt.Claims{UID: 1}, models.App{ID: 1, Name: "web"}, nil
+	case "down":
+		return nil, models.App{}, errors.New("storage is down")
+	default:
+		return nil, models.App{}, fmt.Errorf("Auth.Authenticate: %w", auth.ErrInvalidToken)
+	}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestApp returns App built from cfg with auth; zero MaxRecvMsgSize gets
+// the config default.
+func newTestApp(t *testing.T, auth *fakeAuth, cfg config.GRPCConfig) *App {
+	t.Helper()
+
+	if cfg.MaxRecvMsgSize == 0 {
+		cfg.MaxRecvMsgSize = 4 << 20
+	}
+
+	a, err := New(discardLogger(), auth, fakeValidator{}, cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return a
+}
+
+// serve runs a over an in-memory listener and returns connection to it
+// dialed with opts.
+func serve(t *testing.T, a *App, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+
+	go func() { _ = a.gRPCServer.Serve(lis) }()
+	t.Cleanup(a.gRPCServer.Stop)
+
+	opts = append(opts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestStop(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{})
+	serve(t, a)
+
+	if !a.Stop(time.Second) {
+		t.Error("Stop of idle server = false, want graceful stop")
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	a := newTestApp(t, &fakeAuth{
+		login: func(context.Context) (string, error) {
+			close(started)
+			<-release
+
+			return "token", nil
+		},
+	}, config.GRPCConfig{})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	go func() {
+		_, _ = api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1})
+	}()
+	<-started
+
+	begin := time.Now()
+	if a.Stop(50 * time.Millisecond) {
+		t.Error("Stop with in-flight call = true, want forced stop")
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("Stop took %s, want about the 50ms timeout", elapsed)
+	}
+}
+
+func TestRecoversFromPanic(t *testing.T) {
+	panicked := false
+
+	a := newTestApp(t, &fakeAuth{
+		login: func(context.Context) (string, error) {
+			if !panicked {
+				panicked = true
+				panic("boom")
+			}
+
+			return "token", nil
+		},
+	}, config.GRPCConfig{})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	req := &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}
+
+	if _, err := api.Login(context.Background(), req); status.Code(err) != codes.Internal {
+		t.Fatalf("Login with panicking handler: got %v, want Internal", err)
+	}
+	if _, err := api.Login(context.Background(), req); err != nil {
+		t.Errorf("Login after panic: %v", err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{})
+	health := healthpb.NewHealthClient(serve(t, a))
+
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+
+		resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+
+		return resp.GetStatus()
+	}
+
+	if got := check(); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status before listening = %s, want NOT_SERVING", got)
+	}
+
+	a.SetServing(true)
+	if got := check(); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("status after SetServing(true) = %s, want SERVING", got)
+	}
+
+	a.SetServing(false)
+	if got := check(); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status after SetServing(false) = %s, want NOT_SERVING", got)
+	}
+}
+
+func TestReflection(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{EnableReflection: enabled})
+
+		var registered bool
+		for name := range a.gRPCServer.GetServiceInfo() {
+			if strings.HasPrefix(name, "grpc.reflection.") {
+				registered = true
+			}
+		}
+
+		if registered != enabled {
+			t.Errorf("enable_reflection %t: reflection registered = %t", enabled, registered)
+		}
+	}
+}
+
+func TestMaxRecvMsgSize(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{MaxRecvMsgSize: 1024})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	small := &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}
+	if _, err := api.Login(context.Background(), small); err != nil {
+		t.Fatalf("Login under limit: %v", err)
+	}
+
+	big := &ssov1.LoginRequest{Email: "user@example.com", Password: strings.Repeat("x", 2048), AppId: 1}
+	if _, err := api.Login(context.Background(), big); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Login over limit: got %v, want ResourceExhausted", err)
+	}
+}
+
+func TestKeepaliveMaxConnectionIdle(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{
+		Keepalive: config.KeepaliveConfig{MaxConnectionIdle: 100 * time.Millisecond},
+	})
+	conn := serve(t, a)
+	api := ssov1.NewAuthClient(conn)
+
+	if _, err := api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Server closes idle connection with GOAWAY, client goes idle.
+	for state := conn.GetState(); state != connectivity.Idle; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			t.Fatalf("connection still %s after max idle, want it closed", state)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/authctx"
+	"sso/internal/lib/jwt"
+	"sso/internal/services/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationKey = "authorization"
+	bearerPrefix     = "bearer "
+)
+
+// TokenValidator validates access tokens presented by callers and resolves
+// app they were issued for.
+type TokenValidator interface {
+	Authenticate(ctx context.Context, token string) (*jwt.Claims, models.App, error)
+}
+
+// AuthInterceptor requires a valid bearer token in `authorization` metadata
+// for protected methods (full names, e.g. "/auth.Auth/IsAdmin") and stores
+// its claims and app in context, see authctx.ClaimsFromContext and
+// authctx.AppFromContext. Other methods pass through untouched.
+func AuthInterceptor(validator TokenValidator, protected []string) grpc.UnaryServerInterceptor {
+	methods := make(map[string]struct{}, len(protected))
+	for _, m := range protected {
+		methods[m] = struct{}{}
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if _, ok := methods[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+
+		token, ok := bearerToken(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		claims, app, err := validator.Authenticate(ctx, token)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenRevoked) {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+
+			return nil, status.Error(codes.Internal, "failed to validate token")
+		}
+
+		ctx = authctx.WithClaims(ctx, claims)
+		ctx = authctx.WithApp(ctx, app)
+
+		return handler(ctx, req)
+	}
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	v := values[0]
+	if len(v) <= len(bearerPrefix) || !strings.EqualFold(v[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(v[len(bearerPrefix):]), true
+}
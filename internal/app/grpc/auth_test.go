@@ -0,0 +1,70 @@
+package grpcapp
+
+import (
+	"context"
+	"testing"
+
+	"sso/internal/lib/authctx"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptor(t *testing.T) {
+	interceptor := AuthInterceptor(fakeValidator{}, []string{"/auth.Auth/IsAdmin"})
+
+	call := func(method, authorization string) (uid int64, app string, err error) {
+		ctx := context.Background()
+		if authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationKey, authorization))
+		}
+
+		_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, _ interface{}) (interface{}, error) {
+				if claims, ok := authctx.ClaimsFromContext(ctx); ok {
+					uid = claims.UID
+				}
+				if a, ok := authctx.AppFromContext(ctx); ok {
+					app = a.Name
+				}
+
+				return nil, nil
+			})
+
+		return uid, app, err
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantCode      codes.Code
+		wantUID       int64
+		wantApp       string
+	}{
+		{"unprotected method", "/auth.Auth/Login", "", codes.OK, 0, ""},
+		{"valid token", "/auth.Auth/IsAdmin", "Bearer valid", codes.OK, 1, "web"},
+		{"scheme is case-insensitive", "/auth.Auth/IsAdmin", "bearer valid", codes.OK, 1, "web"},
+		{"missing token", "/auth.Auth/IsAdmin", "", codes.Unauthenticated, 0, ""},
+		{"not bearer", "/auth.Auth/IsAdmin", "Basic dXNlcjpwYXNz", codes.Unauthenticated, 0, ""},
+		{"invalid token", "/auth.Auth/IsAdmin", "Bearer forged", codes.Unauthenticated, 0, ""},
+		{"validator failure", "/auth.Auth/IsAdmin", "Bearer down", codes.Internal, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, app, err := call(tt.method, tt.authorization)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("code = %s (%v), want %s", status.Code(err), err, tt.wantCode)
+			}
+			if uid != tt.wantUID {
+				t.Errorf("uid in handler ctx = %d, want %d", uid, tt.wantUID)
+			}
+			if app != tt.wantApp {
+				t.Errorf("app in handler ctx = %q, want %q", app, tt.wantApp)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package grpcapp
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	// Registers gzip, so gzip-compressed requests can always be decoded.
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// CompressionInterceptor controls response compression. When enabled,
+// gRPC answers a call compressed the same way as its request, so clients
+// opt in per call (grpc.UseCompressor(gzip.Name)). When disabled, responses
+// are always sent uncompressed.
+func CompressionInterceptor(enabled bool) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !enabled {
+			if err := grpc.SetSendCompressor(ctx, encoding.Identity); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
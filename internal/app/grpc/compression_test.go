@@ -0,0 +1,100 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"sso/internal/config"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// countingConn counts bytes read from connection.
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+
+	return n, err
+}
+
+// responseBytes logs in to a with enableGzip once, compressing the request
+// if gzipRequest, and returns how many bytes client read.
+func responseBytes(t *testing.T, enableGzip, gzipRequest bool) int64 {
+	t.Helper()
+
+	a := newTestApp(t, &fakeAuth{
+		login: func(context.Context) (string, error) {
+			return strings.Repeat("token", 20000), nil
+		},
+	}, config.GRPCConfig{EnableGzip: enableGzip})
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = a.gRPCServer.Serve(lis) }()
+	t.Cleanup(a.gRPCServer.Stop)
+
+	var read atomic.Int64
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			c, err := lis.DialContext(ctx)
+
+			return countingConn{Conn: c, read: &read}, err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	var opts []grpc.CallOption
+	if gzipRequest {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+
+	resp, err := ssov1.NewAuthClient(conn).Login(context.Background(),
+		&ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}, opts...)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if len(resp.GetToken()) != 100000 {
+		t.Fatalf("token length = %d, want 100000", len(resp.GetToken()))
+	}
+
+	return read.Load()
+}
+
+func TestGzip(t *testing.T) {
+	const compressed = 10000
+
+	tests := []struct {
+		name           string
+		enableGzip     bool
+		gzipRequest    bool
+		wantCompressed bool
+	}{
+		{"enabled, gzip request", true, true, true},
+		{"enabled, plain request", true, false, false},
+		{"disabled, gzip request", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := responseBytes(t, tt.enableGzip, tt.gzipRequest)
+			if got := n < compressed; got != tt.wantCompressed {
+				t.Errorf("client read %d bytes of 100000 byte token, want compressed %t", n, tt.wantCompressed)
+			}
+		})
+	}
+}
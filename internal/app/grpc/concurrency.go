@@ -0,0 +1,55 @@
+package grpcapp
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConcurrencyLimitInterceptor sheds load: once limit unary calls are in
+// flight, further calls fail at once with ResourceExhausted instead of
+// queuing. Methods in perMethod get their own limit instead of sharing
+// limit. Zero limit means unlimited.
+func ConcurrencyLimitInterceptor(limit int, perMethod map[string]int) grpc.UnaryServerInterceptor {
+	shared := newSemaphore(limit)
+
+	methods := make(map[string]chan struct{}, len(perMethod))
+	for method, l := range perMethod {
+		methods[method] = newSemaphore(l)
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		sem, ok := methods[info.FullMethod]
+		if !ok {
+			sem = shared
+		}
+		if sem == nil {
+			return handler(ctx, req)
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			return nil, status.Error(codes.ResourceExhausted, "too many concurrent requests")
+		}
+		defer func() { <-sem }()
+
+		return handler(ctx, req)
+	}
+}
+
+// newSemaphore returns nil, meaning unlimited, for non-positive limit.
+func newSemaphore(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+
+	return make(chan struct{}, limit)
+}
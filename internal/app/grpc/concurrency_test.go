@@ -0,0 +1,121 @@
+package grpcapp
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"sso/internal/config"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// occupy starts n calls of method through interceptor that block until
+// returned release is called; release waits for them to return.
+func occupy(t *testing.T, interceptor grpc.UnaryServerInterceptor, method string, n int) (release func()) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	started := make(chan struct{})
+	done := make(chan struct{})
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+				func(context.Context, interface{}) (interface{}, error) {
+					started <- struct{}{}
+					<-done
+
+					return nil, nil
+				})
+		}()
+		<-started
+	}
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func call(interceptor grpc.UnaryServerInterceptor, method string) codes.Code {
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+
+	return status.Code(err)
+}
+
+func TestConcurrencyLimitInterceptor(t *testing.T) {
+	const (
+		login    = "/auth.Auth/Login"
+		register = "/auth.Auth/Register"
+		isAdmin  = "/auth.Auth/IsAdmin"
+	)
+
+	interceptor := ConcurrencyLimitInterceptor(2, map[string]int{login: 1, isAdmin: 0})
+
+	release := occupy(t, interceptor, register, 2)
+	if got := call(interceptor, register); got != codes.ResourceExhausted {
+		t.Errorf("call over shared limit: got %s, want ResourceExhausted", got)
+	}
+	if got := call(interceptor, login); got != codes.OK {
+		t.Errorf("call of method with own limit: got %s, want OK", got)
+	}
+	release()
+
+	release = occupy(t, interceptor, login, 1)
+	if got := call(interceptor, login); got != codes.ResourceExhausted {
+		t.Errorf("call over per-method limit: got %s, want ResourceExhausted", got)
+	}
+	if got := call(interceptor, register); got != codes.OK {
+		t.Errorf("shared call while per-method limit is full: got %s, want OK", got)
+	}
+	release()
+
+	release = occupy(t, interceptor, isAdmin, 5)
+	if got := call(interceptor, isAdmin); got != codes.OK {
+		t.Errorf("call of unlimited method: got %s, want OK", got)
+	}
+	release()
+}
+
+func TestConcurrencyLimitInterceptorFreesSlot(t *testing.T) {
+	interceptor := ConcurrencyLimitInterceptor(1, nil)
+
+	for i := 0; i < 3; i++ {
+		if got := call(interceptor, "/auth.Auth/Login"); got != codes.OK {
+			t.Fatalf("sequential call %d: got %s, want OK", i, got)
+		}
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	a := newTestApp(t, &fakeAuth{
+		login: func(context.Context) (string, error) {
+			close(started)
+			<-release
+
+			return "token", nil
+		},
+	}, config.GRPCConfig{MaxConcurrent: 1})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	req := &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}
+
+	go func() { _, _ = api.Login(context.Background(), req) }()
+	<-started
+
+	if _, err := api.Login(context.Background(), req); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Login over max_concurrent: got %v, want ResourceExhausted", err)
+	}
+}
@@ -0,0 +1,32 @@
+package grpcapp
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DisabledMethodsInterceptor rejects calls of disabled methods (full names,
+// e.g. "/auth.Auth/Register") with Unimplemented, as if the server didn't
+// have them. Other methods pass through untouched.
+func DisabledMethodsInterceptor(disabled []string) grpc.UnaryServerInterceptor {
+	methods := make(map[string]struct{}, len(disabled))
+	for _, m := range disabled {
+		methods[m] = struct{}{}
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if _, ok := methods[info.FullMethod]; ok {
+			return nil, status.Error(codes.Unimplemented, "method disabled")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,27 @@
+package grpcapp
+
+import (
+	"context"
+	"testing"
+
+	"sso/internal/config"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDisabledMethods(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{DisabledMethods: []string{"/auth.Auth/Register"}})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	_, err := api.Register(context.Background(), &ssov1.RegisterRequest{Email: "user@example.com", Password: "Secret123"})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Register of disabled method: got %v, want Unimplemented", err)
+	}
+
+	_, err = api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1})
+	if err != nil {
+		t.Errorf("Login of enabled method: %v", err)
+	}
+}
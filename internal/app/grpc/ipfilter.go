@@ -0,0 +1,106 @@
+package grpcapp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// IPFilterInterceptor restricts methods (full names, e.g.
+// "/auth.Auth/IsAdmin") by peer IP: callers from deny networks are
+// rejected with PermissionDenied, and so are callers outside allow networks
+// unless allow is empty. Deny wins over allow. Other methods pass through
+// untouched.
+func IPFilterInterceptor(log *slog.Logger, methods []string, allow, deny []netip.Prefix) grpc.UnaryServerInterceptor {
+	filtered := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		filtered[m] = struct{}{}
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if _, ok := filtered[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+
+		addr, ok := peerAddr(ctx)
+		if !ok || !ipAllowed(addr, allow, deny) {
+			log.WarnContext(ctx, "call rejected by ip filter",
+				slog.String("method", info.FullMethod),
+				slog.String("peer", addr.String()),
+			)
+
+			return nil, status.Error(codes.PermissionDenied, "caller address not allowed")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// ipAllowed reports whether addr passes deny and allow lists.
+func ipAllowed(addr netip.Addr, allow, deny []netip.Prefix) bool {
+	for _, p := range deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	if len(allow) == 0 {
+		return true
+	}
+
+	for _, p := range allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// peerAddr returns IP of the caller. IPv4-mapped IPv6 addresses are
+// unmapped and zones dropped, so they match configured networks.
+func peerAddr(ctx context.Context) (netip.Addr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, false
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap().WithZone(""), true
+}
+
+// parsePrefixes parses CIDRs like "10.0.0.0/8" or "fd00::/8".
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+		}
+
+		prefixes = append(prefixes, p.Masked())
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,116 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"sso/internal/config"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func mustPrefixes(t *testing.T, cidrs ...string) []netip.Prefix {
+	t.Helper()
+
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		t.Fatalf("parsePrefixes: %v", err)
+	}
+
+	return prefixes
+}
+
+func TestIPFilterInterceptor(t *testing.T) {
+	const isAdmin = "/auth.Auth/IsAdmin"
+
+	interceptor := IPFilterInterceptor(discardLogger(), []string{isAdmin},
+		mustPrefixes(t, "10.0.0.0/8", "fd00::/8"),
+		mustPrefixes(t, "10.0.0.13/32"),
+	)
+
+	tests := []struct {
+		name   string
+		method string
+		addr   net.Addr
+		want   codes.Code
+	}{
+		{"allowed IPv4", isAdmin, &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5000}, codes.OK},
+		{"allowed IPv6", isAdmin, &net.TCPAddr{IP: net.ParseIP("fd00::1"), Port: 5000}, codes.OK},
+		{"IPv4-mapped IPv6", isAdmin, &net.TCPAddr{IP: net.ParseIP("::ffff:10.1.2.3"), Port: 5000}, codes.OK},
+		{"denied within allowed", isAdmin, &net.TCPAddr{IP: net.ParseIP("10.0.0.13"), Port: 5000}, codes.PermissionDenied},
+		{"outside allowed", isAdmin, &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 5000}, codes.PermissionDenied},
+		{"no peer address", isAdmin, nil, codes.PermissionDenied},
+		{"unfiltered method", "/auth.Auth/Login", &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 5000}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.addr != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: tt.addr})
+			}
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, interface{}) (interface{}, error) { return nil, nil })
+			if status.Code(err) != tt.want {
+				t.Errorf("interceptor: got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPAllowedEmptyAllow(t *testing.T) {
+	deny := mustPrefixes(t, "192.168.0.0/16")
+
+	if !ipAllowed(netip.MustParseAddr("10.1.2.3"), nil, deny) {
+		t.Error("address outside deny with empty allow rejected, want allowed")
+	}
+	if ipAllowed(netip.MustParseAddr("192.168.1.1"), nil, deny) {
+		t.Error("denied address with empty allow allowed, want rejected")
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := parsePrefixes([]string{"10.1.2.3/8"})
+	if err != nil {
+		t.Fatalf("parsePrefixes: %v", err)
+	}
+	if got := prefixes[0].String(); got != "10.0.0.0/8" {
+		t.Errorf("prefix = %s, want masked 10.0.0.0/8", got)
+	}
+
+	if _, err := parsePrefixes([]string{"10.0.0.0"}); err == nil {
+		t.Error("parsePrefixes of address without mask: got nil error")
+	}
+}
+
+func TestIPFilter(t *testing.T) {
+	// bufconn peer has no IP, so filtered methods reject it.
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{
+		IPFilter: config.IPFilterConfig{Methods: []string{"/auth.Auth/IsAdmin"}, Allow: []string{"10.0.0.0/8"}},
+	})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	if _, err := api.IsAdmin(context.Background(), &ssov1.IsAdminRequest{UserId: 1}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("IsAdmin from outside allowed networks: got %v, want PermissionDenied", err)
+	}
+	if _, err := api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}); err != nil {
+		t.Errorf("Login of unfiltered method: %v", err)
+	}
+}
+
+func TestNewInvalidIPFilter(t *testing.T) {
+	_, err := New(discardLogger(), &fakeAuth{}, fakeValidator{}, config.GRPCConfig{
+		MaxRecvMsgSize: 4 << 20,
+		IPFilter:       config.IPFilterConfig{Methods: []string{"/auth.Auth/IsAdmin"}, Deny: []string{"not-a-cidr"}},
+	})
+	if err == nil {
+		t.Error("New with invalid deny CIDR: got nil error")
+	}
+}
@@ -0,0 +1,46 @@
+package grpcapp
+
+import (
+	"context"
+
+	"sso/internal/lib/random"
+	"sso/internal/lib/requestid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const requestIDSize = 16
+
+// RequestIDInterceptor takes request ID from incoming metadata or generates
+// a new one, stores it in context and echoes it back in response header.
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var id string
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(requestid.MetadataKey); len(values) > 0 {
+				id = values[0]
+			}
+		}
+
+		if id == "" {
+			var err error
+
+			id, err = random.Token(requestIDSize)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Header is sent together with the response, so failure here is not fatal.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+
+		return handler(requestid.WithRequestID(ctx, id), req)
+	}
+}
@@ -0,0 +1,53 @@
+package grpcapp
+
+import (
+	"context"
+	"testing"
+
+	"sso/internal/config"
+	"sso/internal/lib/requestid"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDInterceptor(t *testing.T) {
+	var seen string
+
+	a := newTestApp(t, &fakeAuth{
+		login: func(ctx context.Context) (string, error) {
+			seen, _ = requestid.FromContext(ctx)
+
+			return "token", nil
+		},
+	}, config.GRPCConfig{})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	login := func(ctx context.Context) string {
+		t.Helper()
+
+		var header metadata.MD
+		_, err := api.Login(ctx, &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}, grpc.Header(&header))
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+
+		values := header.Get(requestid.MetadataKey)
+		if len(values) != 1 {
+			t.Fatalf("response header %s = %v, want one value", requestid.MetadataKey, values)
+		}
+
+		return values[0]
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestid.MetadataKey, "req-1")
+	if got := login(ctx); got != "req-1" || seen != "req-1" {
+		t.Errorf("given request id: header %q, handler %q, want req-1", got, seen)
+	}
+
+	got := login(context.Background())
+	if got == "" || got != seen {
+		t.Errorf("generated request id: header %q, handler %q, want same non-empty id", got, seen)
+	}
+}
@@ -0,0 +1,42 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+
+	"sso/internal/lib/requestinfo"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const userAgentKey = "user-agent"
+
+// RequestInfoInterceptor stores caller IP (from peer address) and user
+// agent (from metadata) in context, see requestinfo.FromContext.
+func RequestInfoInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var ri requestinfo.Info
+
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ri.ClientIP = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ri.ClientIP); err == nil {
+				ri.ClientIP = host
+			}
+		}
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(userAgentKey); len(values) > 0 {
+				ri.UserAgent = values[0]
+			}
+		}
+
+		return handler(requestinfo.WithInfo(ctx, ri), req)
+	}
+}
@@ -0,0 +1,63 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"sso/internal/config"
+	"sso/internal/lib/requestinfo"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRequestInfoInterceptor(t *testing.T) {
+	var seen requestinfo.Info
+
+	a := newTestApp(t, &fakeAuth{
+		login: func(ctx context.Context) (string, error) {
+			seen, _ = requestinfo.FromContext(ctx)
+
+			return "token", nil
+		},
+	}, config.GRPCConfig{})
+	api := ssov1.NewAuthClient(serve(t, a, grpc.WithUserAgent("test-agent/1.0")))
+
+	_, err := api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	// bufconn peers have address "bufconn" without port.
+	if seen.ClientIP != "bufconn" {
+		t.Errorf("client ip = %q, want bufconn peer address", seen.ClientIP)
+	}
+	if !strings.HasPrefix(seen.UserAgent, "test-agent/1.0") {
+		t.Errorf("user agent = %q, want test-agent/1.0 prefix", seen.UserAgent)
+	}
+}
+
+func TestRequestInfoInterceptorStripsPort(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 54321},
+	})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(userAgentKey, "curl/8"))
+
+	var seen requestinfo.Info
+	_, err := RequestInfoInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		seen, _ = requestinfo.FromContext(ctx)
+
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+
+	if want := (requestinfo.Info{ClientIP: "10.0.0.1", UserAgent: "curl/8"}); seen != want {
+		t.Errorf("request info = %+v, want %+v", seen, want)
+	}
+}
@@ -0,0 +1,63 @@
+package grpcapp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"sso/internal/config"
+)
+
+func TestRunContext(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{Host: "127.0.0.1", ShutdownTimeout: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- a.RunContext(ctx) }()
+
+	// Let the server start listening before cancelling.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunContext after cancel: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunContext didn't return after ctx was cancelled")
+	}
+}
+
+func TestListenHost(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{Host: "127.0.0.1"})
+
+	l, err := a.listen()
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	if ip := l.Addr().(*net.TCPAddr).IP; !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("listening on %s, want 127.0.0.1", l.Addr())
+	}
+}
+
+func TestRunContextListenError(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer busy.Close()
+
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{
+		Host: "127.0.0.1",
+		Port: busy.Addr().(*net.TCPAddr).Port,
+	})
+
+	if err := a.RunContext(context.Background()); err == nil {
+		t.Error("RunContext on busy port = nil, want listen error")
+	}
+}
@@ -0,0 +1,46 @@
+package grpcapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"sso/internal/lib/requestid"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// SlowRequestInterceptor logs at warn level every unary call that took
+// longer than threshold. Zero threshold disables it.
+func SlowRequestInterceptor(log *slog.Logger, threshold time.Duration) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if threshold <= 0 {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		if elapsed := time.Since(start); elapsed > threshold {
+			attrs := []any{
+				slog.String("method", info.FullMethod),
+				slog.Duration("elapsed", elapsed),
+				slog.Duration("threshold", threshold),
+				slog.String("code", status.Code(err).String()),
+			}
+			if id, ok := requestid.FromContext(ctx); ok {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			log.WarnContext(ctx, "slow request", attrs...)
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,59 @@
+package grpcapp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"sso/internal/lib/requestid"
+)
+
+func TestSlowRequestInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		sleep     time.Duration
+		wantWarn  bool
+	}{
+		{"slow", 10 * time.Millisecond, 30 * time.Millisecond, true},
+		{"fast", time.Second, 0, false},
+		{"disabled", 0, 30 * time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewTextHandler(&buf, nil))
+
+			interceptor := SlowRequestInterceptor(log, tt.threshold)
+			ctx := requestid.WithRequestID(context.Background(), "req-1")
+
+			_, err := interceptor(ctx, nil, testInfo, func(context.Context, interface{}) (interface{}, error) {
+				time.Sleep(tt.sleep)
+
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatalf("interceptor: %v", err)
+			}
+
+			logged := buf.String()
+			if !tt.wantWarn {
+				if logged != "" {
+					t.Errorf("log = %q, want nothing", logged)
+				}
+
+				return
+			}
+
+			for _, want := range []string{"level=WARN", `msg="slow request"`, "method=/auth.Auth/Login", "request_id=req-1", "code=OK"} {
+				if !strings.Contains(logged, want) {
+					t.Errorf("log = %q, want %s", logged, want)
+				}
+			}
+		})
+	}
+}
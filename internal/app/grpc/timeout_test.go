@@ -0,0 +1,106 @@
+package grpcapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sso/internal/config"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testInfo = &grpc.UnaryServerInfo{FullMethod: "/auth.Auth/Login"}
+
+// deadlineOf runs interceptor with ctx and returns how far away the deadline
+// seen by handler was, or zero if there was none.
+func deadlineOf(t *testing.T, interceptor grpc.UnaryServerInterceptor, ctx context.Context) time.Duration {
+	t.Helper()
+
+	var left time.Duration
+
+	_, err := interceptor(ctx, nil, testInfo, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			left = time.Until(deadline)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+
+	return left
+}
+
+func TestTimeoutInterceptorDeadline(t *testing.T) {
+	interceptor := TimeoutInterceptor(time.Minute)
+
+	if left := deadlineOf(t, interceptor, context.Background()); left <= 59*time.Second || left > time.Minute {
+		t.Errorf("deadline in %s, want about 1m", left)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if left := deadlineOf(t, interceptor, ctx); left > time.Second {
+		t.Errorf("deadline with sooner client deadline in %s, want at most 1s", left)
+	}
+
+	if left := deadlineOf(t, TimeoutInterceptor(0), context.Background()); left != 0 {
+		t.Errorf("zero timeout set deadline in %s, want none", left)
+	}
+}
+
+func TestTimeoutInterceptorErrors(t *testing.T) {
+	interceptor := TimeoutInterceptor(20 * time.Millisecond)
+
+	tests := []struct {
+		name    string
+		handler grpc.UnaryHandler
+		want    codes.Code
+	}{
+		{"handler past timeout", func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+
+			return nil, status.Error(codes.Internal, "failed to login")
+		}, codes.DeadlineExceeded},
+		{"handler error in time", func(context.Context, interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}, codes.NotFound},
+		{"handler success in time", func(context.Context, interface{}) (interface{}, error) {
+			return "ok", nil
+		}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := interceptor(context.Background(), nil, testInfo, tt.handler)
+			if status.Code(err) != tt.want {
+				t.Errorf("interceptor: got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	a := newTestApp(t, &fakeAuth{
+		login: func(ctx context.Context) (string, error) {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Second):
+				return "token", nil
+			}
+		},
+	}, config.GRPCConfig{Timeout: 50 * time.Millisecond})
+	api := ssov1.NewAuthClient(serve(t, a))
+
+	_, err := api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Login past server timeout: got %v, want DeadlineExceeded", err)
+	}
+}
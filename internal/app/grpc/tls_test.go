@@ -0,0 +1,150 @@
+package grpcapp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sso/internal/config"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// writeCert writes self-signed certificate for localhost and its key to a
+// temp dir and returns their paths and the certificate.
+func writeCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	return certFile, keyFile, cert
+}
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile, cert := writeCert(t)
+
+	a := newTestApp(t, &fakeAuth{}, config.GRPCConfig{
+		TLS: config.TLSConfig{CertFile: certFile, KeyFile: keyFile},
+	})
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = a.gRPCServer.Serve(lis) }()
+	t.Cleanup(a.gRPCServer.Stop)
+
+	dial := func(creds credentials.TransportCredentials) ssov1.AuthClient {
+		conn, err := grpc.NewClient("passthrough:///localhost",
+			grpc.WithTransportCredentials(creds),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+		)
+		if err != nil {
+			t.Fatalf("grpc.NewClient: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+
+		return ssov1.NewAuthClient(conn)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123", AppId: 1}
+
+	if _, err := dial(credentials.NewTLS(&tls.Config{RootCAs: roots})).Login(ctx, req); err != nil {
+		t.Fatalf("Login over TLS: %v", err)
+	}
+	if _, err := dial(insecure.NewCredentials()).Login(ctx, req); err == nil {
+		t.Error("plaintext Login to TLS server succeeded, want error")
+	}
+}
+
+func TestTLSRequiresCertAndKey(t *testing.T) {
+	certFile, keyFile, _ := writeCert(t)
+
+	for _, tc := range []config.TLSConfig{
+		{CertFile: certFile},
+		{KeyFile: keyFile},
+		{CertFile: keyFile, KeyFile: certFile},
+	} {
+		cfg := config.GRPCConfig{MaxRecvMsgSize: 4 << 20, TLS: tc}
+		if _, err := New(discardLogger(), &fakeAuth{}, fakeValidator{}, cfg); err == nil {
+			t.Errorf("New with tls %+v succeeded, want error", tc)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	certFile, keyFile, _ := writeCert(t)
+
+	if err := Check(config.GRPCConfig{TLS: config.TLSConfig{CertFile: certFile, KeyFile: keyFile}}); err != nil {
+		t.Errorf("Check with valid TLS files: %v", err)
+	}
+
+	for name, cfg := range map[string]config.GRPCConfig{
+		"swapped tls files": {TLS: config.TLSConfig{CertFile: keyFile, KeyFile: certFile}},
+		"missing tls key":   {TLS: config.TLSConfig{CertFile: certFile}},
+		"bad allow cidr":    {IPFilter: config.IPFilterConfig{Allow: []string{"10.0.0.0/33"}}},
+		"bad deny cidr":     {IPFilter: config.IPFilterConfig{Deny: []string{"nope"}}},
+	} {
+		if err := Check(cfg); err == nil {
+			t.Errorf("Check with %s = nil, want error", name)
+		}
+	}
+}
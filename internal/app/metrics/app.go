@@ -0,0 +1,68 @@
+package metricsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type App struct {
+	log        *slog.Logger
+	httpServer *http.Server
+	port       int
+}
+
+// New creates new HTTP server app exposing Prometheus metrics on /metrics.
+func New(
+	log *slog.Logger,
+	port int,
+) *App {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return &App{
+		log:        log,
+		httpServer: &http.Server{Handler: mux},
+		port:       port,
+	}
+}
+
+// MustRun runs metrics server and panics if any error occurs.
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic(err)
+	}
+}
+
+// Run runs metrics server.
+func (a *App) Run() error {
+	const op = "metricsapp.Run"
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("metrics server started", slog.String("addr", l.Addr().String()))
+
+	if err := a.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop stops metrics server.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "metricsapp.Stop"
+
+	a.log.With(slog.String("op", op)).
+		Info("stopping metrics server", slog.Int("port", a.port))
+
+	return a.httpServer.Shutdown(ctx)
+}
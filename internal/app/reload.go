@@ -0,0 +1,83 @@
+package app
+
+import (
+	"log/slog"
+	"reflect"
+
+	"sso/internal/config"
+	"sso/internal/lib/logger"
+)
+
+// Reload applies hot-reloadable fields of cfg: log level and login rate
+// limit. Changes of other fields are logged and take effect after restart.
+// Cached apps are dropped too, so keys changed by manage-app apply without
+// waiting for auth.app_cache_ttl.
+func (a *App) Reload(cfg *config.Config) {
+	const op = "app.Reload"
+
+	log := a.log.With(slog.String("op", op))
+
+	current := a.cfg.Get()
+
+	for _, f := range restartOnlyChanges(current, cfg) {
+		log.Warn("config field changed, restart required to apply", slog.String("field", f))
+	}
+
+	next := *current
+	next.LogLevel = cfg.LogLevel
+	next.Auth.MaxLoginAttempts = cfg.Auth.MaxLoginAttempts
+	next.Auth.LockoutWindow = cfg.Auth.LockoutWindow
+
+	a.logLevel.Set(logger.Level(next.Env, next.LogLevel))
+	a.loginLimiter.SetLimits(next.Auth.MaxLoginAttempts, next.Auth.LockoutWindow)
+	a.cfg.Set(&next)
+	a.apps.InvalidateApps()
+
+	log.Info("config reloaded",
+		slog.String("log_level", a.logLevel.Level().String()),
+		slog.Int("max_login_attempts", next.Auth.MaxLoginAttempts),
+		slog.Duration("lockout_window", next.Auth.LockoutWindow),
+	)
+}
+
+// Config returns currently applied config.
+func (a *App) Config() *config.Config {
+	return a.cfg.Get()
+}
+
+func restartOnlyChanges(old, cfg *config.Config) []string {
+	// Rate limit is reloaded, rest of auth settings is not.
+	oldAuth, newAuth := old.Auth, cfg.Auth
+	oldAuth.MaxLoginAttempts, oldAuth.LockoutWindow = 0, 0
+	newAuth.MaxLoginAttempts, newAuth.LockoutWindow = 0, 0
+
+	fields := []struct {
+		name     string
+		old, new any
+	}{
+		{"env", old.Env, cfg.Env},
+		{"log_format", old.LogFormat, cfg.LogFormat},
+		{"log_file", old.LogFile, cfg.LogFile},
+		{"storage_path", old.StoragePath, cfg.StoragePath},
+		{"storage_path_file", old.StoragePathFile, cfg.StoragePathFile},
+		{"storage", old.Storage, cfg.Storage},
+		{"grpc", old.GRPC, cfg.GRPC},
+		{"migrations_path", old.MigrationsPath, cfg.MigrationsPath},
+		{"auth", oldAuth, newAuth},
+		{"metrics", old.Metrics, cfg.Metrics},
+		{"tracing", old.Tracing, cfg.Tracing},
+		{"audit", old.Audit, cfg.Audit},
+		{"apps", old.Apps, cfg.Apps},
+		{"bootstrap", old.Bootstrap, cfg.Bootstrap},
+		{"token_ttl", old.TokenTTL, cfg.TokenTTL},
+	}
+
+	var changed []string
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.old, f.new) {
+			changed = append(changed, f.name)
+		}
+	}
+
+	return changed
+}
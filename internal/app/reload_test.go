@@ -0,0 +1,119 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sso/internal/config"
+	"sso/internal/lib/ratelimit"
+)
+
+// countingApps counts InvalidateApps calls.
+type countingApps struct {
+	calls int
+}
+
+func (c *countingApps) InvalidateApps() {
+	c.calls++
+}
+
+func TestReload(t *testing.T) {
+	var buf bytes.Buffer
+
+	level := &slog.LevelVar{}
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))
+
+	current := &config.Config{
+		Env:         "prod",
+		LogLevel:    "info",
+		StoragePath: "./storage/sso.db",
+		Auth:        config.AuthConfig{MaxLoginAttempts: 1, LockoutWindow: time.Minute},
+	}
+	apps := &countingApps{}
+	a := &App{
+		log:          log,
+		logLevel:     level,
+		cfg:          config.NewHolder(current),
+		loginLimiter: ratelimit.NewSlidingWindow(1, time.Minute),
+		apps:         apps,
+	}
+
+	next := *current
+	next.LogLevel = "debug"
+	next.Auth.MaxLoginAttempts = 3
+	next.StoragePath = "./storage/other.db"
+
+	a.Reload(&next)
+
+	if apps.calls != 1 {
+		t.Errorf("InvalidateApps called %d times on reload, want 1", apps.calls)
+	}
+	if !log.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug logging disabled after reload to debug level")
+	}
+
+	a.loginLimiter.Fail("user@example.com")
+	if !a.loginLimiter.Allow("user@example.com") {
+		t.Error("limiter still blocks after one failure, want reloaded limit 3")
+	}
+
+	applied := a.Config()
+	if applied.LogLevel != "debug" || applied.Auth.MaxLoginAttempts != 3 {
+		t.Errorf("applied config log_level %q, max_login_attempts %d; want reloaded values",
+			applied.LogLevel, applied.Auth.MaxLoginAttempts)
+	}
+	if applied.StoragePath != current.StoragePath {
+		t.Errorf("applied storage_path = %q, want unchanged %q until restart", applied.StoragePath, current.StoragePath)
+	}
+	if !strings.Contains(buf.String(), "restart required") || !strings.Contains(buf.String(), "field=storage_path") {
+		t.Errorf("log = %q, want restart warning for storage_path", buf.String())
+	}
+}
+
+func TestRestartOnlyChanges(t *testing.T) {
+	base := config.Config{
+		Env:         "local",
+		LogLevel:    "info",
+		StoragePath: "./storage/sso.db",
+		Auth:        config.AuthConfig{MaxLoginAttempts: 5, LockoutWindow: time.Minute},
+		Apps:        []config.AppConfig{{ID: 1, Name: "web", Secret: "secret"}},
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *config.Config)
+		want   []string
+	}{
+		{"nothing", func(c *config.Config) {}, nil},
+		{"log_level is reloaded", func(c *config.Config) { c.LogLevel = "debug" }, nil},
+		{"rate limit is reloaded", func(c *config.Config) {
+			c.Auth.MaxLoginAttempts = 10
+			c.Auth.LockoutWindow = time.Hour
+		}, nil},
+		{"log_format", func(c *config.Config) { c.LogFormat = "json" }, []string{"log_format"}},
+		{"log_file", func(c *config.Config) { c.LogFile.Path = "/var/log/sso.log" }, []string{"log_file"}},
+		{"storage_path_file", func(c *config.Config) { c.StoragePathFile = "/run/secrets/dsn" }, []string{"storage_path_file"}},
+		{"apps", func(c *config.Config) {
+			c.Apps = []config.AppConfig{{ID: 1, Name: "web", Secret: "rotated"}}
+		}, []string{"apps"}},
+		{"bootstrap", func(c *config.Config) { c.Bootstrap.AdminEmail = "admin@example.com" }, []string{"bootstrap"}},
+		{"other auth setting", func(c *config.Config) { c.Auth.BcryptCost = 12 }, []string{"auth"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := base
+			next.Apps = append([]config.AppConfig(nil), base.Apps...)
+			tt.change(&next)
+
+			if got := restartOnlyChanges(&base, &next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restartOnlyChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
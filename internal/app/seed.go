@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"sso/internal/config"
+	"sso/internal/domain/models"
+)
+
+// appSeeder stores apps declared in config.
+type appSeeder interface {
+	UpsertApp(ctx context.Context, app models.App) error
+}
+
+// seedApps creates or updates apps from config. Safe to run on every start.
+func seedApps(ctx context.Context, log *slog.Logger, seeder appSeeder, apps []config.AppConfig) error {
+	const op = "app.seedApps"
+
+	for _, app := range apps {
+		err := seeder.UpsertApp(ctx, models.App{
+			ID:       app.ID,
+			Name:     app.Name,
+			Secret:   app.Secret,
+			TokenTTL: app.TokenTTL,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: app %d: %w", op, app.ID, err)
+		}
+
+		log.Info("app seeded", slog.Int("app_id", app.ID), slog.String("name", app.Name))
+	}
+
+	return nil
+}
+
+type appProvider interface {
+	App(ctx context.Context, id int) (models.App, error)
+}
+
+// checkDefaultApp fails if default app is set but doesn't exist, so Login
+// without app ID doesn't fail on every call.
+func checkDefaultApp(ctx context.Context, apps appProvider, appID int) error {
+	const op = "app.checkDefaultApp"
+
+	if appID == 0 {
+		return nil
+	}
+
+	if _, err := apps.App(ctx, appID); err != nil {
+		return fmt.Errorf("%s: default app %d: %w", op, appID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/config"
+	"sso/internal/storage"
+	"sso/internal/storage/memory"
+)
+
+func TestSeedApps(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	apps := []config.AppConfig{
+		{ID: 1, Name: "web", Secret: "web-secret"},
+		{ID: 2, Name: "mobile", Secret: "mobile-secret", TokenTTL: 5 * time.Minute},
+	}
+
+	// Seeding runs on every start, so a repeated run must succeed.
+	for run := 0; run < 2; run++ {
+		if err := seedApps(ctx, discardLogger(), store, apps); err != nil {
+			t.Fatalf("seedApps run %d: %v", run, err)
+		}
+	}
+
+	for _, want := range apps {
+		app, err := store.App(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("App(%d): %v", want.ID, err)
+		}
+		if app.Name != want.Name || app.Secret != want.Secret || app.TokenTTL != want.TokenTTL {
+			t.Errorf("App(%d) = %+v, want %+v", want.ID, app, want)
+		}
+	}
+
+	// Changed config updates seeded app.
+	apps[0].Secret = "rotated"
+	if err := seedApps(ctx, discardLogger(), store, apps); err != nil {
+		t.Fatalf("seedApps with rotated secret: %v", err)
+	}
+	if app, err := store.App(ctx, 1); err != nil || app.Secret != "rotated" {
+		t.Errorf("App(1) after reseed = %+v, %v; want rotated secret", app, err)
+	}
+}
+
+func TestCheckDefaultApp(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	if err := seedApps(ctx, discardLogger(), store, []config.AppConfig{{ID: 1, Name: "web", Secret: "web-secret"}}); err != nil {
+		t.Fatalf("seedApps: %v", err)
+	}
+
+	if err := checkDefaultApp(ctx, store, 0); err != nil {
+		t.Errorf("checkDefaultApp without default: %v", err)
+	}
+	if err := checkDefaultApp(ctx, store, 1); err != nil {
+		t.Errorf("checkDefaultApp of seeded app: %v", err)
+	}
+	if err := checkDefaultApp(ctx, store, 2); !errors.Is(err, storage.ErrAppNotFound) {
+		t.Errorf("checkDefaultApp of unknown app: got %v, want ErrAppNotFound", err)
+	}
+}
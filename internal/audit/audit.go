@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/requestinfo"
+)
+
+// Supported audit sinks.
+const (
+	SinkLog     = "log"
+	SinkStorage = "storage"
+)
+
+// SlogLogger writes audit events to slog logger.
+type SlogLogger struct {
+	log *slog.Logger
+}
+
+func NewSlog(log *slog.Logger) *SlogLogger {
+	return &SlogLogger{log: log.With(slog.String("component", "audit"))}
+}
+
+// Record logs event.
+func (l *SlogLogger) Record(ctx context.Context, event models.AuditEvent) {
+	event = withSource(ctx, event)
+
+	l.log.LogAttrs(ctx, slog.LevelInfo, "audit event",
+		slog.String("type", event.Type),
+		slog.Time("time", event.Time),
+		slog.Int64("user_id", event.UserID),
+		slog.String("email", event.Email),
+		slog.String("source_ip", event.SourceIP),
+		slog.String("result", event.Result),
+	)
+}
+
+type EventSaver interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+}
+
+// StorageLogger appends audit events to storage. Failures are logged and
+// don't affect the audited request.
+type StorageLogger struct {
+	log   *slog.Logger
+	saver EventSaver
+}
+
+func NewStorage(log *slog.Logger, saver EventSaver) *StorageLogger {
+	return &StorageLogger{log: log, saver: saver}
+}
+
+// Record saves event.
+func (l *StorageLogger) Record(ctx context.Context, event models.AuditEvent) {
+	const op = "audit.StorageLogger.Record"
+
+	event = withSource(ctx, event)
+
+	// Audit trail must be written even if request was cancelled.
+	if err := l.saver.SaveAuditEvent(context.WithoutCancel(ctx), event); err != nil {
+		l.log.Error("failed to save audit event",
+			slog.String("op", op),
+			slog.String("type", event.Type),
+			sl.Err(err),
+		)
+	}
+}
+
+// withSource fills event source IP from request info, if not set.
+func withSource(ctx context.Context, event models.AuditEvent) models.AuditEvent {
+	if event.SourceIP == "" {
+		event.SourceIP = requestinfo.ClientIP(ctx)
+	}
+
+	return event
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/requestinfo"
+)
+
+// saverFunc is EventSaver calling itself.
+type saverFunc func(ctx context.Context, event models.AuditEvent) error
+
+func (f saverFunc) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	return f(ctx, event)
+}
+
+var testEvent = models.AuditEvent{
+	Type:   models.AuditLogin,
+	Time:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	UserID: 1,
+	Email:  "user@example.com",
+	Result: "success",
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlog(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := requestinfo.WithInfo(context.Background(), requestinfo.Info{ClientIP: "10.0.0.1"})
+	l.Record(ctx, testEvent)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("decode log line %q: %v", buf.String(), err)
+	}
+
+	want := map[string]any{
+		"component": "audit",
+		"type":      models.AuditLogin,
+		"user_id":   float64(1),
+		"email":     "user@example.com",
+		"source_ip": "10.0.0.1",
+		"result":    "success",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestStorageLogger(t *testing.T) {
+	var saved []models.AuditEvent
+
+	l := NewStorage(slog.New(slog.NewTextHandler(io.Discard, nil)), saverFunc(func(ctx context.Context, event models.AuditEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		saved = append(saved, event)
+
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(requestinfo.WithInfo(context.Background(), requestinfo.Info{ClientIP: "10.0.0.1"}))
+	cancel()
+
+	// Cancelled request is still audited.
+	l.Record(ctx, testEvent)
+
+	withIP := testEvent
+	withIP.SourceIP = "10.0.0.1"
+	if len(saved) != 1 || saved[0] != withIP {
+		t.Fatalf("saved %+v, want [%+v]", saved, withIP)
+	}
+}
+
+func TestStorageLoggerFailureIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewStorage(slog.New(slog.NewTextHandler(&buf, nil)), saverFunc(func(context.Context, models.AuditEvent) error {
+		return errors.New("storage is down")
+	}))
+
+	l.Record(context.Background(), testEvent)
+
+	if !bytes.Contains(buf.Bytes(), []byte("failed to save audit event")) {
+		t.Errorf("log = %q, want failure logged", buf.String())
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"os"
 	"time"
@@ -8,47 +9,369 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// Allowed values of string settings. They mirror constants of the packages
+// consuming them (logger, audit, auth, jwt), which config doesn't import;
+// internal/app tests check they stay in sync.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+
+	AuditSinkLog     = "log"
+	AuditSinkStorage = "storage"
+
+	RevocationFailClosed = "fail-closed"
+	RevocationFailOpen   = "fail-open"
+)
+
+// TokenAlgorithms are values auth.token_algorithms may list.
+var TokenAlgorithms = []string{"HS256", "ES256"}
+
+// Config is application config. It's read from YAML file, and every field
+// can be overridden (or, without a file, fully set) by the environment
+// variable named in its env tag, e.g. STORAGE_PATH, AUTH_ACCESS_TOKEN_TTL, GRPC_PORT.
 type Config struct {
-	Env            string     `yaml:"env" env-default:"local"`
-	StoragePath    string     `yaml:"storage_path" env-required:"true"`
-	GRPC           GRPCConfig `yaml:"grpc"`
-	MigrationsPath string
-	TokenTTL       time.Duration `yaml:"token_ttl" env-default:"1h"`
+	Env            string        `yaml:"env" env:"ENV" env-default:"local"`
+	LogLevel       string        `yaml:"log_level" env:"LOG_LEVEL"`
+	LogFormat      string        `yaml:"log_format" env:"LOG_FORMAT"`
+	LogFile        LogFileConfig `yaml:"log_file" env-prefix:"LOG_FILE_"`
+	StoragePath    string        `yaml:"storage_path" env:"STORAGE_PATH"`
+	Storage        StorageConfig `yaml:"storage" env-prefix:"STORAGE_"`
+	GRPC           GRPCConfig    `yaml:"grpc" env-prefix:"GRPC_"`
+	MigrationsPath string        `yaml:"migrations_path" env:"MIGRATIONS_PATH"`
+	Auth           AuthConfig    `yaml:"auth" env-prefix:"AUTH_"`
+	Metrics        MetricsConfig `yaml:"metrics" env-prefix:"METRICS_"`
+	Tracing        TracingConfig `yaml:"tracing" env-prefix:"TRACING_"`
+	Audit          AuditConfig   `yaml:"audit" env-prefix:"AUDIT_"`
+	// Apps are created or updated in storage at startup. YAML only.
+	Apps      []AppConfig     `yaml:"apps"`
+	Bootstrap BootstrapConfig `yaml:"bootstrap" env-prefix:"BOOTSTRAP_"`
+
+	// StoragePathFile is file StoragePath is read from, e.g. a mounted
+	// secret with Postgres DSN.
+	StoragePathFile string `yaml:"storage_path_file" env:"STORAGE_PATH_FILE"`
+
+	// Deprecated: use Auth.AccessTokenTTL.
+	TokenTTL time.Duration `yaml:"token_ttl" env:"TOKEN_TTL"`
+	// Deprecated: use Auth.RefreshTokenTTL.
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+}
+
+// LogFileConfig sends logs to rotated file instead of stdout. Empty Path
+// means stdout.
+type LogFileConfig struct {
+	Path string `yaml:"path" env:"PATH"`
+	// MaxSizeMB is size file is rotated at.
+	MaxSizeMB int `yaml:"max_size_mb" env:"MAX_SIZE_MB" env-default:"100"`
+	// MaxBackups is number of rotated files kept; 0 keeps all.
+	MaxBackups int `yaml:"max_backups" env:"MAX_BACKUPS" env-default:"0"`
+	// MaxAgeDays is how long rotated files are kept; 0 keeps them forever.
+	MaxAgeDays int `yaml:"max_age_days" env:"MAX_AGE_DAYS" env-default:"0"`
+}
+
+type StorageConfig struct {
+	// Driver is "sqlite" (StoragePath is db file), "postgres"
+	// (StoragePath is DSN) or "memory" (StoragePath is unused).
+	Driver          string        `yaml:"driver" env:"DRIVER" env-default:"sqlite"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"MAX_OPEN_CONNS" env-default:"10"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"MAX_IDLE_CONNS" env-default:"5"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"CONN_MAX_LIFETIME" env-default:"1h"`
+	// RetryMaxAttempts is total attempts of a call failing with transient
+	// error; 1 disables retries.
+	RetryMaxAttempts int           `yaml:"retry_max_attempts" env:"RETRY_MAX_ATTEMPTS" env-default:"3"`
+	RetryBaseDelay   time.Duration `yaml:"retry_base_delay" env:"RETRY_BASE_DELAY" env-default:"50ms"`
+	// StartupTimeout is how long startup keeps retrying to reach storage
+	// before giving up; 0 means a single attempt.
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"STARTUP_TIMEOUT" env-default:"0"`
 }
 
 type GRPCConfig struct {
-	Port    int           `yaml:"port"`
-	Timeout time.Duration `yaml:"timeout"`
+	// Host to bind to; empty means all interfaces.
+	Host            string        `yaml:"host" env:"HOST"`
+	Port            int           `yaml:"port" env:"PORT"`
+	Timeout         time.Duration `yaml:"timeout" env:"TIMEOUT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+	TLS             TLSConfig     `yaml:"tls" env-prefix:"TLS_"`
+	// ProtectedMethods require a valid bearer token, e.g. "/auth.Auth/IsAdmin".
+	ProtectedMethods []string `yaml:"protected_methods" env:"PROTECTED_METHODS" env-separator:","`
+	// EnableReflection registers gRPC reflection service (for grpcurl etc.).
+	// Keep it off in prod.
+	EnableReflection bool `yaml:"enable_reflection" env:"ENABLE_REFLECTION" env-default:"false"`
+	// MaxRecvMsgSize limits incoming message size in bytes; bigger requests
+	// are rejected with ResourceExhausted.
+	MaxRecvMsgSize int `yaml:"max_recv_msg_size" env:"MAX_RECV_MSG_SIZE" env-default:"4194304"`
+	// SlowRequestThreshold makes calls slower than it logged at warn level.
+	// Zero disables it.
+	SlowRequestThreshold time.Duration `yaml:"slow_request_threshold" env:"SLOW_REQUEST_THRESHOLD" env-default:"1s"`
+	// Keepalive keeps long-lived client connections healthy.
+	Keepalive KeepaliveConfig `yaml:"keepalive" env-prefix:"KEEPALIVE_"`
+	// MaxConcurrent caps in-flight calls; calls over it fail with
+	// ResourceExhausted. Zero means unlimited.
+	MaxConcurrent int `yaml:"max_concurrent" env:"MAX_CONCURRENT" env-default:"0"`
+	// MaxConcurrentPerMethod gives methods own cap instead of sharing
+	// MaxConcurrent, e.g. "/auth.Auth/Login": 100; zero means unlimited.
+	MaxConcurrentPerMethod map[string]int `yaml:"max_concurrent_per_method" env:"MAX_CONCURRENT_PER_METHOD"`
+	// IPFilter restricts some methods to callers from given networks.
+	IPFilter IPFilterConfig `yaml:"ip_filter" env-prefix:"IP_FILTER_"`
+	// EnableGzip lets clients get gzip-compressed responses by sending
+	// gzip-compressed requests.
+	EnableGzip bool `yaml:"enable_gzip" env:"ENABLE_GZIP" env-default:"false"`
+	// DisabledMethods fail with Unimplemented, e.g. "/auth.Auth/Register"
+	// for a deployment without self-registration.
+	DisabledMethods []string `yaml:"disabled_methods" env:"DISABLED_METHODS" env-separator:","`
+}
+
+// KeepaliveConfig sets gRPC server keepalive and connection lifetime.
+// Zero durations mean gRPC defaults (infinite idle and age, 2h ping time).
+type KeepaliveConfig struct {
+	// MaxConnectionIdle closes connections without RPCs for this long.
+	MaxConnectionIdle time.Duration `yaml:"max_connection_idle" env:"MAX_CONNECTION_IDLE" env-default:"15m"`
+	// MaxConnectionAge closes connections after this long, so clients
+	// rebalance; MaxConnectionAgeGrace lets in-flight RPCs finish.
+	MaxConnectionAge      time.Duration `yaml:"max_connection_age" env:"MAX_CONNECTION_AGE" env-default:"2h"`
+	MaxConnectionAgeGrace time.Duration `yaml:"max_connection_age_grace" env:"MAX_CONNECTION_AGE_GRACE" env-default:"30s"`
+	// Time is ping interval of idle connection, Timeout is how long to wait
+	// for ping ack before closing it.
+	Time    time.Duration `yaml:"time" env:"TIME" env-default:"1m"`
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"20s"`
+	// MinTime is the shortest client ping interval allowed; clients pinging
+	// more often are disconnected.
+	MinTime             time.Duration `yaml:"min_time" env:"MIN_TIME" env-default:"30s"`
+	PermitWithoutStream bool          `yaml:"permit_without_stream" env:"PERMIT_WITHOUT_STREAM" env-default:"true"`
+}
+
+// IPFilterConfig rejects calls of Methods from Deny networks, and from
+// networks outside Allow unless it's empty. Deny wins over Allow. Networks
+// are IPv4 or IPv6 CIDRs.
+type IPFilterConfig struct {
+	Methods []string `yaml:"methods" env:"METHODS" env-separator:","`
+	Allow   []string `yaml:"allow" env:"ALLOW" env-separator:","`
+	Deny    []string `yaml:"deny" env:"DENY" env-separator:","`
+}
+
+// TLSConfig enables TLS for gRPC server when both files are set.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"KEY_FILE"`
+}
+
+// Enabled reports whether TLS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// MetricsConfig configures HTTP server exposing Prometheus metrics.
+// Zero Port disables it.
+type MetricsConfig struct {
+	Port int `yaml:"port" env:"PORT"`
+}
+
+// TracingConfig configures OTLP/gRPC trace exporter.
+// Tracing is a no-op unless Enabled is set.
+type TracingConfig struct {
+	Enabled     bool   `yaml:"enabled" env:"ENABLED" env-default:"false"`
+	Endpoint    string `yaml:"endpoint" env:"ENDPOINT" env-default:"localhost:4317"`
+	ServiceName string `yaml:"service_name" env:"SERVICE_NAME" env-default:"sso"`
+	Insecure    bool   `yaml:"insecure" env:"INSECURE" env-default:"false"`
+}
+
+// AuditConfig selects where authentication audit events go:
+// "log" (application log) or "storage" (audit_log table).
+type AuditConfig struct {
+	Sink string `yaml:"sink" env:"SINK" env-default:"log"`
+}
+
+type AuthConfig struct {
+	// AccessTokenTTL defaults to deprecated TokenTTL, then 1h.
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" env:"ACCESS_TOKEN_TTL"`
+	// RefreshTokenTTL defaults to deprecated top-level RefreshTokenTTL,
+	// then 720h.
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL"`
+
+	MaxLoginAttempts int                  `yaml:"max_login_attempts" env:"MAX_LOGIN_ATTEMPTS" env-default:"5"`
+	LockoutWindow    time.Duration        `yaml:"lockout_window" env:"LOCKOUT_WINDOW" env-default:"15m"`
+	PasswordPolicy   PasswordPolicyConfig `yaml:"password_policy" env-prefix:"PASSWORD_"`
+	// LockoutRetryAfter tells locked out clients when they can retry, in
+	// google.rpc.RetryInfo detail of ResourceExhausted.
+	LockoutRetryAfter bool `yaml:"lockout_retry_after" env:"LOCKOUT_RETRY_AFTER" env-default:"true"`
+
+	RequireVerifiedEmail bool          `yaml:"require_verified_email" env:"REQUIRE_VERIFIED_EMAIL" env-default:"false"`
+	VerificationTokenTTL time.Duration `yaml:"verification_token_ttl" env:"VERIFICATION_TOKEN_TTL" env-default:"24h"`
+	ResetTokenTTL        time.Duration `yaml:"reset_token_ttl" env:"RESET_TOKEN_TTL" env-default:"1h"`
+	// IdempotencyKeyTTL is how long Register remembers Idempotency-Key.
+	IdempotencyKeyTTL time.Duration `yaml:"idempotency_key_ttl" env:"IDEMPOTENCY_KEY_TTL" env-default:"24h"`
+
+	// DefaultAppID is app Login uses when request has no app_id; 0 means
+	// app_id is required.
+	DefaultAppID int `yaml:"default_app_id" env:"DEFAULT_APP_ID" env-default:"0"`
+	// Issuer is iss claim of issued tokens.
+	Issuer string `yaml:"issuer" env:"ISSUER" env-default:"sso"`
+	// ClockSkewLeeway is tolerated clock skew when verifying token expiry.
+	ClockSkewLeeway time.Duration `yaml:"clock_skew_leeway" env:"CLOCK_SKEW_LEEWAY" env-default:"30s"`
+
+	// BcryptWorkers bounds concurrent bcrypt hashing and comparisons; 0 means GOMAXPROCS.
+	BcryptWorkers int `yaml:"bcrypt_workers" env:"BCRYPT_WORKERS" env-default:"0"`
+	// BcryptCost is cost of new password hashes.
+	BcryptCost int `yaml:"bcrypt_cost" env:"BCRYPT_COST" env-default:"10"`
+	// RehashOnLogin re-hashes passwords stored with cost below BcryptCost
+	// on successful login.
+	RehashOnLogin bool `yaml:"rehash_on_login" env:"REHASH_ON_LOGIN" env-default:"false"`
+	// AppCacheTTL is how long app lookups are cached; 0 disables the cache.
+	AppCacheTTL time.Duration `yaml:"app_cache_ttl" env:"APP_CACHE_TTL" env-default:"30s"`
+	// AdminCacheTTL is how long IsAdmin results are cached; 0 disables the
+	// cache. AdminCacheSize bounds number of cached users.
+	AdminCacheTTL  time.Duration `yaml:"admin_cache_ttl" env:"ADMIN_CACHE_TTL" env-default:"10s"`
+	AdminCacheSize int           `yaml:"admin_cache_size" env:"ADMIN_CACHE_SIZE" env-default:"10000"`
+	// PrehashPasswords lets passwords exceed bcrypt's 72 byte limit by
+	// hashing them with SHA-256 first. Set it before any user is created.
+	PrehashPasswords bool `yaml:"prehash_passwords" env:"PREHASH_PASSWORDS" env-default:"false"`
+	// RevocationFailurePolicy decides what happens to tokens when revocation
+	// status can't be checked: "fail-closed" rejects them, "fail-open"
+	// accepts them.
+	RevocationFailurePolicy string `yaml:"revocation_failure_policy" env:"REVOCATION_FAILURE_POLICY" env-default:"fail-closed"`
+	// TokenAlgorithms are signing algorithms accepted in tokens, whatever
+	// their header claims; others, "none" included, are rejected.
+	TokenAlgorithms []string `yaml:"token_algorithms" env:"TOKEN_ALGORITHMS" env-separator:"," env-default:"HS256,ES256"`
+}
+
+// BootstrapConfig describes admin created at startup while there's no admin
+// yet. Empty AdminEmail disables it.
+type BootstrapConfig struct {
+	AdminEmail    string `yaml:"admin_email" env:"ADMIN_EMAIL"`
+	AdminPassword string `yaml:"admin_password" env:"ADMIN_PASSWORD"`
+	// AdminPasswordFile is file AdminPassword is read from.
+	AdminPasswordFile string `yaml:"admin_password_file" env:"ADMIN_PASSWORD_FILE"`
+}
+
+// AppConfig declares client app seeded at startup. Secret can be taken from
+// environment variable named by SecretEnv or from SecretFile instead of being
+// put in the config.
+type AppConfig struct {
+	ID        int    `yaml:"id"`
+	Name      string `yaml:"name"`
+	Secret    string `yaml:"secret"`
+	SecretEnv string `yaml:"secret_env"`
+	// SecretFile is file Secret is read from.
+	SecretFile string `yaml:"secret_file"`
+	// TokenTTL overrides auth.access_token_ttl for tokens of this app; 0
+	// means no override.
+	TokenTTL time.Duration `yaml:"token_ttl"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength    int  `yaml:"min_length" env:"MIN_LENGTH" env-default:"8"`
+	MaxLength    int  `yaml:"max_length" env:"MAX_LENGTH" env-default:"72"`
+	RequireDigit bool `yaml:"require_digit" env:"REQUIRE_DIGIT" env-default:"true"`
+	RequireUpper bool `yaml:"require_upper" env:"REQUIRE_UPPER" env-default:"true"`
+	RequireLower bool `yaml:"require_lower" env:"REQUIRE_LOWER" env-default:"true"`
 }
 
 func MustLoad() *Config {
-	configPath := fetchConfigPath()
-	if configPath == "" {
-		panic("config path is empty")
+	return MustLoadPath(FetchConfigPath())
+}
+
+// MustLoadPath reads config from configPath. If configPath is empty or the
+// file doesn't exist, config is read from environment variables only.
+func MustLoadPath(configPath string) *Config {
+	cfg, err := LoadPath(configPath)
+	if err != nil {
+		panic(err.Error())
 	}
 
-	return MustLoadPath(configPath)
+	return cfg
 }
 
-func MustLoadPath(configPath string) *Config {
+// LoadPath is MustLoadPath returning error instead of panicking.
+func LoadPath(configPath string) (*Config, error) {
+	if configPath == "" {
+		return LoadEnv()
+	}
+
 	// check if file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		panic("config file does not exist: " + configPath)
+		return LoadEnv()
 	}
 
 	var cfg Config
 
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		panic("cannot read config: " + err.Error())
+		return nil, errors.New("cannot read config: " + err.Error())
 	}
 
-	return &cfg
+	return validated(&cfg)
+}
+
+// MustLoadEnv reads config from environment variables only.
+func MustLoadEnv() *Config {
+	cfg, err := LoadEnv()
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return cfg
+}
+
+// LoadEnv is MustLoadEnv returning error instead of panicking.
+func LoadEnv() (*Config, error) {
+	var cfg Config
+
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		return nil, errors.New("cannot read config from env: " + err.Error())
+	}
+
+	return validated(&cfg)
+}
+
+const (
+	defaultAccessTokenTTL  = time.Hour
+	defaultRefreshTokenTTL = 720 * time.Hour
+)
+
+func validated(cfg *Config) (*Config, error) {
+	cfg.applyDeprecated()
+	cfg.resolveAppSecrets()
+
+	if err := cfg.readSecretFiles(); err != nil {
+		return nil, errors.New("invalid config:\n" + err.Error())
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.New("invalid config:\n" + err.Error())
+	}
+
+	return cfg, nil
+}
+
+// resolveAppSecrets reads secrets of apps that reference env variables.
+func (c *Config) resolveAppSecrets() {
+	for i := range c.Apps {
+		if c.Apps[i].SecretEnv != "" {
+			c.Apps[i].Secret = os.Getenv(c.Apps[i].SecretEnv)
+		}
+	}
+}
+
+// applyDeprecated maps deprecated fields to their replacements, which win
+// if both are set, and fills TTL defaults.
+func (c *Config) applyDeprecated() {
+	if c.Auth.AccessTokenTTL == 0 {
+		c.Auth.AccessTokenTTL = c.TokenTTL
+	}
+	if c.Auth.AccessTokenTTL == 0 {
+		c.Auth.AccessTokenTTL = defaultAccessTokenTTL
+	}
+
+	if c.Auth.RefreshTokenTTL == 0 {
+		c.Auth.RefreshTokenTTL = c.RefreshTokenTTL
+	}
+	if c.Auth.RefreshTokenTTL == 0 {
+		c.Auth.RefreshTokenTTL = defaultRefreshTokenTTL
+	}
 }
 
-// fetchConfigPath fetches config path from command line flag or environment variable.
+// FetchConfigPath fetches config path from command line flag or environment variable.
 // Priority: flag > env > default.
 // Default value is empty string.
-func fetchConfigPath() string {
+func FetchConfigPath() string {
 	var res string
 
 	flag.StringVar(&res, "config", "", "path to config file")
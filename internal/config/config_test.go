@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeConfig writes YAML config to a temp file and returns its path.
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("STORAGE_PATH", "/tmp/sso.db")
+	t.Setenv("GRPC_PORT", "44044")
+	t.Setenv("AUTH_ACCESS_TOKEN_TTL", "15m")
+
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "missing.yaml")} {
+		cfg, err := LoadPath(path)
+		if err != nil {
+			t.Fatalf("LoadPath(%q): %v", path, err)
+		}
+
+		if cfg.StoragePath != "/tmp/sso.db" || cfg.GRPC.Port != 44044 || cfg.Auth.AccessTokenTTL != 15*time.Minute {
+			t.Errorf("LoadPath(%q) = storage %q, port %d, ttl %s; want values from env",
+				path, cfg.StoragePath, cfg.GRPC.Port, cfg.Auth.AccessTokenTTL)
+		}
+		if cfg.Env != "local" || cfg.Auth.Issuer != "sso" {
+			t.Errorf("LoadPath(%q) = env %q, issuer %q; want defaults", path, cfg.Env, cfg.Auth.Issuer)
+		}
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeConfig(t, `
+storage_path: ./storage/sso.db
+grpc:
+  port: 40000
+`)
+	t.Setenv("GRPC_PORT", "44044")
+
+	cfg, err := LoadPath(path)
+	if err != nil {
+		t.Fatalf("LoadPath: %v", err)
+	}
+
+	if cfg.GRPC.Port != 44044 {
+		t.Errorf("grpc.port = %d, want 44044 from env", cfg.GRPC.Port)
+	}
+	if cfg.StoragePath != "./storage/sso.db" {
+		t.Errorf("storage_path = %q, want value from file", cfg.StoragePath)
+	}
+}
+
+func TestLoadEnvMissingRequired(t *testing.T) {
+	t.Setenv("GRPC_PORT", "44044")
+
+	if _, err := LoadEnv(); err == nil {
+		t.Fatal("LoadEnv without STORAGE_PATH succeeded, want error")
+	}
+}
+
+func TestLoadAppSecretEnv(t *testing.T) {
+	path := writeConfig(t, `
+storage_path: ./storage/sso.db
+grpc:
+  port: 44044
+apps:
+  - id: 1
+    name: web
+    secret_env: SSO_TEST_WEB_SECRET
+`)
+	t.Setenv("SSO_TEST_WEB_SECRET", "from-env")
+
+	cfg, err := LoadPath(path)
+	if err != nil {
+		t.Fatalf("LoadPath: %v", err)
+	}
+	if len(cfg.Apps) != 1 || cfg.Apps[0].Secret != "from-env" {
+		t.Errorf("apps = %+v, want web with secret from env", cfg.Apps)
+	}
+
+	t.Setenv("SSO_TEST_WEB_SECRET", "")
+	if _, err := LoadPath(path); err == nil {
+		t.Error("LoadPath with unset secret_env = nil error, want validation error")
+	}
+}
+
+func TestApplyDeprecated(t *testing.T) {
+	tests := []struct {
+		name                string
+		cfg                 Config
+		wantAccess, wantRef time.Duration
+	}{
+		{"defaults", Config{}, time.Hour, 720 * time.Hour},
+		{"deprecated keys", Config{TokenTTL: time.Minute, RefreshTokenTTL: 24 * time.Hour}, time.Minute, 24 * time.Hour},
+		{"new keys win", Config{
+			TokenTTL:        time.Minute,
+			RefreshTokenTTL: 24 * time.Hour,
+			Auth:            AuthConfig{AccessTokenTTL: 2 * time.Minute, RefreshTokenTTL: 48 * time.Hour},
+		}, 2 * time.Minute, 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		tt.cfg.applyDeprecated()
+		if tt.cfg.Auth.AccessTokenTTL != tt.wantAccess || tt.cfg.Auth.RefreshTokenTTL != tt.wantRef {
+			t.Errorf("%s: access %s, refresh %s; want %s, %s", tt.name,
+				tt.cfg.Auth.AccessTokenTTL, tt.cfg.Auth.RefreshTokenTTL, tt.wantAccess, tt.wantRef)
+		}
+	}
+}
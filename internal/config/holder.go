@@ -0,0 +1,26 @@
+package config
+
+import "sync/atomic"
+
+// Holder keeps current config, safe for concurrent use. It's swapped on
+// reload.
+type Holder struct {
+	cfg atomic.Pointer[Config]
+}
+
+func NewHolder(cfg *Config) *Holder {
+	h := &Holder{}
+	h.cfg.Store(cfg)
+
+	return h
+}
+
+// Get returns current config. It must not be modified.
+func (h *Holder) Get() *Config {
+	return h.cfg.Load()
+}
+
+// Set replaces current config.
+func (h *Holder) Set(cfg *Config) {
+	h.cfg.Store(cfg)
+}
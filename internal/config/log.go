@@ -0,0 +1,101 @@
+package config
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// redacted replaces sensitive values when config is logged.
+const redacted = "***"
+
+// LogValue implements slog.LogValuer. Fields that may carry credentials
+// (storage DSN, TLS key, app secrets, bootstrap admin password) are
+// redacted.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("env", c.Env),
+		slog.String("log_level", c.LogLevel),
+		slog.String("log_format", c.LogFormat),
+		slog.Any("log_file", c.LogFile),
+		slog.String("storage_path", redact(c.StoragePath)),
+		slog.String("storage_path_file", c.StoragePathFile),
+		slog.Any("storage", c.Storage),
+		slog.Any("grpc", c.GRPC),
+		slog.String("migrations_path", c.MigrationsPath),
+		slog.Any("auth", c.Auth),
+		slog.Any("metrics", c.Metrics),
+		slog.Any("tracing", c.Tracing),
+		slog.Any("audit", c.Audit),
+		slog.Any("apps", appsValue(c.Apps)),
+		slog.Any("bootstrap", c.Bootstrap),
+	)
+}
+
+// LogValue implements slog.LogValuer. Password is redacted.
+func (c BootstrapConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("admin_email", c.AdminEmail),
+		slog.String("admin_password", redact(c.AdminPassword)),
+		slog.String("admin_password_file", c.AdminPasswordFile),
+	)
+}
+
+// LogValue implements slog.LogValuer. Secret is redacted.
+func (c AppConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("id", c.ID),
+		slog.String("name", c.Name),
+		slog.String("secret", redact(c.Secret)),
+		slog.String("secret_env", c.SecretEnv),
+		slog.String("secret_file", c.SecretFile),
+		slog.Duration("token_ttl", c.TokenTTL),
+	)
+}
+
+// appsValue groups apps by index. Slice elements aren't resolved as
+// slog.LogValuer, so logging the slice itself would leak secrets.
+func appsValue(apps []AppConfig) slog.Value {
+	attrs := make([]slog.Attr, 0, len(apps))
+	for i, app := range apps {
+		attrs = append(attrs, slog.Any(strconv.Itoa(i), app))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue implements slog.LogValuer.
+func (c GRPCConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", c.Host),
+		slog.Int("port", c.Port),
+		slog.Duration("timeout", c.Timeout),
+		slog.Duration("shutdown_timeout", c.ShutdownTimeout),
+		slog.Any("tls", c.TLS),
+		slog.Any("protected_methods", c.ProtectedMethods),
+		slog.Bool("enable_reflection", c.EnableReflection),
+		slog.Int("max_recv_msg_size", c.MaxRecvMsgSize),
+		slog.Duration("slow_request_threshold", c.SlowRequestThreshold),
+		slog.Any("keepalive", c.Keepalive),
+		slog.Int("max_concurrent", c.MaxConcurrent),
+		slog.Any("max_concurrent_per_method", c.MaxConcurrentPerMethod),
+		slog.Any("ip_filter", c.IPFilter),
+		slog.Bool("enable_gzip", c.EnableGzip),
+		slog.Any("disabled_methods", c.DisabledMethods),
+	)
+}
+
+// LogValue implements slog.LogValuer. Key file path is redacted.
+func (c TLSConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("cert_file", c.CertFile),
+		slog.String("key_file", redact(c.KeyFile)),
+	)
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
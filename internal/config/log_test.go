@@ -0,0 +1,32 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogValueRedactsSecrets(t *testing.T) {
+	cfg := Config{
+		StoragePath: "postgres://sso:db-password@db/sso",
+		GRPC:        GRPCConfig{Port: 44044, TLS: TLSConfig{CertFile: "/tls/cert.pem", KeyFile: "/tls/key.pem"}},
+		Apps:        []AppConfig{{ID: 1, Name: "web", Secret: "app-secret"}},
+		Bootstrap:   BootstrapConfig{AdminEmail: "admin@example.com", AdminPassword: "admin-password"},
+	}
+
+	var out bytes.Buffer
+	slog.New(slog.NewJSONHandler(&out, nil)).Info("config", slog.Any("config", cfg))
+	logged := out.String()
+
+	for _, secret := range []string{"db-password", "/tls/key.pem", "app-secret", "admin-password"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("logged config contains %q: %s", secret, logged)
+		}
+	}
+	for _, visible := range []string{"44044", "/tls/cert.pem", "admin@example.com", `"name":"web"`} {
+		if !strings.Contains(logged, visible) {
+			t.Errorf("logged config lacks %q: %s", visible, logged)
+		}
+	}
+}
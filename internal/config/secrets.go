@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readSecretFiles fills fields whose value is given as a path in their
+// `_file` counterpart, e.g. storage_path_file, as Docker and Kubernetes
+// secrets are mounted. Setting both the value and its file is an error.
+func (c *Config) readSecretFiles() error {
+	var errs []error
+
+	read := func(name string, dst *string, path string) {
+		if path == "" {
+			return
+		}
+		if *dst != "" {
+			errs = append(errs, fmt.Errorf("set either %s or %s_file, not both", name, name))
+
+			return
+		}
+
+		v, err := readSecretFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s_file: %w", name, err))
+
+			return
+		}
+
+		*dst = v
+	}
+
+	read("storage_path", &c.StoragePath, c.StoragePathFile)
+	read("bootstrap.admin_password", &c.Bootstrap.AdminPassword, c.Bootstrap.AdminPasswordFile)
+	for i := range c.Apps {
+		read(fmt.Sprintf("apps[%d].secret", i), &c.Apps[i].Secret, c.Apps[i].SecretFile)
+	}
+
+	return errors.Join(errs...)
+}
+
+// readSecretFile returns file content without trailing newline.
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSecret writes secret file the way Docker mounts it, with trailing
+// newline, and returns its path.
+func writeSecret(t *testing.T, name, value string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(value+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	dsn := writeSecret(t, "dsn", "postgres://sso:pass@db:5432/sso")
+	secret := writeSecret(t, "web-secret", "from-file")
+
+	path := writeConfig(t, `
+storage_path_file: `+dsn+`
+storage:
+  driver: postgres
+grpc:
+  port: 44044
+apps:
+  - id: 1
+    name: web
+    secret_file: `+secret+`
+`)
+
+	cfg, err := LoadPath(path)
+	if err != nil {
+		t.Fatalf("LoadPath: %v", err)
+	}
+	if cfg.StoragePath != "postgres://sso:pass@db:5432/sso" {
+		t.Errorf("storage_path = %q, want file content without newline", cfg.StoragePath)
+	}
+	if cfg.Apps[0].Secret != "from-file" {
+		t.Errorf("apps[0].secret = %q, want from-file", cfg.Apps[0].Secret)
+	}
+}
+
+func TestReadSecretFilesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			"value and file",
+			Config{StoragePath: "./storage/sso.db", StoragePathFile: writeSecret(t, "dsn", "x")},
+			"set either storage_path or storage_path_file, not both",
+		},
+		{
+			"missing file",
+			Config{Apps: []AppConfig{{SecretFile: filepath.Join(t.TempDir(), "missing")}}},
+			"apps[0].secret_file:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.readSecretFiles()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readSecretFiles: got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
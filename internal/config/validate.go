@@ -0,0 +1,257 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"sso/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Validate checks values cleanenv tags can't express. All problems are
+// reported at once, joined into a single error.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.LogLevel != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("log_level: %w", err))
+		}
+	}
+	if c.LogFormat != "" && c.LogFormat != LogFormatJSON && c.LogFormat != LogFormatText {
+		errs = append(errs, fmt.Errorf("log_format must be %q or %q, got %q",
+			LogFormatJSON, LogFormatText, c.LogFormat))
+	}
+	if c.LogFile.MaxSizeMB < 1 {
+		errs = append(errs, fmt.Errorf("log_file.max_size_mb must be at least 1, got %d", c.LogFile.MaxSizeMB))
+	}
+	if c.LogFile.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log_file.max_backups must not be negative, got %d", c.LogFile.MaxBackups))
+	}
+	if c.LogFile.MaxAgeDays < 0 {
+		errs = append(errs, fmt.Errorf("log_file.max_age_days must not be negative, got %d", c.LogFile.MaxAgeDays))
+	}
+	if c.StoragePath == "" && c.Storage.Driver != storage.DriverMemory {
+		errs = append(errs, errors.New("storage_path is required"))
+	}
+	switch c.Storage.Driver {
+	case storage.DriverSQLite, storage.DriverPostgres, storage.DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver must be %q, %q or %q, got %q",
+			storage.DriverSQLite, storage.DriverPostgres, storage.DriverMemory, c.Storage.Driver))
+	}
+	if c.Storage.RetryMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("storage.retry_max_attempts must be at least 1, got %d", c.Storage.RetryMaxAttempts))
+	}
+	if c.Storage.RetryBaseDelay < 0 {
+		errs = append(errs, fmt.Errorf("storage.retry_base_delay must not be negative, got %s", c.Storage.RetryBaseDelay))
+	}
+	if c.Storage.StartupTimeout < 0 {
+		errs = append(errs, fmt.Errorf("storage.startup_timeout must not be negative, got %s", c.Storage.StartupTimeout))
+	}
+	if c.GRPC.Host != "" && !validHost(c.GRPC.Host) {
+		errs = append(errs, fmt.Errorf("grpc.host must be an IP address or hostname, got %q", c.GRPC.Host))
+	}
+	if c.GRPC.Port < 1 || c.GRPC.Port > 65535 {
+		errs = append(errs, fmt.Errorf("grpc.port must be in range 1-65535, got %d", c.GRPC.Port))
+	}
+	if c.GRPC.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("grpc.timeout must not be negative, got %s", c.GRPC.Timeout))
+	}
+	if c.GRPC.SlowRequestThreshold < 0 {
+		errs = append(errs, fmt.Errorf("grpc.slow_request_threshold must not be negative, got %s", c.GRPC.SlowRequestThreshold))
+	}
+	ka := c.GRPC.Keepalive
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"max_connection_idle", ka.MaxConnectionIdle},
+		{"max_connection_age", ka.MaxConnectionAge},
+		{"max_connection_age_grace", ka.MaxConnectionAgeGrace},
+		{"time", ka.Time},
+		{"timeout", ka.Timeout},
+		{"min_time", ka.MinTime},
+	} {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("grpc.keepalive.%s must not be negative, got %s", d.name, d.value))
+		}
+	}
+	if c.GRPC.MaxConcurrent < 0 {
+		errs = append(errs, fmt.Errorf("grpc.max_concurrent must not be negative, got %d", c.GRPC.MaxConcurrent))
+	}
+	for method, limit := range c.GRPC.MaxConcurrentPerMethod {
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("grpc.max_concurrent_per_method[%q] must not be negative, got %d", method, limit))
+		}
+	}
+	errs = append(errs, c.GRPC.IPFilter.validate()...)
+	for _, method := range c.GRPC.DisabledMethods {
+		if !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+			errs = append(errs, fmt.Errorf("grpc.disabled_methods: %q is not a full method name like \"/auth.Auth/Register\"", method))
+		}
+	}
+	if c.GRPC.MaxRecvMsgSize <= 0 {
+		errs = append(errs, fmt.Errorf("grpc.max_recv_msg_size must be positive, got %d", c.GRPC.MaxRecvMsgSize))
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("auth.access_token_ttl must be positive, got %s", c.Auth.AccessTokenTTL))
+	}
+	if c.Auth.MaxLoginAttempts <= 0 {
+		errs = append(errs, fmt.Errorf("auth.max_login_attempts must be positive, got %d", c.Auth.MaxLoginAttempts))
+	}
+	if c.Auth.LockoutWindow <= 0 {
+		errs = append(errs, fmt.Errorf("auth.lockout_window must be positive, got %s", c.Auth.LockoutWindow))
+	}
+	if c.Auth.VerificationTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("auth.verification_token_ttl must be positive, got %s", c.Auth.VerificationTokenTTL))
+	}
+	if c.Auth.ResetTokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("auth.reset_token_ttl must be positive, got %s", c.Auth.ResetTokenTTL))
+	}
+	if c.Auth.RefreshTokenTTL <= c.Auth.AccessTokenTTL {
+		errs = append(errs, fmt.Errorf("auth.refresh_token_ttl must be longer than access token ttl, got %s", c.Auth.RefreshTokenTTL))
+	}
+	if c.Auth.Issuer == "" {
+		errs = append(errs, errors.New("auth.issuer is required"))
+	}
+	if c.Auth.IdempotencyKeyTTL <= 0 {
+		errs = append(errs, fmt.Errorf("auth.idempotency_key_ttl must be positive, got %s", c.Auth.IdempotencyKeyTTL))
+	}
+	if c.Auth.ClockSkewLeeway < 0 {
+		errs = append(errs, fmt.Errorf("auth.clock_skew_leeway must not be negative, got %s", c.Auth.ClockSkewLeeway))
+	}
+	if c.Auth.AppCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("auth.app_cache_ttl must not be negative, got %s", c.Auth.AppCacheTTL))
+	}
+	if c.Auth.AdminCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("auth.admin_cache_ttl must not be negative, got %s", c.Auth.AdminCacheTTL))
+	}
+	if c.Auth.AdminCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("auth.admin_cache_size must not be negative, got %d", c.Auth.AdminCacheSize))
+	}
+	if c.Auth.BcryptWorkers < 0 {
+		errs = append(errs, fmt.Errorf("auth.bcrypt_workers must not be negative, got %d", c.Auth.BcryptWorkers))
+	}
+	if c.Auth.DefaultAppID < 0 {
+		errs = append(errs, fmt.Errorf("auth.default_app_id must not be negative, got %d", c.Auth.DefaultAppID))
+	}
+	if c.Auth.BcryptCost < bcrypt.MinCost || c.Auth.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("auth.bcrypt_cost must be between %d and %d, got %d",
+			bcrypt.MinCost, bcrypt.MaxCost, c.Auth.BcryptCost))
+	}
+	if p := c.Auth.RevocationFailurePolicy; p != RevocationFailClosed && p != RevocationFailOpen {
+		errs = append(errs, fmt.Errorf("auth.revocation_failure_policy must be %q or %q, got %q",
+			RevocationFailClosed, RevocationFailOpen, p))
+	}
+	if len(c.Auth.TokenAlgorithms) == 0 {
+		errs = append(errs, errors.New("auth.token_algorithms must not be empty"))
+	}
+	for _, alg := range c.Auth.TokenAlgorithms {
+		if !slices.Contains(TokenAlgorithms, alg) {
+			errs = append(errs, fmt.Errorf("auth.token_algorithms: unsupported algorithm %q", alg))
+		}
+	}
+	errs = append(errs, c.validateApps()...)
+	if (c.Bootstrap.AdminEmail == "") != (c.Bootstrap.AdminPassword == "") {
+		errs = append(errs, errors.New("bootstrap.admin_email and bootstrap.admin_password must be set together"))
+	}
+	if c.Audit.Sink != AuditSinkLog && c.Audit.Sink != AuditSinkStorage {
+		errs = append(errs, fmt.Errorf("audit.sink must be %q or %q, got %q",
+			AuditSinkLog, AuditSinkStorage, c.Audit.Sink))
+	}
+	if c.Metrics.Port < 0 || c.Metrics.Port > 65535 {
+		errs = append(errs, fmt.Errorf("metrics.port must be in range 0-65535, got %d", c.Metrics.Port))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateApps() []error {
+	var errs []error
+
+	ids := make(map[int]struct{}, len(c.Apps))
+	for i, app := range c.Apps {
+		if app.ID <= 0 {
+			errs = append(errs, fmt.Errorf("apps[%d].id must be positive, got %d", i, app.ID))
+		}
+		if _, ok := ids[app.ID]; ok {
+			errs = append(errs, fmt.Errorf("apps[%d].id %d is duplicated", i, app.ID))
+		}
+		ids[app.ID] = struct{}{}
+
+		if app.Name == "" {
+			errs = append(errs, fmt.Errorf("apps[%d].name is required", i))
+		}
+		if app.Secret == "" {
+			if app.SecretEnv != "" {
+				errs = append(errs, fmt.Errorf("apps[%d].secret_env: %s is not set", i, app.SecretEnv))
+			} else {
+				errs = append(errs, fmt.Errorf("apps[%d].secret is required", i))
+			}
+		}
+		if app.TokenTTL < 0 {
+			errs = append(errs, fmt.Errorf("apps[%d].token_ttl must not be negative, got %s", i, app.TokenTTL))
+		}
+	}
+
+	return errs
+}
+
+// validHost reports whether host is an IP address or RFC 1123 hostname.
+func validHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if len(label) < 1 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (c IPFilterConfig) validate() []error {
+	var errs []error
+
+	for _, list := range []struct {
+		name  string
+		cidrs []string
+	}{
+		{"allow", c.Allow},
+		{"deny", c.Deny},
+	} {
+		for _, cidr := range list.cidrs {
+			if _, err := netip.ParsePrefix(cidr); err != nil {
+				errs = append(errs, fmt.Errorf("grpc.ip_filter.%s: invalid CIDR %q", list.name, cidr))
+			}
+		}
+	}
+
+	if len(c.Methods) == 0 && (len(c.Allow) > 0 || len(c.Deny) > 0) {
+		errs = append(errs, errors.New("grpc.ip_filter.methods must be set when allow or deny is"))
+	}
+
+	return errs
+}
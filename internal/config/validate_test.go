@@ -0,0 +1,137 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns config passing Validate.
+func validConfig() Config {
+	return Config{
+		Env:         "local",
+		StoragePath: "./storage/sso.db",
+		LogFile:     LogFileConfig{MaxSizeMB: 100},
+		Storage:     StorageConfig{Driver: "sqlite", RetryMaxAttempts: 3},
+		GRPC:        GRPCConfig{Port: 44044, MaxRecvMsgSize: 4 << 20},
+		Auth: AuthConfig{
+			AccessTokenTTL:          time.Hour,
+			RefreshTokenTTL:         720 * time.Hour,
+			VerificationTokenTTL:    24 * time.Hour,
+			ResetTokenTTL:           time.Hour,
+			MaxLoginAttempts:        5,
+			LockoutWindow:           15 * time.Minute,
+			Issuer:                  "sso",
+			IdempotencyKeyTTL:       24 * time.Hour,
+			BcryptCost:              10,
+			RevocationFailurePolicy: "fail-closed",
+			TokenAlgorithms:         []string{"HS256"},
+		},
+		Audit: AuditConfig{Sink: "log"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := validConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate of valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"log level", func(c *Config) { c.LogLevel = "loud" }, "log_level"},
+		{"log file size", func(c *Config) { c.LogFile.MaxSizeMB = 0 }, "log_file.max_size_mb must be at least 1"},
+		{"log file backups", func(c *Config) { c.LogFile.MaxBackups = -1 }, "log_file.max_backups must not be negative"},
+		{"storage path", func(c *Config) { c.StoragePath = "" }, "storage_path is required"},
+		{"startup timeout", func(c *Config) { c.Storage.StartupTimeout = -time.Second }, "storage.startup_timeout must not be negative"},
+		{"driver", func(c *Config) { c.Storage.Driver = "mysql" }, "storage.driver must be"},
+		{"port", func(c *Config) { c.GRPC.Port = 70000 }, "grpc.port must be in range 1-65535, got 70000"},
+		{"max concurrent", func(c *Config) { c.GRPC.MaxConcurrent = -1 }, "grpc.max_concurrent must not be negative"},
+		{"max concurrent per method", func(c *Config) {
+			c.GRPC.MaxConcurrentPerMethod = map[string]int{"/auth.Auth/Login": -1}
+		}, `grpc.max_concurrent_per_method["/auth.Auth/Login"] must not be negative`},
+		{"ip filter cidr", func(c *Config) {
+			c.GRPC.IPFilter = IPFilterConfig{Methods: []string{"/auth.Auth/IsAdmin"}, Allow: []string{"10.0.0.1"}}
+		}, `grpc.ip_filter.allow: invalid CIDR "10.0.0.1"`},
+		{"ip filter methods", func(c *Config) {
+			c.GRPC.IPFilter = IPFilterConfig{Deny: []string{"10.0.0.0/8"}}
+		}, "grpc.ip_filter.methods must be set"},
+		{"disabled method", func(c *Config) { c.GRPC.DisabledMethods = []string{"Register"} }, `grpc.disabled_methods: "Register" is not a full method name`},
+		{"default app id", func(c *Config) { c.Auth.DefaultAppID = -1 }, "auth.default_app_id must not be negative"},
+		{"token ttl", func(c *Config) { c.Auth.AccessTokenTTL = 0 }, "auth.access_token_ttl must be positive"},
+		{"max login attempts", func(c *Config) { c.Auth.MaxLoginAttempts = 0 }, "auth.max_login_attempts must be positive"},
+		{"lockout window", func(c *Config) { c.Auth.LockoutWindow = 0 }, "auth.lockout_window must be positive"},
+		{"verification token ttl", func(c *Config) { c.Auth.VerificationTokenTTL = 0 }, "auth.verification_token_ttl must be positive"},
+		{"reset token ttl", func(c *Config) { c.Auth.ResetTokenTTL = 0 }, "auth.reset_token_ttl must be positive"},
+		{"refresh token ttl", func(c *Config) { c.Auth.RefreshTokenTTL = time.Hour }, "auth.refresh_token_ttl must be longer than access token ttl"},
+		{"bcrypt cost", func(c *Config) { c.Auth.BcryptCost = 99 }, "auth.bcrypt_cost must be between"},
+		{"revocation policy", func(c *Config) { c.Auth.RevocationFailurePolicy = "fail-maybe" }, "auth.revocation_failure_policy must be"},
+		{"algorithm", func(c *Config) { c.Auth.TokenAlgorithms = []string{"none"} }, `unsupported algorithm "none"`},
+		{"no algorithms", func(c *Config) { c.Auth.TokenAlgorithms = nil }, "auth.token_algorithms must not be empty"},
+		{"app secret", func(c *Config) { c.Apps = []AppConfig{{ID: 1, Name: "web"}} }, "apps[0].secret is required"},
+		{"bootstrap", func(c *Config) { c.Bootstrap.AdminEmail = "admin@example.com" }, "must be set together"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.change(&cfg)
+
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate: got %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.StoragePath = ""
+	cfg.GRPC.Port = 0
+	cfg.Auth.Issuer = ""
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded, want error")
+	}
+
+	for _, want := range []string{"storage_path", "grpc.port", "auth.issuer"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate error %q doesn't mention %s", err, want)
+		}
+	}
+}
+
+func TestValidHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"localhost", true},
+		{"sso.internal.example.com.", true},
+		{"bad host", false},
+		{"-leading.example.com", false},
+		{"trailing-.example.com", false},
+		{"a..b", false},
+		{"127.0.0.1:44044", false},
+		{strings.Repeat("a", 64) + ".com", false},
+	}
+
+	for _, tt := range tests {
+		if got := validHost(tt.host); got != tt.want {
+			t.Errorf("validHost(%q) = %t, want %t", tt.host, got, tt.want)
+		}
+	}
+
+	cfg := validConfig()
+	cfg.GRPC.Host = "bad host"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "grpc.host must be an IP address or hostname") {
+		t.Errorf("Validate with bad grpc.host: got %v", err)
+	}
+}
@@ -1,7 +1,65 @@
 package models
 
+import "time"
+
 type App struct {
-	ID     int
-	Name   string
+	ID   int
+	Name string
+	// Secret is legacy signing key, used for tokens without kid and when
+	// app has no active key.
 	Secret string
+	Keys   []AppKey
+	// SecretExpiresAt is when Secret stops verifying tokens after rotation;
+	// zero means never.
+	SecretExpiresAt time.Time
+	// TokenTTL overrides global access token TTL for this app when set.
+	TokenTTL time.Duration
+	// PrivateKey and PublicKey are PEM-encoded ECDSA P-256 key pair. When
+	// PrivateKey is set, tokens are signed with ES256 instead of HS256.
+	PrivateKey string
+	PublicKey  string
+}
+
+// AppKey is one of app signing keys. New tokens are signed with the active
+// key; inactive keys still verify tokens issued before rotation.
+type AppKey struct {
+	ID        string
+	Secret    string
+	Active    bool
+	CreatedAt time.Time
+	// ExpiresAt is when retired key stops verifying tokens; zero means never.
+	ExpiresAt time.Time
+}
+
+// Expired reports whether key no longer verifies tokens at now.
+func (k AppKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// SecretExpired reports whether legacy Secret no longer verifies tokens at
+// now.
+func (a App) SecretExpired(now time.Time) bool {
+	return !a.SecretExpiresAt.IsZero() && !now.Before(a.SecretExpiresAt)
+}
+
+// ActiveKey returns key new tokens are signed with.
+func (a App) ActiveKey() (AppKey, bool) {
+	for _, k := range a.Keys {
+		if k.Active {
+			return k, true
+		}
+	}
+
+	return AppKey{}, false
+}
+
+// Key returns key with given id.
+func (a App) Key(id string) (AppKey, bool) {
+	for _, k := range a.Keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+
+	return AppKey{}, false
 }
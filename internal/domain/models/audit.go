@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Audit event types.
+const (
+	AuditLogin    = "login"
+	AuditRegister = "register"
+	AuditIsAdmin  = "is_admin"
+)
+
+// AuditEvent is a single authentication decision recorded to audit trail.
+type AuditEvent struct {
+	Type     string
+	Time     time.Time
+	UserID   int64
+	Email    string
+	SourceIP string
+	Result   string
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers result of a request made with client supplied
+// key, so retries get the same result.
+type IdempotencyKey struct {
+	Key       string
+	Email     string
+	UserID    int64
+	ExpiresAt time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+const (
+	RoleAdmin = "admin"
+
+	// PermissionAll grants every permission.
+	PermissionAll = "*"
+)
+
+type Role struct {
+	ID          int
+	Name        string
+	Permissions []string
+}
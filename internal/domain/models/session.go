@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session is access token issued to user, identified by its jti.
+type Session struct {
+	JTI       string
+	UserID    int64
+	AppID     int
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// LastSeenIP is address of the caller the token was issued to.
+	LastSeenIP string
+}
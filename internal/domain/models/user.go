@@ -4,4 +4,11 @@ type User struct {
 	ID       int64
 	Email    string
 	PassHash []byte
+	// Username is optional alternative login identifier; empty if not set.
+	Username string
+
+	EmailVerified bool
+	// TokenVersion is put in issued tokens; tokens of lower version are
+	// rejected, so incrementing it revokes all of them.
+	TokenVersion int64
 }
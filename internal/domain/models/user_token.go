@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UserToken purposes.
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// UserToken is one-time token issued to user for a specific purpose.
+type UserToken struct {
+	Token     string
+	UserID    int64
+	Purpose   string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"sso/internal/services/auth"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// fieldViolation describes single invalid request field.
+func fieldViolation(field, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	}
+}
+
+// validationError builds InvalidArgument status carrying google.rpc.BadRequest
+// detail with given field violations, so clients can render per-field errors.
+func validationError(violations ...*errdetails.BadRequest_FieldViolation) error {
+	descriptions := make([]string, 0, len(violations))
+	for _, v := range violations {
+		descriptions = append(descriptions, v.GetDescription())
+	}
+
+	st := status.New(codes.InvalidArgument, strings.Join(descriptions, "; "))
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// lockoutError builds ResourceExhausted status carrying google.rpc.RetryInfo
+// detail, so clients know when to retry login.
+func lockoutError(retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, "too many login attempts")
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// sentinelStatuses maps service errors handlers may return to gRPC statuses.
+// Order matters only for errors matching several entries.
+var sentinelStatuses = []struct {
+	err  error
+	code codes.Code
+	msg  string
+}{
+	{auth.ErrInvalidCredentials, codes.Unauthenticated, "invalid email or password"},
+	{auth.ErrInvalidToken, codes.Unauthenticated, "invalid token"},
+	{auth.ErrTokenRevoked, codes.Unauthenticated, "invalid token"},
+	{auth.ErrInvalidRefreshToken, codes.Unauthenticated, "invalid refresh token"},
+	{auth.ErrTooManyAttempts, codes.ResourceExhausted, "too many login attempts"},
+	{auth.ErrEmailNotVerified, codes.FailedPrecondition, "email not verified"},
+	{auth.ErrUserAlreadyExists, codes.AlreadyExists, "user already exists"},
+	{auth.ErrUsernameTaken, codes.AlreadyExists, "username already taken"},
+	{auth.ErrUserNotFound, codes.NotFound, "user not found"},
+	{auth.ErrPermissionDenied, codes.PermissionDenied, "permission denied"},
+	{auth.ErrUnknownRole, codes.InvalidArgument, "unknown role"},
+	{auth.ErrIdempotencyKeyReused, codes.InvalidArgument, "idempotency key reused with different request"},
+	{auth.ErrInvalidVerificationToken, codes.InvalidArgument, "invalid verification token"},
+	{auth.ErrInvalidResetToken, codes.InvalidArgument, "invalid password reset token"},
+}
+
+// toGRPCError converts error returned by Auth service to gRPC status error.
+// Known sentinels get their own code, validation errors carry field
+// violations, context errors become Canceled/DeadlineExceeded; anything
+// else is Internal with msg, so internal details don't leak to clients.
+func toGRPCError(err error, msg string) error {
+	if errors.Is(err, auth.ErrInvalidEmail) {
+		return validationError(fieldViolation("email", "invalid email"))
+	}
+
+	if errors.Is(err, auth.ErrInvalidUsername) {
+		return validationError(fieldViolation("email", "invalid email or username"))
+	}
+
+	var weakErr *auth.WeakPasswordError
+	if errors.As(err, &weakErr) {
+		return validationError(fieldViolation("password", "password "+weakErr.Rule))
+	}
+
+	if errors.Is(err, auth.ErrPasswordTooLong) {
+		return validationError(fieldViolation("password", auth.ErrPasswordTooLong.Error()))
+	}
+
+	if errors.Is(err, auth.ErrPasswordRequired) {
+		return validationError(fieldViolation("password", "password is required"))
+	}
+
+	if errors.Is(err, auth.ErrInvalidAppID) {
+		return validationError(fieldViolation("app_id", "invalid app_id"))
+	}
+
+	var lockoutErr *auth.LockoutError
+	if errors.As(err, &lockoutErr) && lockoutErr.RetryAfter > 0 {
+		return lockoutError(lockoutErr.RetryAfter)
+	}
+
+	for _, s := range sentinelStatuses {
+		if errors.Is(err, s.err) {
+			return status.Error(s.code, s.msg)
+		}
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Error(codes.Internal, msg)
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"sso/internal/services/auth"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidationErrorDetails(t *testing.T) {
+	err := validationError(
+		fieldViolation("email", "email is required"),
+		fieldViolation("password", "password is required"),
+	)
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %s, want InvalidArgument", st.Code())
+	}
+	if want := "email is required; password is required"; st.Message() != want {
+		t.Errorf("message = %q, want %q", st.Message(), want)
+	}
+
+	var got []*errdetails.BadRequest_FieldViolation
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			got = append(got, br.GetFieldViolations()...)
+		}
+	}
+	if len(got) != 2 || got[1].GetField() != "password" || got[1].GetDescription() != "password is required" {
+		t.Errorf("field violations = %v, want email and password", got)
+	}
+}
+
+func TestServiceValidationErrorsCarryField(t *testing.T) {
+	tests := []struct {
+		err   error
+		field string
+	}{
+		{auth.ErrInvalidEmail, "email"},
+		{&auth.WeakPasswordError{Rule: "must contain a digit"}, "password"},
+		{auth.ErrPasswordTooLong, "password"},
+		{auth.ErrInvalidAppID, "app_id"},
+		{auth.ErrPasswordRequired, "password"},
+		{auth.ErrInvalidUsername, "email"},
+	}
+
+	for _, tt := range tests {
+		code, fields := fieldViolations(t, toGRPCError(fmt.Errorf("Auth.Login: %w", tt.err), "failed"))
+		if code != codes.InvalidArgument || !reflect.DeepEqual(fields, []string{tt.field}) {
+			t.Errorf("toGRPCError(%v) = %s %v, want InvalidArgument [%s]", tt.err, code, fields, tt.field)
+		}
+	}
+}
+
+func TestToGRPCErrorSentinels(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+		msg  string
+	}{
+		{auth.ErrInvalidCredentials, codes.Unauthenticated, "invalid email or password"},
+		{auth.ErrInvalidToken, codes.Unauthenticated, "invalid token"},
+		{auth.ErrTokenRevoked, codes.Unauthenticated, "invalid token"},
+		{auth.ErrInvalidRefreshToken, codes.Unauthenticated, "invalid refresh token"},
+		{auth.ErrTooManyAttempts, codes.ResourceExhausted, "too many login attempts"},
+		{auth.ErrEmailNotVerified, codes.FailedPrecondition, "email not verified"},
+		{auth.ErrUserAlreadyExists, codes.AlreadyExists, "user already exists"},
+		{auth.ErrUsernameTaken, codes.AlreadyExists, "username already taken"},
+		{auth.ErrUserNotFound, codes.NotFound, "user not found"},
+		{auth.ErrPermissionDenied, codes.PermissionDenied, "permission denied"},
+		{auth.ErrUnknownRole, codes.InvalidArgument, "unknown role"},
+		{auth.ErrIdempotencyKeyReused, codes.InvalidArgument, "idempotency key reused with different request"},
+		{auth.ErrInvalidVerificationToken, codes.InvalidArgument, "invalid verification token"},
+		{auth.ErrInvalidResetToken, codes.InvalidArgument, "invalid password reset token"},
+	}
+
+	if len(tests) != len(sentinelStatuses) {
+		t.Fatalf("test covers %d sentinels, sentinelStatuses has %d", len(tests), len(sentinelStatuses))
+	}
+
+	for _, tt := range tests {
+		st := status.Convert(toGRPCError(fmt.Errorf("Auth.Login: %w", tt.err), "failed to login"))
+		if st.Code() != tt.code || st.Message() != tt.msg {
+			t.Errorf("toGRPCError(%v) = %s %q, want %s %q", tt.err, st.Code(), st.Message(), tt.code, tt.msg)
+		}
+	}
+}
+
+func TestToGRPCErrorUnknown(t *testing.T) {
+	err := fmt.Errorf("Auth.Login: %w", errors.New("storage.sqlite.User: database is locked"))
+
+	st := status.Convert(toGRPCError(err, "failed to login"))
+	if st.Code() != codes.Internal || st.Message() != "failed to login" {
+		t.Errorf("toGRPCError(unknown) = %s %q, want Internal \"failed to login\"", st.Code(), st.Message())
+	}
+}
+
+// retryDelay returns delay of RetryInfo detail of st, false if it has none.
+func retryDelay(st *status.Status) (time.Duration, bool) {
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
+
+func TestToGRPCErrorLockout(t *testing.T) {
+	err := fmt.Errorf("Auth.Login: %w", &auth.LockoutError{RetryAfter: 42 * time.Second})
+
+	st := status.Convert(toGRPCError(err, "failed to login"))
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %s, want ResourceExhausted", st.Code())
+	}
+	if delay, ok := retryDelay(st); !ok || delay != 42*time.Second {
+		t.Errorf("RetryInfo delay = %s (present %t), want 42s", delay, ok)
+	}
+
+	// Undisclosed lockout time gives plain ResourceExhausted.
+	st = status.Convert(toGRPCError(fmt.Errorf("Auth.Login: %w", &auth.LockoutError{}), "failed to login"))
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code without retry after = %s, want ResourceExhausted", st.Code())
+	}
+	if _, ok := retryDelay(st); ok {
+		t.Error("RetryInfo present without retry after, want none")
+	}
+}
@@ -2,13 +2,15 @@ package auth
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"reflect"
+
 	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
-	"sso/internal/services/auth"
-	"sso/internal/storage"
 )
 
 type Auth interface {
@@ -23,41 +25,63 @@ type Auth interface {
 		email string,
 		password string,
 	) (userID int64, err error)
+	RegisterNewUserIdempotent(
+		ctx context.Context,
+		key string,
+		email string,
+		password string,
+	) (userID int64, err error)
 	IsAdmin(ctx context.Context, userID int64) (bool, error)
 }
 
+// idempotencyKeyMetadata is optional Register metadata; retries with the
+// same key get the same user ID instead of AlreadyExists.
+const idempotencyKeyMetadata = "idempotency-key"
+
 type serverAPI struct {
 	ssov1.UnimplementedAuthServer
 	auth Auth
 }
 
+// NewServerAPI returns Auth gRPC handlers backed by auth. It panics if auth
+// is nil, so a miswired server fails at startup rather than on first call.
+func NewServerAPI(auth Auth) *serverAPI {
+	if auth == nil {
+		panic("grpc/auth: NewServerAPI called with nil Auth service")
+	}
+	if v := reflect.ValueOf(auth); v.Kind() == reflect.Pointer && v.IsNil() {
+		panic(fmt.Sprintf("grpc/auth: NewServerAPI called with nil %T", auth))
+	}
+
+	return &serverAPI{auth: auth}
+}
+
 func Register(gRPCServer *grpc.Server, auth Auth) {
-	ssov1.RegisterAuthServer(gRPCServer, &serverAPI{auth: auth})
+	ssov1.RegisterAuthServer(gRPCServer, NewServerAPI(auth))
 }
 
 func (s *serverAPI) Login(
 	ctx context.Context,
 	in *ssov1.LoginRequest,
 ) (*ssov1.LoginResponse, error) {
-	if in.Email == "" {
-		return nil, status.Error(codes.InvalidArgument, "email is required")
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	if in.GetEmail() == "" {
+		violations = append(violations, fieldViolation("email", "email is required"))
 	}
 
-	if in.Password == "" {
-		return nil, status.Error(codes.InvalidArgument, "password is required")
+	if in.GetPassword() == "" {
+		violations = append(violations, fieldViolation("password", "password is required"))
 	}
 
-	if in.GetAppId() == 0 {
-		return nil, status.Error(codes.InvalidArgument, "app_id is required")
+	if len(violations) > 0 {
+		return nil, validationError(violations...)
 	}
 
+	// Zero app_id is left to the service, which may have a default app.
 	token, err := s.auth.Login(ctx, in.GetEmail(), in.GetPassword(), int(in.GetAppId()))
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid email or password")
-		}
-
-		return nil, status.Error(codes.Internal, "failed to login")
+		return nil, toGRPCError(err, "failed to login")
 	}
 
 	return &ssov1.LoginResponse{Token: token}, nil
@@ -67,41 +91,61 @@ func (s *serverAPI) Register(
 	ctx context.Context,
 	in *ssov1.RegisterRequest,
 ) (*ssov1.RegisterResponse, error) {
-	if in.Email == "" {
-		return nil, status.Error(codes.InvalidArgument, "email is required")
+	var violations []*errdetails.BadRequest_FieldViolation
+
+	if in.GetEmail() == "" {
+		violations = append(violations, fieldViolation("email", "email is required"))
 	}
 
-	if in.Password == "" {
-		return nil, status.Error(codes.InvalidArgument, "password is required")
+	if in.GetPassword() == "" {
+		violations = append(violations, fieldViolation("password", "password is required"))
 	}
 
-	uid, err := s.auth.RegisterNewUser(ctx, in.GetEmail(), in.GetPassword())
-	if err != nil {
-		if errors.Is(err, storage.ErrUserExists) {
-			return nil, status.Error(codes.AlreadyExists, "user already exists")
-		}
+	if len(violations) > 0 {
+		return nil, validationError(violations...)
+	}
+
+	var (
+		uid int64
+		err error
+	)
 
-		return nil, status.Error(codes.Internal, "failed to register user")
+	if key := idempotencyKey(ctx); key != "" {
+		uid, err = s.auth.RegisterNewUserIdempotent(ctx, key, in.GetEmail(), in.GetPassword())
+	} else {
+		uid, err = s.auth.RegisterNewUser(ctx, in.GetEmail(), in.GetPassword())
+	}
+	if err != nil {
+		return nil, toGRPCError(err, "failed to register user")
 	}
 
 	return &ssov1.RegisterResponse{UserId: uid}, nil
 }
 
+func idempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if values := md.Get(idempotencyKeyMetadata); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
 func (s *serverAPI) IsAdmin(
 	ctx context.Context,
 	in *ssov1.IsAdminRequest,
 ) (*ssov1.IsAdminResponce, error) {
-	if in.UserId == 0 {
+	if in.GetUserId() == 0 {
 		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
 
 	isAdmin, err := s.auth.IsAdmin(ctx, in.GetUserId())
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			return nil, status.Error(codes.NotFound, "user not found")
-		}
-
-		return nil, status.Error(codes.Internal, "failed to check admin status")
+		return nil, toGRPCError(err, "failed to check admin status")
 	}
 
 	return &ssov1.IsAdminResponce{IsAdmin: isAdmin}, nil
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"sso/internal/services/auth"
+
+	ssov1 "github.com/vremyavnikuda/protos/gen/go/sso"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAuth is Auth whose methods are set per test; unset ones fail the call.
+type fakeAuth struct {
+	login              func(ctx context.Context, email, password string, appID int) (string, error)
+	register           func(ctx context.Context, email, password string) (int64, error)
+	registerIdempotent func(ctx context.Context, key, email, password string) (int64, error)
+	isAdmin            func(ctx context.Context, userID int64) (bool, error)
+}
+
+var errNotStubbed = errors.New("not stubbed")
+
+func (f *fakeAuth) Login(ctx context.Context, email, password string, appID int) (string, error) {
+	if f.login == nil {
+		return "", errNotStubbed
+	}
+
+	return f.login(ctx, email, password, appID)
+}
+
+func (f *fakeAuth) RegisterNewUser(ctx context.Context, email, password string) (int64, error) {
+	if f.register == nil {
+		return 0, errNotStubbed
+	}
+
+	return f.register(ctx, email, password)
+}
+
+func (f *fakeAuth) RegisterNewUserIdempotent(ctx context.Context, key, email, password string) (int64, error) {
+	if f.registerIdempotent == nil {
+		return 0, errNotStubbed
+	}
+
+	return f.registerIdempotent(ctx, key, email, password)
+}
+
+func (f *fakeAuth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	if f.isAdmin == nil {
+		return false, errNotStubbed
+	}
+
+	return f.isAdmin(ctx, userID)
+}
+
+// fieldViolations returns code of err and fields of its BadRequest detail.
+func fieldViolations(t *testing.T, err error) (codes.Code, []string) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("got non-status error %v", err)
+	}
+
+	var fields []string
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+
+	return st.Code(), fields
+}
+
+func TestLogin(t *testing.T) {
+	var gotAppID int
+
+	api := NewServerAPI(&fakeAuth{
+		login: func(_ context.Context, email, password string, appID int) (string, error) {
+			gotAppID = appID
+			if password != "Secret123" {
+				return "", fmt.Errorf("Auth.Login: %w", auth.ErrInvalidCredentials)
+			}
+
+			return "token-for-" + email, nil
+		},
+	})
+
+	resp, err := api.Login(context.Background(), &ssov1.LoginRequest{
+		Email:    "user@example.com",
+		Password: "Secret123",
+		AppId:    3,
+	})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if resp.GetToken() != "token-for-user@example.com" {
+		t.Errorf("token = %q, want token-for-user@example.com", resp.GetToken())
+	}
+	if gotAppID != 3 {
+		t.Errorf("service got app id %d, want 3", gotAppID)
+	}
+
+	_, err = api.Login(context.Background(), &ssov1.LoginRequest{
+		Email:    "user@example.com",
+		Password: "wrong",
+		AppId:    3,
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Login with wrong password: got %v, want Unauthenticated", err)
+	}
+
+	// Zero app_id is left to the service, which may have a default app.
+	if _, err := api.Login(context.Background(), &ssov1.LoginRequest{Email: "user@example.com", Password: "Secret123"}); err != nil {
+		t.Fatalf("Login without app_id: %v", err)
+	}
+	if gotAppID != 0 {
+		t.Errorf("service got app id %d without app_id, want 0", gotAppID)
+	}
+}
+
+func TestLoginMissingFields(t *testing.T) {
+	api := NewServerAPI(&fakeAuth{})
+
+	_, err := api.Login(context.Background(), &ssov1.LoginRequest{AppId: 1})
+
+	code, fields := fieldViolations(t, err)
+	if code != codes.InvalidArgument {
+		t.Fatalf("code = %s, want InvalidArgument", code)
+	}
+	if want := []string{"email", "password"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("field violations = %v, want %v", fields, want)
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	api := NewServerAPI(&fakeAuth{
+		isAdmin: func(_ context.Context, userID int64) (bool, error) {
+			switch userID {
+			case 1:
+				return true, nil
+			case 2:
+				return false, nil
+			default:
+				return false, fmt.Errorf("Auth.IsAdmin: %w", auth.ErrUserNotFound)
+			}
+		},
+	})
+
+	for _, tc := range []struct {
+		userID int64
+		want   bool
+	}{{1, true}, {2, false}} {
+		resp, err := api.IsAdmin(context.Background(), &ssov1.IsAdminRequest{UserId: tc.userID})
+		if err != nil {
+			t.Fatalf("IsAdmin(%d): %v", tc.userID, err)
+		}
+		if resp.GetIsAdmin() != tc.want {
+			t.Errorf("IsAdmin(%d) = %t, want %t", tc.userID, resp.GetIsAdmin(), tc.want)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   codes.Code
+	}{
+		{"missing user_id", 0, codes.InvalidArgument},
+		{"unknown user", 3, codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.IsAdmin(context.Background(), &ssov1.IsAdminRequest{UserId: tt.userID})
+			if status.Code(err) != tt.want {
+				t.Errorf("IsAdmin: got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	registered := map[string]int64{}
+
+	api := NewServerAPI(&fakeAuth{
+		register: func(_ context.Context, email, _ string) (int64, error) {
+			if _, ok := registered[email]; ok {
+				return 0, fmt.Errorf("Auth.RegisterNewUser: %w", auth.ErrUserAlreadyExists)
+			}
+			registered[email] = int64(len(registered) + 1)
+
+			return registered[email], nil
+		},
+	})
+
+	req := &ssov1.RegisterRequest{Email: "user@example.com", Password: "Secret123"}
+
+	resp, err := api.Register(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if resp.GetUserId() != 1 {
+		t.Errorf("user id = %d, want 1", resp.GetUserId())
+	}
+
+	if _, err := api.Register(context.Background(), req); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Register of taken email: got %v, want AlreadyExists", err)
+	}
+}
+
+func TestRegisterIdempotencyKey(t *testing.T) {
+	var gotKey string
+
+	api := NewServerAPI(&fakeAuth{
+		register: func(context.Context, string, string) (int64, error) {
+			return 1, nil
+		},
+		registerIdempotent: func(_ context.Context, key, _, _ string) (int64, error) {
+			gotKey = key
+
+			return 2, nil
+		},
+	})
+
+	req := &ssov1.RegisterRequest{Email: "user@example.com", Password: "Secret123"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKeyMetadata, "key-1"))
+	resp, err := api.Register(ctx, req)
+	if err != nil {
+		t.Fatalf("Register with idempotency key: %v", err)
+	}
+	if resp.GetUserId() != 2 || gotKey != "key-1" {
+		t.Errorf("Register with key: user %d, key %q; want idempotent registration with key-1", resp.GetUserId(), gotKey)
+	}
+
+	resp, err = api.Register(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Register without idempotency key: %v", err)
+	}
+	if resp.GetUserId() != 1 {
+		t.Errorf("Register without key: user %d, want plain registration", resp.GetUserId())
+	}
+}
+
+func TestRegisterMissingFields(t *testing.T) {
+	api := NewServerAPI(&fakeAuth{})
+
+	tests := []struct {
+		name string
+		req  *ssov1.RegisterRequest
+		want []string
+	}{
+		{"no email", &ssov1.RegisterRequest{Password: "Secret123"}, []string{"email"}},
+		{"no password", &ssov1.RegisterRequest{Email: "user@example.com"}, []string{"password"}},
+		{"empty", &ssov1.RegisterRequest{}, []string{"email", "password"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := api.Register(context.Background(), tt.req)
+
+			code, fields := fieldViolations(t, err)
+			if code != codes.InvalidArgument {
+				t.Fatalf("code = %s, want InvalidArgument", code)
+			}
+			if !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("field violations = %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginContextErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{context.Canceled, codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want.String(), func(t *testing.T) {
+			api := NewServerAPI(&fakeAuth{
+				login: func(context.Context, string, string, int) (string, error) {
+					return "", fmt.Errorf("Auth.Login: %w", tt.err)
+				},
+			})
+
+			_, err := api.Login(context.Background(), &ssov1.LoginRequest{
+				Email:    "user@example.com",
+				Password: "Secret123",
+				AppId:    1,
+			})
+			if status.Code(err) != tt.want {
+				t.Errorf("Login: got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewServerAPINilAuth(t *testing.T) {
+	var nilService *auth.Auth
+
+	tests := []struct {
+		name string
+		auth Auth
+	}{
+		{"untyped nil", nil},
+		{"typed nil pointer", nilService},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("NewServerAPI didn't panic")
+				}
+			}()
+
+			NewServerAPI(tt.auth)
+		})
+	}
+}
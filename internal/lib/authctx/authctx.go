@@ -0,0 +1,37 @@
+package authctx
+
+import (
+	"context"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+)
+
+type (
+	ctxKey    struct{}
+	appCtxKey struct{}
+)
+
+// WithClaims returns copy of ctx carrying claims of authenticated caller.
+func WithClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+// ClaimsFromContext returns claims stored in ctx by auth interceptor, if any.
+func ClaimsFromContext(ctx context.Context) (*jwt.Claims, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(*jwt.Claims)
+
+	return claims, ok && claims != nil
+}
+
+// WithApp returns copy of ctx carrying app the caller's token was issued for.
+func WithApp(ctx context.Context, app models.App) context.Context {
+	return context.WithValue(ctx, appCtxKey{}, app)
+}
+
+// AppFromContext returns app stored in ctx by auth interceptor, if any.
+func AppFromContext(ctx context.Context) (models.App, bool) {
+	app, ok := ctx.Value(appCtxKey{}).(models.App)
+
+	return app, ok
+}
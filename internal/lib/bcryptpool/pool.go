@@ -0,0 +1,47 @@
+package bcryptpool
+
+import (
+	"context"
+	"runtime"
+)
+
+// Pool bounds number of concurrent password hashing and comparisons.
+type Pool struct {
+	sem chan struct{}
+}
+
+// New creates pool running at most size operations at once.
+// Non-positive size means GOMAXPROCS.
+func New(size int) *Pool {
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+
+	return &Pool{sem: make(chan struct{}, size)}
+}
+
+// Do runs fn on the pool and returns its result. It returns ctx.Err()
+// if ctx is done before a worker is free or before fn finishes; in the
+// latter case fn completes in background and frees its worker
+// afterwards.
+func (p *Pool) Do(ctx context.Context, fn func() error) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-p.sem }()
+
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+package bcryptpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	p := New(1)
+
+	want := errors.New("mismatch")
+	if err := p.Do(context.Background(), func() error { return want }); !errors.Is(err, want) {
+		t.Errorf("Do: got %v, want %v", err, want)
+	}
+}
+
+func TestDoCancelledMidCompare(t *testing.T) {
+	p := New(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	err := p.Do(ctx, func() error {
+		close(started)
+		<-release
+		close(finished)
+
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do cancelled mid-compare: got %v, want context.Canceled", err)
+	}
+
+	// Abandoned comparison still holds its worker until it's done.
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	if err := p.Do(waitCtx, func() error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do while worker busy: got %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	<-finished
+
+	if err := p.Do(context.Background(), func() error { return nil }); err != nil {
+		t.Errorf("Do after worker freed: %v", err)
+	}
+}
+
+func TestDoCancelledBeforeWorker(t *testing.T) {
+	p := New(1)
+	p.sem <- struct{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	if err := p.Do(ctx, func() error { ran = true; return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do with no free worker: got %v, want context.Canceled", err)
+	}
+	if ran {
+		t.Error("fn ran without a free worker")
+	}
+}
+
+func TestNewDefaultsToGOMAXPROCS(t *testing.T) {
+	if got := cap(New(0).sem); got < 1 {
+		t.Errorf("New(0) capacity = %d, want GOMAXPROCS", got)
+	}
+	if got := cap(New(3).sem); got != 3 {
+		t.Errorf("New(3) capacity = %d, want 3", got)
+	}
+}
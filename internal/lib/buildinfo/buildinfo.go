@@ -0,0 +1,35 @@
+package buildinfo
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Set at build time:
+//
+//	go build -ldflags "-X sso/internal/lib/buildinfo.Version=v1.2.3 \
+//	  -X sso/internal/lib/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X sso/internal/lib/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// String formats build info for --version output.
+func String() string {
+	return format(Version, Commit, Date)
+}
+
+func format(version, commit, date string) string {
+	return fmt.Sprintf("sso %s (commit %s, built %s)", version, commit, date)
+}
+
+// Attr returns build info as log attribute.
+func Attr() slog.Attr {
+	return slog.Group("build",
+		slog.String("version", Version),
+		slog.String("commit", Commit),
+		slog.String("date", Date),
+	)
+}
@@ -0,0 +1,30 @@
+package buildinfo
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	got := format("v1.2.3", "abc1234", "2024-01-02T03:04:05Z")
+	if want := "sso v1.2.3 (commit abc1234, built 2024-01-02T03:04:05Z)"; got != want {
+		t.Errorf("format() = %q, want %q", got, want)
+	}
+
+	if got := String(); got != "sso dev (commit unknown, built unknown)" {
+		t.Errorf("String() without ldflags = %q", got)
+	}
+}
+
+func TestAttr(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("started", Attr())
+
+	for _, want := range []string{"build.version=dev", "build.commit=unknown", "build.date=unknown"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log = %q, want %s", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownAlgorithm is returned by Compare for hash no registered
+// algorithm recognizes.
+var ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+
+// ErrMismatch is returned by Compare when password doesn't match hash.
+var ErrMismatch = errors.New("password doesn't match hash")
+
+// Algorithm is password hashing scheme. Hashes it produces must start with
+// a prefix no other algorithm uses, e.g. "$2a$" for bcrypt or "$argon2id$",
+// so stored hashes can be routed back to it.
+type Algorithm interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) error
+	// Owns reports whether hash was produced by this algorithm.
+	Owns(hash string) bool
+	// NeedsRehash reports whether hash it owns is weaker than one Hash
+	// would produce now, e.g. made with lower cost.
+	NeedsRehash(hash string) bool
+}
+
+// Bcrypt hashes passwords with bcrypt of given cost.
+type Bcrypt struct {
+	Cost int
+}
+
+// NewBcrypt returns bcrypt algorithm. Cost outside bcrypt's range means
+// bcrypt.DefaultCost.
+func NewBcrypt(cost int) Bcrypt {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return Bcrypt{Cost: cost}
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b Bcrypt) Compare(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+
+	return err
+}
+
+// Owns reports whether hash is in bcrypt modular crypt format.
+func (Bcrypt) Owns(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
+
+// NeedsRehash reports whether hash was made with cost below b.Cost.
+func (b Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+
+	return cost < b.Cost
+}
+
+// Hasher hashes new passwords with primary algorithm and checks stored hashes
+// with whichever algorithm produced them, so hashes made by an older
+// algorithm keep working after primary is changed.
+type Hasher struct {
+	primary Algorithm
+	legacy  []Algorithm
+}
+
+// New returns hasher creating hashes with primary and accepting hashes of
+// primary and legacy algorithms.
+func New(primary Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{primary: primary, legacy: legacy}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+func (h *Hasher) Compare(hash, password string) error {
+	if h.primary.Owns(hash) {
+		return h.primary.Compare(hash, password)
+	}
+
+	for _, alg := range h.legacy {
+		if alg.Owns(hash) {
+			return alg.Compare(hash, password)
+		}
+	}
+
+	return ErrUnknownAlgorithm
+}
+
+// NeedsRehash reports whether hash should be replaced by a fresh one: it was
+// made by a legacy algorithm or primary considers it weak.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if h.primary.Owns(hash) {
+		return h.primary.NeedsRehash(hash)
+	}
+
+	for _, alg := range h.legacy {
+		if alg.Owns(hash) {
+			return true
+		}
+	}
+
+	return false
+}
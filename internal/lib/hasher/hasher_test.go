@@ -0,0 +1,89 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// plain is test algorithm storing passwords as is behind "$plain$" prefix.
+type plain struct{}
+
+func (plain) Hash(password string) (string, error) { return "$plain$" + password, nil }
+
+func (plain) Compare(hash, password string) error {
+	if hash != "$plain$"+password {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+func (plain) Owns(hash string) bool { return strings.HasPrefix(hash, "$plain$") }
+
+func (plain) NeedsRehash(string) bool { return false }
+
+func TestBcrypt(t *testing.T) {
+	b := NewBcrypt(bcrypt.MinCost)
+
+	hash, err := b.Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !b.Owns(hash) {
+		t.Errorf("Owns(%q) = false", hash)
+	}
+	if err := b.Compare(hash, "Secret123"); err != nil {
+		t.Errorf("Compare of right password: %v", err)
+	}
+	if err := b.Compare(hash, "Wrong1234"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Compare of wrong password: got %v, want ErrMismatch", err)
+	}
+
+	if !NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(hash) {
+		t.Error("NeedsRehash of lower cost hash = false")
+	}
+	if b.NeedsRehash(hash) {
+		t.Error("NeedsRehash of same cost hash = true")
+	}
+	if got := NewBcrypt(99).Cost; got != bcrypt.DefaultCost {
+		t.Errorf("NewBcrypt(99).Cost = %d, want default", got)
+	}
+}
+
+func TestHasherRoutesByPrefix(t *testing.T) {
+	h := New(NewBcrypt(bcrypt.MinCost), plain{})
+
+	bcryptHash, err := h.Hash("Secret123")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !NewBcrypt(bcrypt.MinCost).Owns(bcryptHash) {
+		t.Fatalf("Hash = %q, want primary bcrypt hash", bcryptHash)
+	}
+
+	tests := []struct {
+		name        string
+		hash        string
+		want        error
+		needsRehash bool
+	}{
+		{"primary", bcryptHash, nil, false},
+		{"legacy", "$plain$Secret123", nil, true},
+		{"legacy mismatch", "$plain$other", ErrMismatch, true},
+		{"unknown algorithm", "$argon2id$v=19$...", ErrUnknownAlgorithm, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := h.Compare(tt.hash, "Secret123"); !errors.Is(err, tt.want) {
+				t.Errorf("Compare: got %v, want %v", err, tt.want)
+			}
+			if got := h.NeedsRehash(tt.hash); got != tt.needsRehash {
+				t.Errorf("NeedsRehash = %t, want %t", got, tt.needsRehash)
+			}
+		})
+	}
+}
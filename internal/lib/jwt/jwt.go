@@ -1,25 +1,228 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"sso/internal/domain/models"
-	"time"
 )
 
-// NewToken генерация нового токета
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodES256)
+var (
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenMalformed = errors.New("token malformed")
+	ErrTokenInvalid   = errors.New("token invalid")
+)
+
+// supportedAlgorithms алгоритмы, которыми пакет подписывает токены
+var supportedAlgorithms = []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodES256.Alg()}
+
+// SupportedAlgorithm сообщает, может ли пакет проверить токен с алгоритмом alg
+func SupportedAlgorithm(alg string) bool {
+	for _, a := range supportedAlgorithms {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Claims содержимое токена
+type Claims struct {
+	UID   int64  `json:"uid"`
+	Email string `json:"email"`
+	AppID int    `json:"app_id"`
+	// TokenVersion версия токенов пользователя на момент выпуска
+	TokenVersion int64 `json:"token_version"`
+	jwt.RegisteredClaims
+}
+
+// Token подписанный токен и его метаданные
+type Token struct {
+	Signed    string
+	ID        string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// NewToken генерация нового токета.
+// Ключ подписи выдаёт keys, nil - StorageKeys.
+// iss - issuer, aud - имя приложения.
+func NewToken(
+	user models.User,
+	app models.App,
+	issuer string,
+	duration time.Duration,
+	keys KeyProvider,
+) (Token, error) {
+	if keys == nil {
+		keys = StorageKeys{}
+	}
+
+	signingKey, err := keys.SigningKey(app)
+	if err != nil {
+		return Token{}, err
+	}
+
+	token := jwt.New(signingKey.Method)
+	if signingKey.KID != "" {
+		token.Header["kid"] = signingKey.KID
+	}
+
 	claims := token.Claims.(jwt.MapClaims)
 
+	now := time.Now()
+	jti := uuid.NewString()
+	expiresAt := now.Add(duration)
+
 	claims["uid"] = user.ID
 	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["jti"] = jti
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
 	claims["app_id"] = app.ID
+	claims["token_version"] = user.TokenVersion
+	claims["iss"] = issuer
+	claims["aud"] = app.Name
 
 	//Подписываем свой токен
-	tokenString, err := token.SignedString([]byte(app.Secret))
+	tokenString, err := token.SignedString(signingKey.Key)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{
+		Signed:    tokenString,
+		ID:        jti,
+		IssuedAt:  time.Unix(now.Unix(), 0),
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+// ParseOption настройка проверки токена
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	leeway time.Duration
+	now    func() time.Time
+	issuer string
+	keys   KeyProvider
+	algs   []string
+}
+
+// WithLeeway допуск на расхождение часов при проверке exp/iat/nbf
+func WithLeeway(leeway time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithIssuer требует совпадения iss
+func WithIssuer(issuer string) ParseOption {
+	return func(o *parseOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithAlgorithms принимает только токены, подписанные перечисленными
+// алгоритмами, независимо от заголовка токена. По умолчанию HS256 и ES256.
+// none и неподдерживаемые алгоритмы отбрасываются.
+func WithAlgorithms(algs ...string) ParseOption {
+	return func(o *parseOptions) {
+		o.algs = algs
+	}
+}
+
+// WithKeyProvider берёт ключи проверки из p вместо приложения
+func WithKeyProvider(p KeyProvider) ParseOption {
+	return func(o *parseOptions) {
+		o.keys = p
+	}
+}
+
+// WithClock подменяет текущее время (для тестов)
+func WithClock(now func() time.Time) ParseOption {
+	return func(o *parseOptions) {
+		o.now = now
+	}
+}
+
+// ParseToken проверка подписи и срока действия токена
+func ParseToken(tokenString string, app models.App, opts ...ParseOption) (*Claims, error) {
+	const op = "jwt.ParseToken"
+
+	o := parseOptions{now: time.Now, keys: StorageKeys{}, algs: supportedAlgorithms}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Защита от подмены алгоритма: принимаем только разрешённые, которыми
+	// умеем подписывать. Пустой список jwt понимает как "любой", поэтому
+	// без разрешённых алгоритмов токен отклоняется сразу.
+	var algs []string
+	for _, alg := range o.algs {
+		if SupportedAlgorithm(alg) {
+			algs = append(algs, alg)
+		}
+	}
+	if len(algs) == 0 {
+		return nil, fmt.Errorf("%s: %w: no allowed signing algorithms", op, ErrTokenInvalid)
+	}
+
+	parserOpts := []jwt.ParserOption{
+		// Тип ключа под алгоритм выбирает KeyProvider.
+		jwt.WithValidMethods(algs),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(o.leeway),
+		jwt.WithTimeFunc(o.now),
+		// Токен выпущен для этого приложения
+		jwt.WithAudience(app.Name),
+	}
+	if o.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(o.issuer))
+	}
+
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) {
+			return o.keys.VerificationKey(token, app, o.now())
+		},
+		parserOpts...,
+	)
 	if err != nil {
-		return "", err
+		switch {
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenExpired)
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return nil, fmt.Errorf("%s: %w", op, ErrTokenMalformed)
+		default:
+			return nil, fmt.Errorf("%s: %w: %v", op, ErrTokenInvalid, err)
+		}
+	}
+
+	if claims.AppID != app.ID {
+		return nil, fmt.Errorf("%s: %w: app_id mismatch", op, ErrTokenInvalid)
+	}
+
+	return &claims, nil
+}
+
+// AppID возвращает app_id из токена без проверки подписи.
+// Нужен, чтобы найти приложение, секретом которого проверяется токен.
+func AppID(tokenString string) (int, error) {
+	const op = "jwt.AppID"
+
+	var claims Claims
+
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrTokenMalformed)
 	}
-	return tokenString, err
+
+	return claims.AppID, nil
 }
@@ -0,0 +1,400 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"sso/internal/domain/models"
+)
+
+const testIssuer = "sso-test"
+
+var (
+	testUser = models.User{ID: 1, Email: "user@example.com"}
+	testApp  = models.App{ID: 1, Name: "web", Secret: "web-secret"}
+)
+
+func newTestToken(t *testing.T, app models.App, duration time.Duration) Token {
+	t.Helper()
+
+	token, err := NewToken(testUser, app, testIssuer, duration, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	return token
+}
+
+func TestParseToken(t *testing.T) {
+	token := newTestToken(t, testApp, time.Hour)
+
+	claims, err := ParseToken(token.Signed, testApp, WithIssuer(testIssuer))
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.UID != testUser.ID || claims.Email != testUser.Email || claims.AppID != testApp.ID {
+		t.Errorf("claims = %+v, want user %d of app %d", claims, testUser.ID, testApp.ID)
+	}
+
+	otherSecret := testApp
+	otherSecret.Secret = "other-secret"
+	otherName := testApp
+	otherName.Name = "mobile"
+	otherID := testApp
+	otherID.ID = 2
+
+	tests := []struct {
+		name  string
+		token string
+		app   models.App
+		opts  []ParseOption
+		want  error
+	}{
+		{"expired", newTestToken(t, testApp, -time.Minute).Signed, testApp, nil, ErrTokenExpired},
+		{"malformed", "not-a-token", testApp, nil, ErrTokenMalformed},
+		{"wrong secret", token.Signed, otherSecret, nil, ErrTokenInvalid},
+		{"other audience", token.Signed, otherName, nil, ErrTokenInvalid},
+		{"other app id", token.Signed, otherID, nil, ErrTokenInvalid},
+		{"other issuer", token.Signed, testApp, []ParseOption{WithIssuer("other")}, ErrTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseToken(tt.token, tt.app, tt.opts...); !errors.Is(err, tt.want) {
+				t.Errorf("ParseToken: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssuerAndAudienceClaims(t *testing.T) {
+	token := newTestToken(t, testApp, time.Hour)
+
+	claims, err := ParseToken(token.Signed, testApp)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.Issuer != testIssuer {
+		t.Errorf("iss = %q, want %q", claims.Issuer, testIssuer)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != testApp.Name {
+		t.Errorf("aud = %v, want [%s]", claims.Audience, testApp.Name)
+	}
+}
+
+func TestAppID(t *testing.T) {
+	token := newTestToken(t, testApp, time.Hour)
+
+	id, err := AppID(token.Signed)
+	if err != nil {
+		t.Fatalf("AppID: %v", err)
+	}
+	if id != testApp.ID {
+		t.Errorf("AppID = %d, want %d", id, testApp.ID)
+	}
+
+	if _, err := AppID("not-a-token"); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("AppID of malformed token: got %v, want ErrTokenMalformed", err)
+	}
+}
+
+// signed подписывает claims вручную, в обход NewToken.
+func signed(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+
+	s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	return s
+}
+
+func validClaims(app models.App) jwt.MapClaims {
+	return jwt.MapClaims{
+		"uid":    testUser.ID,
+		"app_id": app.ID,
+		"aud":    app.Name,
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newKeyPairApp(t *testing.T) models.App {
+	t.Helper()
+
+	privateKey, publicKey, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+
+	app := testApp
+	app.PrivateKey = privateKey
+	app.PublicKey = publicKey
+
+	return app
+}
+
+func TestES256RoundTrip(t *testing.T) {
+	app := newKeyPairApp(t)
+	token := newTestToken(t, app, time.Hour)
+
+	if _, err := ParseToken(token.Signed, app, WithIssuer(testIssuer)); err != nil {
+		t.Fatalf("ParseToken of ES256 token: %v", err)
+	}
+
+	// Без открытого ключа ES256 токен проверить нечем, секрет не подходит.
+	if _, err := ParseToken(token.Signed, testApp); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("ParseToken without public key: got %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestES256Tampering(t *testing.T) {
+	app := newKeyPairApp(t)
+	token := newTestToken(t, app, time.Hour)
+	parts := strings.Split(token.Signed, ".")
+
+	// Подменяем uid в полезной нагрузке, подпись остаётся прежней.
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	forged := strings.Replace(string(payload), `"uid":1`, `"uid":2`, 1)
+	if forged == string(payload) {
+		t.Fatalf("payload %s has no uid 1", payload)
+	}
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + parts[2]
+
+	other := newKeyPairApp(t)
+	otherKey := app
+	otherKey.PublicKey = other.PublicKey
+
+	tests := []struct {
+		name  string
+		token string
+		app   models.App
+	}{
+		{"tampered payload", tampered, app},
+		{"other key pair", token.Signed, otherKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseToken(tt.token, tt.app); !errors.Is(err, ErrTokenInvalid) {
+				t.Errorf("ParseToken: got %v, want ErrTokenInvalid", err)
+			}
+		})
+	}
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	privateKey, publicKey, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+
+	priv, err := parsePrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("parsePrivateKey: %v", err)
+	}
+	pub, err := parsePublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("parsePublicKey: %v", err)
+	}
+	if !priv.PublicKey.Equal(pub) {
+		t.Error("public key doesn't match private key")
+	}
+
+	if _, second, _ := GenerateKeyPair(); second == publicKey {
+		t.Error("two generated key pairs are equal")
+	}
+}
+
+func TestSigningMethodConfusion(t *testing.T) {
+	app := newKeyPairApp(t)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// Открытый ключ публичен: HS256 с ним в роли секрета - подделка.
+		{"HS256 with public key as secret", signed(t, jwt.SigningMethodHS256, []byte(app.PublicKey), validClaims(app))},
+		{"none", signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(app))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseToken(tt.token, app); !errors.Is(err, ErrTokenInvalid) {
+				t.Errorf("ParseToken: got %v, want ErrTokenInvalid", err)
+			}
+		})
+	}
+}
+
+func TestTokenIDAndIssuedAt(t *testing.T) {
+	before := time.Now().Truncate(time.Second)
+
+	first := newTestToken(t, testApp, time.Hour)
+	second := newTestToken(t, testApp, time.Hour)
+
+	if first.ID == "" || first.ID == second.ID {
+		t.Fatalf("token ids %q and %q, want distinct non-empty", first.ID, second.ID)
+	}
+
+	claims, err := ParseToken(first.Signed, testApp)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.ID != first.ID {
+		t.Errorf("jti = %q, want %q", claims.ID, first.ID)
+	}
+	if claims.IssuedAt == nil || !claims.IssuedAt.Equal(first.IssuedAt) || first.IssuedAt.Before(before) {
+		t.Errorf("iat = %v, token IssuedAt = %s, want same time not before %s", claims.IssuedAt, first.IssuedAt, before)
+	}
+	if got := first.ExpiresAt.Sub(first.IssuedAt); got != time.Hour {
+		t.Errorf("ExpiresAt - IssuedAt = %s, want 1h", got)
+	}
+}
+
+func TestKeyIDs(t *testing.T) {
+	now := time.Now()
+
+	app := testApp
+	app.Keys = []models.AppKey{
+		{ID: "k1", Secret: "k1-secret", ExpiresAt: now.Add(time.Hour)},
+		{ID: "k2", Secret: "k2-secret", Active: true},
+	}
+
+	token := newTestToken(t, app, 2*time.Hour)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token.Signed, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	if kid := parsed.Header["kid"]; kid != "k2" {
+		t.Fatalf("kid = %v, want active key k2", kid)
+	}
+
+	if _, err := ParseToken(token.Signed, app); err != nil {
+		t.Errorf("ParseToken of token signed with active key: %v", err)
+	}
+
+	// Token of retired key verifies until the key expires.
+	retired := app
+	retired.Keys = []models.AppKey{app.Keys[0], {ID: "k3", Secret: "k3-secret", Active: true}}
+	retired.Keys[0].Active = true
+	oldToken := newTestToken(t, retired, 2*time.Hour)
+	retired.Keys[0].Active = false
+
+	if _, err := ParseToken(oldToken.Signed, retired); err != nil {
+		t.Errorf("ParseToken of retired key token before expiry: %v", err)
+	}
+	later := WithClock(func() time.Time { return now.Add(90 * time.Minute) })
+	if _, err := ParseToken(oldToken.Signed, retired, later); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("ParseToken of retired key token after expiry: got %v, want ErrTokenInvalid", err)
+	}
+
+	// Token of deleted key doesn't verify, even with legacy secret left.
+	if _, err := ParseToken(token.Signed, retired); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("ParseToken with unknown kid: got %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestLeeway(t *testing.T) {
+	token := newTestToken(t, testApp, time.Hour)
+	expiry := token.ExpiresAt
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want error
+	}{
+		{"just expired within leeway", expiry.Add(20 * time.Second), nil},
+		{"expired beyond leeway", expiry.Add(40 * time.Second), ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := WithClock(func() time.Time { return tt.now })
+
+			_, err := ParseToken(token.Signed, testApp, WithLeeway(30*time.Second), clock)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseToken: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Без допуска токен истекает ровно в exp.
+	clock := WithClock(func() time.Time { return expiry.Add(time.Second) })
+	if _, err := ParseToken(token.Signed, testApp, clock); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("ParseToken without leeway: got %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	app := newKeyPairApp(t)
+	es256 := newTestToken(t, app, time.Hour).Signed
+	hs256 := newTestToken(t, testApp, time.Hour).Signed
+
+	tests := []struct {
+		name  string
+		token string
+		app   models.App
+		algs  []string
+		want  error
+	}{
+		{"HS256 allowed", hs256, testApp, []string{"HS256"}, nil},
+		{"ES256 allowed", es256, app, []string{"HS256", "ES256"}, nil},
+		{"ES256 not allowed", es256, app, []string{"HS256"}, ErrTokenInvalid},
+		{"HS256 not allowed", hs256, testApp, []string{"ES256"}, ErrTokenInvalid},
+		// Неподдерживаемые алгоритмы отбрасываются, пустой список не пускает никого.
+		{"only unsupported", hs256, testApp, []string{"none", "RS256"}, ErrTokenInvalid},
+		{"empty", hs256, testApp, []string{}, ErrTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseToken(tt.token, tt.app, WithAlgorithms(tt.algs...)); !errors.Is(err, tt.want) {
+				t.Errorf("ParseToken: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupportedAlgorithm(t *testing.T) {
+	for alg, want := range map[string]bool{"HS256": true, "ES256": true, "none": false, "RS256": false, "": false} {
+		if got := SupportedAlgorithm(alg); got != want {
+			t.Errorf("SupportedAlgorithm(%q) = %t, want %t", alg, got, want)
+		}
+	}
+}
+
+func TestTokenVersionClaim(t *testing.T) {
+	user := testUser
+	user.TokenVersion = 3
+
+	token, err := NewToken(user, testApp, testIssuer, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	claims, err := ParseToken(token.Signed, testApp)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.TokenVersion != 3 {
+		t.Errorf("token_version = %d, want 3", claims.TokenVersion)
+	}
+
+	// Токены, выпущенные до появления claim, читаются с версией 0.
+	claims, err = ParseToken(signed(t, jwt.SigningMethodHS256, []byte(testApp.Secret), validClaims(testApp)), testApp)
+	if err != nil {
+		t.Fatalf("ParseToken of token without token_version: %v", err)
+	}
+	if claims.TokenVersion != 0 {
+		t.Errorf("token_version of token without claim = %d, want 0", claims.TokenVersion)
+	}
+}
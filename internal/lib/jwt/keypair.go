@@ -0,0 +1,48 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// GenerateKeyPair генерирует пару ключей ECDSA P-256 для ES256 в PEM:
+// закрытый ключ в PKCS#8, открытый в PKIX.
+func GenerateKeyPair() (privateKey, publicKey string, err error) {
+	const op = "jwt.GenerateKeyPair"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	privateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+	publicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+
+	return privateKey, publicKey, nil
+}
+
+// parsePrivateKey разбирает закрытый ключ приложения
+func parsePrivateKey(pemKey string) (*ecdsa.PrivateKey, error) {
+	return jwt.ParseECPrivateKeyFromPEM([]byte(pemKey))
+}
+
+// parsePublicKey разбирает открытый ключ приложения
+func parsePublicKey(pemKey string) (*ecdsa.PublicKey, error) {
+	return jwt.ParseECPublicKeyFromPEM([]byte(pemKey))
+}
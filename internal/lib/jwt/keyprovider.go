@@ -0,0 +1,112 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"sso/internal/domain/models"
+)
+
+// ErrKeyProviderNotConfigured внешний провайдер ключей не подключён
+var ErrKeyProviderNotConfigured = errors.New("external key provider not configured")
+
+// SigningKey ключ подписи токена.
+// KID пишется в заголовок, если не пустой.
+type SigningKey struct {
+	Method jwt.SigningMethod
+	KID    string
+	Key    interface{}
+}
+
+// KeyProvider источник ключей подписи и проверки токенов приложения.
+// Позволяет хранить ключи вне БД, например в KMS или Vault.
+type KeyProvider interface {
+	// SigningKey ключ, которым подписываются новые токены приложения
+	SigningKey(app models.App) (SigningKey, error)
+	// VerificationKey ключ проверки токена, алгоритм и kid берутся из заголовка
+	VerificationKey(token *jwt.Token, app models.App, now time.Time) (interface{}, error)
+}
+
+// StorageKeys ключи из приложения, загруженного из хранилища.
+// Провайдер по умолчанию.
+type StorageKeys struct{}
+
+// SigningKey если у приложения есть закрытый ключ, подписываем ES256 без kid.
+// Иначе HS256 активным ключом приложения с его kid;
+// если активного ключа нет, используется app.Secret без kid.
+func (StorageKeys) SigningKey(app models.App) (SigningKey, error) {
+	if app.PrivateKey != "" {
+		key, err := parsePrivateKey(app.PrivateKey)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		return SigningKey{Method: jwt.SigningMethodES256, Key: key}, nil
+	}
+
+	if key, ok := app.ActiveKey(); ok {
+		return SigningKey{Method: jwt.SigningMethodHS256, KID: key.ID, Key: []byte(key.Secret)}, nil
+	}
+
+	return SigningKey{Method: jwt.SigningMethodHS256, Key: []byte(app.Secret)}, nil
+}
+
+// VerificationKey ES256 проверяется открытым ключом приложения, HS256 - ключом
+// по kid из заголовка, токены без kid - app.Secret. Ключи и секрет, выведенные
+// из оборота ротацией, после истечения срока не принимаются.
+func (StorageKeys) VerificationKey(token *jwt.Token, app models.App, now time.Time) (interface{}, error) {
+	if token.Method.Alg() == jwt.SigningMethodES256.Alg() {
+		if app.PublicKey == "" {
+			return nil, errors.New("app has no public key")
+		}
+
+		return parsePublicKey(app.PublicKey)
+	}
+
+	kid, ok := token.Header["kid"]
+	if !ok {
+		if app.Secret == "" {
+			return nil, errors.New("token has no kid and app has no legacy secret")
+		}
+		if app.SecretExpired(now) {
+			return nil, errors.New("legacy app secret expired")
+		}
+
+		return []byte(app.Secret), nil
+	}
+
+	id, ok := kid.(string)
+	if !ok {
+		return nil, errors.New("kid must be a string")
+	}
+
+	key, ok := app.Key(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", id)
+	}
+	if key.Expired(now) {
+		return nil, fmt.Errorf("key %q expired", id)
+	}
+
+	return []byte(key.Secret), nil
+}
+
+// ExternalKeys заготовка провайдера, берущего ключи из внешнего сервиса
+// (KMS, Vault). Клиент сервиса ещё не реализован, поэтому все вызовы
+// возвращают ErrKeyProviderNotConfigured.
+type ExternalKeys struct {
+	// Address адрес сервиса ключей
+	Address string
+}
+
+// SigningKey не реализован
+func (p ExternalKeys) SigningKey(app models.App) (SigningKey, error) {
+	return SigningKey{}, fmt.Errorf("%w: app %d at %q", ErrKeyProviderNotConfigured, app.ID, p.Address)
+}
+
+// VerificationKey не реализован
+func (p ExternalKeys) VerificationKey(_ *jwt.Token, app models.App, _ time.Time) (interface{}, error) {
+	return nil, fmt.Errorf("%w: app %d at %q", ErrKeyProviderNotConfigured, app.ID, p.Address)
+}
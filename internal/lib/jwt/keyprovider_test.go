@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"sso/internal/domain/models"
+)
+
+// staticKeys подписывает и проверяет все токены одним ключом вне приложения.
+type staticKeys struct{ key []byte }
+
+func (p staticKeys) SigningKey(models.App) (SigningKey, error) {
+	return SigningKey{Method: jwt.SigningMethodHS256, KID: "static", Key: p.key}, nil
+}
+
+func (p staticKeys) VerificationKey(*jwt.Token, models.App, time.Time) (interface{}, error) {
+	return p.key, nil
+}
+
+func TestKeyProvider(t *testing.T) {
+	keys := staticKeys{key: []byte("external-key")}
+
+	token, err := NewToken(testUser, testApp, testIssuer, time.Hour, keys)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	if _, err := ParseToken(token.Signed, testApp, WithKeyProvider(keys)); err != nil {
+		t.Fatalf("ParseToken with same provider: %v", err)
+	}
+
+	// Ключа static у приложения нет, StorageKeys токен не примет.
+	if _, err := ParseToken(token.Signed, testApp); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("ParseToken with StorageKeys: got %v, want ErrTokenInvalid", err)
+	}
+
+	other := WithKeyProvider(staticKeys{key: []byte("other-key")})
+	if _, err := ParseToken(token.Signed, testApp, other); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("ParseToken with other key: got %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestExternalKeys(t *testing.T) {
+	keys := ExternalKeys{Address: "vault:8200"}
+
+	if _, err := NewToken(testUser, testApp, testIssuer, time.Hour, keys); !errors.Is(err, ErrKeyProviderNotConfigured) {
+		t.Errorf("NewToken: got %v, want ErrKeyProviderNotConfigured", err)
+	}
+
+	token := newTestToken(t, testApp, time.Hour)
+	if _, err := ParseToken(token.Signed, testApp, WithKeyProvider(keys)); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("ParseToken: got %v, want ErrTokenInvalid", err)
+	}
+}
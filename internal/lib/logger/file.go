@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"io"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// NewFile returns writer appending to file at path, which is rotated once it
+// grows over maxSizeMB megabytes (0 means 100). Rotated files are named after
+// the rotation time; at most maxBackups of them (0 means all) younger than
+// maxAgeDays days (0 means any age) are kept.
+func NewFile(path string, maxSizeMB, maxBackups, maxAgeDays int) io.WriteCloser {
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAgeDays,
+	}
+}
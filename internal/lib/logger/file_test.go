@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sso.log")
+
+	f := NewFile(path, 1, 2, 0)
+	log := NewWithFormat(FormatJSON, f, slog.LevelInfo)
+	log.Info("hello", slog.String("key", "value"))
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(data), `"msg":"hello"`) || !strings.Contains(string(data), `"key":"value"`) {
+		t.Errorf("log file = %q, want hello record", data)
+	}
+}
+
+func TestNewFileRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sso.log")
+
+	f := NewFile(path, 1, 0, 0)
+	t.Cleanup(func() { _ = f.Close() })
+
+	// Second write of 600KB overflows 1MB limit.
+	chunk := append(bytes.Repeat([]byte("x"), 600<<10), '\n')
+	for i := 0; i < 2; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("dir has %d files, want current log and one rotated", len(entries))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() > 1<<20 {
+		t.Errorf("current log is %d bytes, want at most 1MB after rotation", info.Size())
+	}
+}
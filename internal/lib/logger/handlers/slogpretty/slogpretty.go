@@ -49,13 +49,13 @@ func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
 	fields := make(map[string]interface{}, r.NumAttrs())
 
 	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = attrValue(a.Value)
 
 		return true
 	})
 
 	for _, a := range h.attrs {
-		fields[a.Key] = a.Value.Any()
+		fields[a.Key] = attrValue(a.Value)
 	}
 
 	var b []byte
@@ -96,3 +96,21 @@ func (h *PrettyHandler) WithGroup(name string) slog.Handler {
 		l:       h.l,
 	}
 }
+
+// attrValue resolves slog.LogValuer values (so redaction is applied) and
+// turns groups into maps, which marshal to readable JSON.
+func attrValue(v slog.Value) interface{} {
+	v = v.Resolve()
+
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+
+	group := v.Group()
+	m := make(map[string]interface{}, len(group))
+	for _, a := range group {
+		m[a.Key] = attrValue(a.Value)
+	}
+
+	return m
+}
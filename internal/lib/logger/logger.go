@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"io"
+	"log/slog"
+
+	"sso/internal/lib/logger/handlers/slogpretty"
+)
+
+const (
+	EnvLocal = "local"
+	EnvDev   = "dev"
+	EnvProd  = "prod"
+)
+
+// Log output formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New creates logger for env writing to out: pretty text at Debug for
+// local, JSON at Info for dev and prod. Unknown env is treated as prod.
+func New(env string, out io.Writer) *slog.Logger {
+	return NewWithLevel(env, out, DefaultLevel(env))
+}
+
+// NewWithLevel is New with explicit level. Pass *slog.LevelVar to change
+// level at runtime.
+func NewWithLevel(env string, out io.Writer, level slog.Leveler) *slog.Logger {
+	return NewWithFormat(DefaultFormat(env), out, level)
+}
+
+// NewWithFormat creates logger writing to out in format (FormatText is
+// human-readable pretty output, anything else is JSON) at level.
+func NewWithFormat(format string, out io.Writer, level slog.Leveler) *slog.Logger {
+	switch format {
+	case FormatText:
+		return setupPrettySlog(out, level)
+	default:
+		return slog.New(
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level}),
+		)
+	}
+}
+
+// DefaultFormat returns format used for env when none is configured.
+func DefaultFormat(env string) string {
+	if env == EnvLocal {
+		return FormatText
+	}
+
+	return FormatJSON
+}
+
+// Format returns configured format or default format for env if format is
+// empty.
+func Format(env string, format string) string {
+	if format == "" {
+		return DefaultFormat(env)
+	}
+
+	return format
+}
+
+// DefaultLevel returns level used for env when none is configured.
+func DefaultLevel(env string) slog.Level {
+	if env == EnvLocal {
+		return slog.LevelDebug
+	}
+
+	return slog.LevelInfo
+}
+
+// Level returns configured level (e.g. "debug", "WARN") or default level
+// for env if level is empty or invalid.
+func Level(env string, level string) slog.Level {
+	if level == "" {
+		return DefaultLevel(env)
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return DefaultLevel(env)
+	}
+
+	return l
+}
+
+func setupPrettySlog(out io.Writer, level slog.Leveler) *slog.Logger {
+	opts := slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{
+			Level: level,
+		},
+	}
+	handler := opts.NewPrettyHandler(out)
+
+	return slog.New(handler)
+}
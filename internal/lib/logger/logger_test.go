@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestEnvDefaults(t *testing.T) {
+	tests := []struct {
+		env    string
+		level  slog.Level
+		format string
+	}{
+		{EnvLocal, slog.LevelDebug, FormatText},
+		{EnvDev, slog.LevelInfo, FormatJSON},
+		{EnvProd, slog.LevelInfo, FormatJSON},
+		{"staging", slog.LevelInfo, FormatJSON},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultLevel(tt.env); got != tt.level {
+			t.Errorf("DefaultLevel(%q) = %s, want %s", tt.env, got, tt.level)
+		}
+		if got := DefaultFormat(tt.env); got != tt.format {
+			t.Errorf("DefaultFormat(%q) = %q, want %q", tt.env, got, tt.format)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		env, level string
+		want       slog.Level
+	}{
+		{EnvProd, "", slog.LevelInfo},
+		{EnvProd, "debug", slog.LevelDebug},
+		{EnvLocal, "WARN", slog.LevelWarn},
+		{EnvLocal, "loud", slog.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		if got := Level(tt.env, tt.level); got != tt.want {
+			t.Errorf("Level(%q, %q) = %s, want %s", tt.env, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestNewRespectsEnvLevel(t *testing.T) {
+	var out bytes.Buffer
+
+	log := New(EnvProd, &out)
+	if log.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("prod logger has debug enabled")
+	}
+
+	log.Info("hello")
+	if !bytes.HasPrefix(out.Bytes(), []byte("{")) {
+		t.Errorf("prod logger output %q, want JSON", out.String())
+	}
+
+	if !New(EnvLocal, &out).Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("local logger has debug disabled")
+	}
+}
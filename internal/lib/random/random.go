@@ -0,0 +1,16 @@
+package random
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// Token returns URL-safe random string built from size random bytes.
+func Token(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
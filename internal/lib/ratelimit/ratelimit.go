@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingWindow is in-memory limiter that allows at most limit failures
+// per key within window.
+type SlidingWindow struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	now      func() time.Time
+	failures map[string][]time.Time
+	// swept is when keys with no failures left in window were last
+	// dropped, so keys that never come back don't pile up.
+	swept time.Time
+}
+
+type Option func(*SlidingWindow)
+
+// WithClock overrides clock used by limiter.
+func WithClock(now func() time.Time) Option {
+	return func(l *SlidingWindow) {
+		l.now = now
+	}
+}
+
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	l := &SlidingWindow{
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		failures: make(map[string][]time.Time),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	l.swept = l.now()
+
+	return l
+}
+
+// Allow reports whether key has fewer than limit failures within window.
+func (l *SlidingWindow) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.prune(key)) < l.limit
+}
+
+// RetryAfter returns how long until Allow(key) becomes true again, zero if
+// it already is: time until enough failures fall out of window.
+func (l *SlidingWindow) RetryAfter(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures := l.prune(key)
+	if len(failures) < l.limit {
+		return 0
+	}
+
+	return failures[len(failures)-l.limit].Add(l.window).Sub(l.now())
+}
+
+// Fail records failure for key. Once per window it also drops every key
+// whose failures all fell out of window.
+func (l *SlidingWindow) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep()
+
+	l.failures[key] = append(l.prune(key), l.now())
+}
+
+// Reset forgets all failures for key.
+func (l *SlidingWindow) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+}
+
+// prune drops failures that fell out of window. Must be called with mu held.
+func (l *SlidingWindow) prune(key string) []time.Time {
+	failures := l.failures[key]
+	cutoff := l.now().Add(-l.window)
+
+	i := 0
+	for i < len(failures) && !failures[i].After(cutoff) {
+		i++
+	}
+
+	failures = failures[i:]
+	if len(failures) == 0 {
+		delete(l.failures, key)
+
+		return nil
+	}
+
+	l.failures[key] = failures
+
+	return failures
+}
+
+// sweep prunes all keys if window passed since last sweep. Must be called
+// with mu held.
+func (l *SlidingWindow) sweep() {
+	now := l.now()
+	if now.Sub(l.swept) < l.window {
+		return
+	}
+
+	for key := range l.failures {
+		l.prune(key)
+	}
+
+	l.swept = now
+}
+
+// SetLimits changes limit and window, e.g. on config reload. Recorded
+// failures are kept.
+func (l *SlidingWindow) SetLimits(limit int, window time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.window = window
+}
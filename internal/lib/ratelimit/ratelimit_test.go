@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestSlidingWindowLockout(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewSlidingWindow(3, time.Minute, WithClock(clock.now))
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("user") {
+			t.Fatalf("Allow after %d failures = false, want true", i)
+		}
+		l.Fail("user")
+		clock.advance(time.Second)
+	}
+
+	if l.Allow("user") {
+		t.Fatal("Allow after limit failures = true, want false")
+	}
+	if got, want := l.RetryAfter("user"), 57*time.Second; got != want {
+		t.Errorf("RetryAfter = %s, want %s", got, want)
+	}
+
+	clock.advance(57 * time.Second)
+	if !l.Allow("user") {
+		t.Error("Allow after oldest failure left window = false, want true")
+	}
+}
+
+func TestSlidingWindowReset(t *testing.T) {
+	l := NewSlidingWindow(1, time.Minute)
+
+	l.Fail("user")
+	l.Reset("user")
+
+	if !l.Allow("user") {
+		t.Error("Allow after Reset = false, want true")
+	}
+}
+
+func TestSlidingWindowEvictsStaleKeys(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := NewSlidingWindow(5, time.Minute, WithClock(clock.now))
+
+	// Unknown logins fail once and never come back.
+	for i := 0; i < 1000; i++ {
+		l.Fail(fmt.Sprintf("unknown-%d@example.com", i))
+	}
+
+	clock.advance(2 * time.Minute)
+	l.Fail("fresh@example.com")
+
+	if got := len(l.failures); got != 1 {
+		t.Fatalf("tracked keys after window = %d, want 1", got)
+	}
+	if !l.Allow("fresh@example.com") {
+		t.Error("fresh key should still be allowed after one failure")
+	}
+}
@@ -0,0 +1,20 @@
+package requestid
+
+import "context"
+
+// MetadataKey is gRPC metadata key carrying request ID in both directions.
+const MetadataKey = "x-request-id"
+
+type ctxKey struct{}
+
+// WithRequestID returns copy of ctx carrying request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns request ID stored in ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+
+	return id, ok
+}
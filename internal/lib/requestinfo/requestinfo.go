@@ -0,0 +1,38 @@
+package requestinfo
+
+import "context"
+
+// Info describes the caller of current request.
+type Info struct {
+	// ClientIP is peer IP address without port.
+	ClientIP  string
+	UserAgent string
+}
+
+type ctxKey struct{}
+
+// WithInfo returns copy of ctx carrying request info.
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// FromContext returns request info stored in ctx, if any.
+func FromContext(ctx context.Context) (Info, bool) {
+	info, ok := ctx.Value(ctxKey{}).(Info)
+
+	return info, ok
+}
+
+// ClientIP returns caller IP stored in ctx or empty string.
+func ClientIP(ctx context.Context) string {
+	info, _ := FromContext(ctx)
+
+	return info.ClientIP
+}
+
+// UserAgent returns caller user agent stored in ctx or empty string.
+func UserAgent(ctx context.Context) string {
+	info, _ := FromContext(ctx)
+
+	return info.UserAgent
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "sso"
+
+// Result label values.
+const (
+	ResultSuccess            = "success"
+	ResultInvalidCredentials = "invalid_credentials"
+	ResultInvalidArgument    = "invalid_argument"
+	ResultTooManyAttempts    = "too_many_attempts"
+	ResultAlreadyExists      = "already_exists"
+	ResultError              = "error"
+)
+
+var (
+	LoginTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_total",
+		Help:      "Number of login attempts by result.",
+	}, []string{"result"})
+
+	RegisterTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "register_total",
+		Help:      "Number of registration attempts by result.",
+	}, []string{"result"})
+
+	GRPCHandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_handler_duration_seconds",
+		Help:      "Duration of gRPC handlers by method and code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	StorageQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_query_duration_seconds",
+		Help:      "Duration of storage calls by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	StorageErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "Number of failed storage calls by method and error kind.",
+	}, []string{"method", "kind"})
+)
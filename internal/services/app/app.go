@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/random"
+	"sso/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// appSecretSize is size in bytes of secrets generated by RotateSecret.
+const appSecretSize = 32
+
+type App struct {
+	log       *slog.Logger
+	appSaver  AppSaver
+	tokenTTL  time.Duration
+	clockSkew time.Duration
+	now       func() time.Time
+}
+
+var (
+	ErrAppExists   = errors.New("app already exists")
+	ErrInvalidApp  = errors.New("app name and secret are required")
+	ErrAppNotFound = errors.New("app not found")
+)
+
+type AppSaver interface {
+	SaveApp(ctx context.Context, name string, secret string, tokenTTL time.Duration) (appID int, err error)
+	App(ctx context.Context, appID int) (models.App, error)
+	SetAppKeyPair(ctx context.Context, appID int, privateKey, publicKey string) error
+	RotateAppKey(ctx context.Context, appID int, key models.AppKey, retireAt time.Time) error
+	DeleteAppKey(ctx context.Context, appID int, kid string) error
+}
+
+// New returns app service. tokenTTL is global access token TTL and
+// clockSkew is tolerated clock skew of token verification; together with
+// app's own TTL they bound how long secrets replaced by RotateSecret are
+// kept for verification.
+func New(
+	log *slog.Logger,
+	appSaver AppSaver,
+	tokenTTL time.Duration,
+	clockSkew time.Duration,
+) *App {
+	return &App{
+		log:       log,
+		appSaver:  appSaver,
+		tokenTTL:  tokenTTL,
+		clockSkew: clockSkew,
+		now:       time.Now,
+	}
+}
+
+// CreateApp registers new client app and returns its ID. Non-zero tokenTTL
+// overrides global access token TTL for the app.
+// If app with given name already exists, returns ErrAppExists.
+func (a *App) CreateApp(ctx context.Context, name string, secret string, tokenTTL time.Duration) (int, error) {
+	const op = "App.CreateApp"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("name", name),
+	)
+
+	log.Info("creating app")
+
+	if name == "" || secret == "" {
+		return 0, fmt.Errorf("%s: %w", op, ErrInvalidApp)
+	}
+
+	id, err := a.appSaver.SaveApp(ctx, name, secret, tokenTTL)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppExists) {
+			log.Warn("app already exists", sl.Err(err))
+
+			return 0, fmt.Errorf("%s: %w", op, ErrAppExists)
+		}
+
+		log.Error("failed to save app", sl.Err(err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("app created", slog.Int("app_id", id))
+
+	return id, nil
+}
+
+// GenerateKeyPair generates ES256 key pair for app, stores it and returns
+// public key in PEM. Tokens of app are signed with the new key from then on.
+func (a *App) GenerateKeyPair(ctx context.Context, appID int) (string, error) {
+	const op = "App.GenerateKeyPair"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", appID),
+	)
+
+	privateKey, publicKey, err := jwt.GenerateKeyPair()
+	if err != nil {
+		log.Error("failed to generate key pair", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.appSaver.SetAppKeyPair(ctx, appID, privateKey, publicKey); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to save key pair", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("key pair generated")
+
+	return publicKey, nil
+}
+
+// RotateSecret generates new signing secret for app and returns it; it's not
+// retrievable later. New tokens are signed with it at once, while previous
+// secret and keys keep verifying tokens for the grace period: the longer of
+// app's and global access token TTL, plus clock skew leeway, so every token
+// signed before rotation expires before its key is retired.
+// Auth caches apps for auth.app_cache_ttl, so sso picks the change up when
+// its cache entry expires, or at once after Auth.InvalidateApps.
+func (a *App) RotateSecret(ctx context.Context, appID int) (string, error) {
+	const op = "App.RotateSecret"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", appID),
+	)
+
+	app, err := a.appSaver.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := random.Token(appSecretSize)
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := models.AppKey{ID: uuid.NewString(), Secret: secret, Active: true}
+	retireAt := a.now().Add(max(app.TokenTTL, a.tokenTTL) + a.clockSkew)
+
+	if err := a.appSaver.RotateAppKey(ctx, appID, key, retireAt); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to rotate secret", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("secret rotated", slog.String("kid", key.ID), slog.Time("previous_valid_until", retireAt))
+
+	return secret, nil
+}
+
+// PruneKeys removes app keys retired by RotateSecret whose grace period is
+// over and returns their number. They no longer verify tokens, so removing
+// them changes nothing but the size of app.
+func (a *App) PruneKeys(ctx context.Context, appID int) (int, error) {
+	const op = "App.PruneKeys"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", appID),
+	)
+
+	app, err := a.appSaver.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+
+			return 0, fmt.Errorf("%s: %w", op, ErrAppNotFound)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := a.now()
+	pruned := 0
+
+	for _, key := range app.Keys {
+		if key.Active || !key.Expired(now) {
+			continue
+		}
+
+		if err := a.appSaver.DeleteAppKey(ctx, appID, key.ID); err != nil {
+			log.Error("failed to delete key", slog.String("kid", key.ID), sl.Err(err))
+
+			return pruned, fmt.Errorf("%s: %w", op, err)
+		}
+
+		pruned++
+	}
+
+	log.Info("keys pruned", slog.Int("count", pruned))
+
+	return pruned, nil
+}
@@ -0,0 +1,253 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/storage/memory"
+)
+
+const testIssuer = "sso-test"
+
+func newTestApp(tokenTTL time.Duration) (*App, *memory.Storage) {
+	store := memory.New()
+
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, tokenTTL, 0), store
+}
+
+func TestCreateAppDuplicateName(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestApp(time.Hour)
+
+	if _, err := svc.CreateApp(ctx, "web", "secret", 0); err != nil {
+		t.Fatalf("CreateApp: %v", err)
+	}
+
+	_, err := svc.CreateApp(ctx, "web", "other-secret", 0)
+	if !errors.Is(err, ErrAppExists) {
+		t.Fatalf("CreateApp with taken name: got %v, want ErrAppExists", err)
+	}
+}
+
+func TestCreateAppRequiresNameAndSecret(t *testing.T) {
+	svc, _ := newTestApp(time.Hour)
+
+	for _, tc := range []struct{ name, secret string }{{"", "secret"}, {"web", ""}} {
+		if _, err := svc.CreateApp(context.Background(), tc.name, tc.secret, 0); !errors.Is(err, ErrInvalidApp) {
+			t.Errorf("CreateApp(%q, %q): got %v, want ErrInvalidApp", tc.name, tc.secret, err)
+		}
+	}
+}
+
+func TestGenerateKeyPairRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestApp(time.Hour)
+
+	id, err := svc.CreateApp(ctx, "web", "secret", 0)
+	if err != nil {
+		t.Fatalf("CreateApp: %v", err)
+	}
+
+	publicKey, err := svc.GenerateKeyPair(ctx, id)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	if !strings.Contains(publicKey, "PUBLIC KEY") {
+		t.Fatalf("GenerateKeyPair returned %q, want PEM public key", publicKey)
+	}
+
+	app, err := store.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	token, err := jwt.NewToken(models.User{ID: 1, Email: "user@example.com"}, app, testIssuer, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	claims, err := jwt.ParseToken(token.Signed, app, jwt.WithIssuer(testIssuer), jwt.WithAlgorithms("ES256"))
+	if err != nil {
+		t.Fatalf("ParseToken of ES256 token: %v", err)
+	}
+	if claims.UID != 1 {
+		t.Errorf("uid = %d, want 1", claims.UID)
+	}
+
+	// Flip a character in the middle of the signature.
+	sig := strings.LastIndex(token.Signed, ".") + 10
+	tampered := []byte(token.Signed)
+	if tampered[sig] == 'A' {
+		tampered[sig] = 'B'
+	} else {
+		tampered[sig] = 'A'
+	}
+
+	if _, err := jwt.ParseToken(string(tampered), app, jwt.WithIssuer(testIssuer)); !errors.Is(err, jwt.ErrTokenInvalid) {
+		t.Errorf("ParseToken of tampered token: got %v, want ErrTokenInvalid", err)
+	}
+}
+
+func TestGenerateKeyPairUnknownApp(t *testing.T) {
+	svc, _ := newTestApp(time.Hour)
+
+	if _, err := svc.GenerateKeyPair(context.Background(), 42); !errors.Is(err, ErrAppNotFound) {
+		t.Fatalf("GenerateKeyPair: got %v, want ErrAppNotFound", err)
+	}
+}
+
+func TestRotateSecretGraceWindow(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestApp(time.Hour)
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	id, err := svc.CreateApp(ctx, "web", "old-secret", 0)
+	if err != nil {
+		t.Fatalf("CreateApp: %v", err)
+	}
+
+	before, err := store.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	user := models.User{ID: 1, Email: "user@example.com"}
+
+	oldToken, err := jwt.NewToken(user, before, testIssuer, 24*time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	secret, err := svc.RotateSecret(ctx, id)
+	if err != nil {
+		t.Fatalf("RotateSecret: %v", err)
+	}
+	if secret == "" || secret == "old-secret" {
+		t.Fatalf("RotateSecret returned %q, want new secret", secret)
+	}
+
+	after, err := store.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	at := func(d time.Duration) jwt.ParseOption {
+		return jwt.WithClock(func() time.Time { return now.Add(d) })
+	}
+
+	if _, err := jwt.ParseToken(oldToken.Signed, after, at(30*time.Minute)); err != nil {
+		t.Errorf("old token within grace window: %v", err)
+	}
+	if _, err := jwt.ParseToken(oldToken.Signed, after, at(2*time.Hour)); err == nil {
+		t.Error("old token after grace window verified, want error")
+	}
+
+	newToken, err := jwt.NewToken(user, after, testIssuer, 24*time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	if _, err := jwt.ParseToken(newToken.Signed, after, at(2*time.Hour)); err != nil {
+		t.Errorf("token signed with rotated secret: %v", err)
+	}
+}
+
+func TestRotateSecretUnknownApp(t *testing.T) {
+	svc, _ := newTestApp(time.Hour)
+
+	if _, err := svc.RotateSecret(context.Background(), 42); !errors.Is(err, ErrAppNotFound) {
+		t.Fatalf("RotateSecret: got %v, want ErrAppNotFound", err)
+	}
+}
+
+func TestRotateSecretGraceCoversAppTTLAndSkew(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	svc := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, time.Hour, 5*time.Minute)
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	// App's own TTL is longer than the global one, so it sets the grace.
+	id, err := svc.CreateApp(ctx, "web", "old-secret", 3*time.Hour)
+	if err != nil {
+		t.Fatalf("CreateApp: %v", err)
+	}
+	before, err := store.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	oldToken, err := jwt.NewToken(models.User{ID: 1, Email: "user@example.com"}, before, testIssuer, 24*time.Hour, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	if _, err := svc.RotateSecret(ctx, id); err != nil {
+		t.Fatalf("RotateSecret: %v", err)
+	}
+	after, err := store.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	at := func(d time.Duration) jwt.ParseOption {
+		return jwt.WithClock(func() time.Time { return now.Add(d) })
+	}
+
+	if _, err := jwt.ParseToken(oldToken.Signed, after, at(3*time.Hour+4*time.Minute)); err != nil {
+		t.Errorf("old token within app TTL plus skew: %v", err)
+	}
+	if _, err := jwt.ParseToken(oldToken.Signed, after, at(3*time.Hour+6*time.Minute)); err == nil {
+		t.Error("old token after app TTL plus skew verified, want error")
+	}
+}
+
+func TestPruneKeys(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestApp(time.Hour)
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	id, err := svc.CreateApp(ctx, "web", "old-secret", 0)
+	if err != nil {
+		t.Fatalf("CreateApp: %v", err)
+	}
+	// Two rotations leave one retired key besides the active one.
+	for range 2 {
+		if _, err := svc.RotateSecret(ctx, id); err != nil {
+			t.Fatalf("RotateSecret: %v", err)
+		}
+	}
+
+	if pruned, err := svc.PruneKeys(ctx, id); err != nil || pruned != 0 {
+		t.Errorf("PruneKeys within grace = %d, %v; want 0", pruned, err)
+	}
+
+	svc.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	if pruned, err := svc.PruneKeys(ctx, id); err != nil || pruned != 1 {
+		t.Errorf("PruneKeys after grace = %d, %v; want 1", pruned, err)
+	}
+
+	app, err := store.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if len(app.Keys) != 1 || !app.Keys[0].Active {
+		t.Errorf("keys after prune = %+v, want only the active one", app.Keys)
+	}
+
+	if _, err := svc.PruneKeys(ctx, 42); !errors.Is(err, ErrAppNotFound) {
+		t.Errorf("PruneKeys of unknown app: got %v, want ErrAppNotFound", err)
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+type adminCacheEntry struct {
+	isAdmin   bool
+	expiresAt time.Time
+}
+
+// adminCache keeps IsAdmin results for a short time. When it's full,
+// expired entries are dropped first, then arbitrary ones.
+type adminCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[int64]adminCacheEntry
+}
+
+func newAdminCache(ttl time.Duration, size int) *adminCache {
+	return &adminCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[int64]adminCacheEntry, size),
+	}
+}
+
+func (c *adminCache) get(userID int64) (isAdmin bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.isAdmin, true
+}
+
+func (c *adminCache) set(userID int64, isAdmin bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[userID]; !ok && len(c.entries) >= c.size {
+		c.evict()
+	}
+
+	c.entries[userID] = adminCacheEntry{isAdmin: isAdmin, expiresAt: c.now().Add(c.ttl)}
+}
+
+// evict makes room for one entry. Caller holds c.mu.
+func (c *adminCache) evict() {
+	now := c.now()
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+
+	for id := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, id)
+	}
+}
+
+func (c *adminCache) invalidate(userID int64) {
+	c.mu.Lock()
+	delete(c.entries, userID)
+	c.mu.Unlock()
+}
+
+// InvalidateUserRoles drops cached IsAdmin result of user. Call it after
+// user's roles were changed. No-op when caching is disabled.
+func (a *Auth) InvalidateUserRoles(userID int64) {
+	if a.adminCache != nil {
+		a.adminCache.invalidate(userID)
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdminCache(t *testing.T) {
+	now := time.Now()
+	c := newAdminCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	if _, ok := c.get(1); ok {
+		t.Fatal("get of empty cache hit")
+	}
+
+	c.set(1, true)
+	if isAdmin, ok := c.get(1); !ok || !isAdmin {
+		t.Errorf("get(1) = %t, %t; want cached admin", isAdmin, ok)
+	}
+
+	c.invalidate(1)
+	if _, ok := c.get(1); ok {
+		t.Error("get(1) after invalidate hit")
+	}
+
+	c.set(1, true)
+	now = now.Add(time.Minute)
+	if _, ok := c.get(1); ok {
+		t.Error("get(1) after ttl hit")
+	}
+}
+
+func TestAdminCacheSize(t *testing.T) {
+	now := time.Now()
+	c := newAdminCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	c.set(1, true)
+	now = now.Add(30 * time.Second)
+	c.set(2, false)
+	now = now.Add(40 * time.Second)
+
+	// 1 has expired, so it's evicted first and 2 stays.
+	c.set(3, true)
+	if len(c.entries) != 2 {
+		t.Fatalf("cache holds %d entries, want size 2", len(c.entries))
+	}
+	if _, ok := c.get(2); !ok {
+		t.Error("unexpired entry evicted while expired one was there")
+	}
+
+	// Without expired entries an arbitrary one makes room.
+	c.set(4, true)
+	if len(c.entries) != 2 {
+		t.Errorf("cache holds %d entries, want size 2", len(c.entries))
+	}
+	if _, ok := c.get(4); !ok {
+		t.Error("newly set entry missing")
+	}
+}
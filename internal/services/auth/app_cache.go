@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"sso/internal/domain/models"
+)
+
+type appCacheEntry struct {
+	app       models.App
+	expiresAt time.Time
+}
+
+// cachedAppProvider is a TTL cache in front of AppProvider, keyed by app ID.
+// Errors are not cached.
+type cachedAppProvider struct {
+	provider AppProvider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	apps map[int]appCacheEntry
+}
+
+func newCachedAppProvider(provider AppProvider, ttl time.Duration) *cachedAppProvider {
+	return &cachedAppProvider{
+		provider: provider,
+		ttl:      ttl,
+		now:      time.Now,
+		apps:     make(map[int]appCacheEntry),
+	}
+}
+
+// App returns cached app or fetches it from underlying provider.
+func (c *cachedAppProvider) App(ctx context.Context, appID int) (models.App, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.apps[appID]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.app, nil
+	}
+
+	app, err := c.provider.App(ctx, appID)
+	if err != nil {
+		return models.App{}, err
+	}
+
+	c.mu.Lock()
+	c.apps[appID] = appCacheEntry{app: app, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return app, nil
+}
+
+// InvalidateAll drops every cached app, so next lookups go to underlying
+// provider.
+func (c *cachedAppProvider) InvalidateAll() {
+	c.mu.Lock()
+	clear(c.apps)
+	c.mu.Unlock()
+}
+
+// InvalidateApps drops cached apps, so changes made to them outside of this
+// process, e.g. keys rotated by manage-app, apply before cache entries
+// expire. No-op when caching is disabled.
+func (a *Auth) InvalidateApps() {
+	if a.appCache != nil {
+		a.appCache.InvalidateAll()
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+)
+
+// appProviderFunc is AppProvider counting its calls.
+type appProviderFunc struct {
+	app   func(appID int) (models.App, error)
+	calls int
+}
+
+func (p *appProviderFunc) App(_ context.Context, appID int) (models.App, error) {
+	p.calls++
+
+	return p.app(appID)
+}
+
+func TestCachedAppProvider(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	provider := &appProviderFunc{app: func(appID int) (models.App, error) {
+		return models.App{ID: appID, Name: "web"}, nil
+	}}
+	c := newCachedAppProvider(provider, time.Minute)
+	c.now = func() time.Time { return now }
+
+	lookup := func(wantCalls int) {
+		t.Helper()
+
+		app, err := c.App(ctx, 1)
+		if err != nil {
+			t.Fatalf("App: %v", err)
+		}
+		if app.ID != 1 {
+			t.Errorf("App id = %d, want 1", app.ID)
+		}
+		if provider.calls != wantCalls {
+			t.Errorf("provider called %d times, want %d", provider.calls, wantCalls)
+		}
+	}
+
+	lookup(1)
+	// Within TTL the cached app is returned.
+	now = now.Add(30 * time.Second)
+	lookup(1)
+	// After TTL it's fetched again.
+	now = now.Add(time.Minute)
+	lookup(2)
+	// Invalidated app is fetched again.
+	c.InvalidateAll()
+	lookup(3)
+}
+
+func TestCachedAppProviderDoesNotCacheErrors(t *testing.T) {
+	errDown := errors.New("storage is down")
+	fail := true
+
+	provider := &appProviderFunc{app: func(appID int) (models.App, error) {
+		if fail {
+			return models.App{}, errDown
+		}
+
+		return models.App{ID: appID}, nil
+	}}
+	c := newCachedAppProvider(provider, time.Minute)
+
+	if _, err := c.App(context.Background(), 1); !errors.Is(err, errDown) {
+		t.Fatalf("App: got %v, want %v", err, errDown)
+	}
+
+	fail = false
+	if _, err := c.App(context.Background(), 1); err != nil {
+		t.Fatalf("App after provider recovered: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times, want 2", provider.calls)
+	}
+}
@@ -0,0 +1,83 @@
+package auth_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"sso/internal/domain/models"
+	"sso/internal/metrics"
+	"sso/internal/services/auth"
+)
+
+// recordingAudit keeps recorded events.
+type recordingAudit struct {
+	mu     sync.Mutex
+	events []models.AuditEvent
+}
+
+func (r *recordingAudit) Record(_ context.Context, event models.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// take returns events recorded since the last call.
+func (r *recordingAudit) take() []models.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.events
+	r.events = nil
+
+	return events
+}
+
+func TestAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	rec := &recordingAudit{}
+	a := newTestAuthWith(t, store, testDeps{audit: rec}, auth.Config{})
+
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err == nil {
+		t.Fatal("RegisterNewUser of taken email succeeded")
+	}
+	_, _ = a.Login(ctx, "user@example.com", "Wrong1234", appID)
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := a.IsAdmin(ctx, uid); err != nil {
+		t.Fatalf("IsAdmin: %v", err)
+	}
+
+	want := []models.AuditEvent{
+		{Type: models.AuditRegister, UserID: uid, Email: "user@example.com", Result: metrics.ResultSuccess},
+		{Type: models.AuditRegister, Email: "user@example.com", Result: metrics.ResultAlreadyExists},
+		{Type: models.AuditLogin, UserID: uid, Email: "user@example.com", Result: metrics.ResultInvalidCredentials},
+		{Type: models.AuditLogin, UserID: uid, Email: "user@example.com", Result: metrics.ResultSuccess},
+		{Type: models.AuditIsAdmin, UserID: uid, Result: "not_admin"},
+	}
+
+	got := rec.take()
+	if len(got) != len(want) {
+		t.Fatalf("recorded %d events %+v, want %d", len(got), got, len(want))
+	}
+	for i, event := range got {
+		if event.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+		event.Time = want[i].Time
+		if event != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, event, want[i])
+		}
+	}
+}
@@ -5,26 +5,114 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"sso/internal/domain/models"
+	"sso/internal/lib/authctx"
+	"sso/internal/lib/bcryptpool"
 	"sso/internal/lib/jwt"
 	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/random"
+	"sso/internal/lib/requestinfo"
+	"sso/internal/metrics"
 	"sso/internal/storage"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Auth struct {
-	log         *slog.Logger
-	usrSaver    UserSaver
-	usrProvider UserProvider
-	appProvider AppProvider
-	tokenTTL    time.Duration
+	log             *slog.Logger
+	usrSaver        UserSaver
+	usrProvider     UserProvider
+	usrUpdater      UserUpdater
+	roleProvider    RoleProvider
+	appProvider     AppProvider
+	appCache        *cachedAppProvider
+	adminCache      *adminCache
+	refreshStorage  RefreshTokenStorage
+	revocationStore RevocationStore
+	sessions        SessionStore
+	userTokens      UserTokenStorage
+	idempotencyKeys IdempotencyStore
+	transactor      Transactor
+	loginLimiter    LoginLimiter
+	audit           AuditLogger
+	events          *eventBroker
+	hasher          Hasher
+	keys            jwt.KeyProvider
+	bcryptPool      *bcryptpool.Pool
+	cfg             Config
+
+	// dummyPassHash is compared against when user doesn't exist, so the
+	// response takes as long as for a wrong password.
+	dummyPassHash func() []byte
+}
+
+// Config holds Auth service settings.
+type Config struct {
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
+	VerificationTokenTTL  time.Duration
+	PasswordResetTokenTTL time.Duration
+	IdempotencyKeyTTL     time.Duration
+	PasswordPolicy        PasswordPolicy
+	RequireVerifiedEmail  bool
+	// Issuer is put into iss claim and required when validating tokens.
+	Issuer string
+	// ClockSkewLeeway is tolerated clock difference when checking token
+	// expiry.
+	ClockSkewLeeway time.Duration
+	// BcryptWorkers bounds concurrent password hashing and comparisons.
+	// Zero means GOMAXPROCS.
+	BcryptWorkers int
+	// AppCacheTTL is how long looked up apps are reused. Zero disables
+	// caching.
+	AppCacheTTL time.Duration
+	// AdminCacheTTL is how long IsAdmin results are reused. Zero disables
+	// caching.
+	AdminCacheTTL time.Duration
+	// AdminCacheSize bounds number of cached IsAdmin results.
+	AdminCacheSize int
+	// PrehashPasswords feeds SHA-256 of password to bcrypt instead of
+	// rejecting passwords over MaxPasswordBytes. Changes stored hashes, so
+	// it must not be toggled once users exist.
+	PrehashPasswords bool
+	// RevocationFailurePolicy is RevocationFailClosed or RevocationFailOpen;
+	// empty means RevocationFailClosed.
+	RevocationFailurePolicy string
+	// TokenAlgorithms are signing algorithms accepted when validating
+	// tokens; empty means all the jwt package supports.
+	TokenAlgorithms []string
+	// LockoutRetryAfter makes Login tell locked out callers when they can
+	// retry, see LockoutError.
+	LockoutRetryAfter bool
+	// RehashOnLogin replaces stored hash on successful login when hasher
+	// would make a stronger one, e.g. after bcrypt cost was raised.
+	RehashOnLogin bool
+	// DefaultAppID is used by Login and RefreshToken when called with zero
+	// appID; zero means appID is required.
+	DefaultAppID int
 }
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrUsernameTaken       = errors.New("username already taken")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrTooManyAttempts     = errors.New("too many login attempts")
+	ErrEmailNotVerified    = errors.New("email not verified")
+	ErrPermissionDenied    = errors.New("permission denied")
+	ErrUnknownRole         = errors.New("unknown role")
+	ErrPasswordRequired    = errors.New("password is required")
+	ErrInvalidAppID        = errors.New("invalid app id")
+)
+
+const (
+	refreshTokenSize = 32
+	userTokenSize    = 32
+
+	defaultListUsersLimit = 50
+	maxListUsersLimit     = 500
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
@@ -34,109 +122,600 @@ type UserSaver interface {
 		email string,
 		passHash []byte,
 	) (uid int64, err error)
+	SaveUserWithUsername(
+		ctx context.Context,
+		email string,
+		username string,
+		passHash []byte,
+	) (uid int64, err error)
+	// SaveUserWithRoles saves user and grants it roles atomically.
+	SaveUserWithRoles(
+		ctx context.Context,
+		email string,
+		passHash []byte,
+		roles []string,
+	) (uid int64, err error)
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (models.User, error)
-	IsAdmin(ctx context.Context, userID int64) (bool, error)
+	UserByUsername(ctx context.Context, username string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	// ListUsers returns users ordered by id, without password hashes.
+	ListUsers(ctx context.Context, limit, offset int) ([]models.User, error)
+	// Ping checks that storage is reachable.
+	Ping(ctx context.Context) error
+}
+
+type RoleProvider interface {
+	HasRole(ctx context.Context, userID int64, role string) (bool, error)
+	// HasRoleBatch returns role membership for existing users among userIDs;
+	// unknown IDs are absent from the result.
+	HasRoleBatch(ctx context.Context, userIDs []int64, role string) (map[int64]bool, error)
+	HasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+}
+
+// UserUpdater modifies or removes existing users.
+type UserUpdater interface {
+	UpdatePasswordHash(ctx context.Context, userID int64, passHash []byte) error
+	// IncrementTokenVersion invalidates all tokens issued to user so far.
+	IncrementTokenVersion(ctx context.Context, userID int64) error
+	// DeleteUser removes user together with its roles, sessions, refresh,
+	// idempotency and one-time tokens.
+	DeleteUser(ctx context.Context, userID int64) error
+	// AssignRole grants role with given name to user.
+	AssignRole(ctx context.Context, userID int64, role string) error
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int) (models.App, error)
 }
 
+type RefreshTokenStorage interface {
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RefreshToken(ctx context.Context, token string) (models.RefreshToken, error)
+	// RevokeRefreshTokens marks all refresh tokens of user as revoked.
+	RevokeRefreshTokens(ctx context.Context, userID int64) error
+}
+
+// RevocationStore keeps IDs (jti) of revoked tokens until they expire.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// SessionStore records access tokens issued to users.
+type SessionStore interface {
+	SaveSession(ctx context.Context, session models.Session) error
+	// Sessions returns user's sessions whose tokens aren't revoked, newest
+	// first.
+	Sessions(ctx context.Context, userID int64) ([]models.Session, error)
+}
+
+// UserTokenStorage keeps one-time user tokens, e.g. for email verification.
+type UserTokenStorage interface {
+	SaveUserToken(ctx context.Context, token models.UserToken) error
+	UserToken(ctx context.Context, token string, purpose string) (models.UserToken, error)
+	DeleteUserToken(ctx context.Context, token string) error
+	SetEmailVerified(ctx context.Context, userID int64) error
+}
+
+// Transactor runs fn in storage transaction: storage calls made with ctx
+// passed to fn are committed together or not at all.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// AuditLogger records authentication decisions. Implementations must not
+// fail the request, so Record returns nothing.
+type AuditLogger interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// Hasher hashes passwords and checks them against stored hashes. Hashes
+// carry algorithm prefix, so Compare works for hashes made by any algorithm
+// hasher supports.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) error
+	// NeedsRehash reports whether hash should be replaced by a fresh one.
+	NeedsRehash(hash string) bool
+}
+
+// LoginLimiter tracks failed login attempts per key (email).
+type LoginLimiter interface {
+	Allow(key string) bool
+	// RetryAfter returns how long until key is allowed again.
+	RetryAfter(key string) time.Duration
+	Fail(key string)
+	Reset(key string)
+}
+
 func New(
 	log *slog.Logger,
 	userSaver UserSaver,
 	userProvider UserProvider,
+	userUpdater UserUpdater,
+	roleProvider RoleProvider,
 	appProvider AppProvider,
-	tokenTTL time.Duration,
+	refreshStorage RefreshTokenStorage,
+	revocationStore RevocationStore,
+	sessions SessionStore,
+	userTokens UserTokenStorage,
+	idempotencyKeys IdempotencyStore,
+	transactor Transactor,
+	loginLimiter LoginLimiter,
+	audit AuditLogger,
+	hasher Hasher,
+	keys jwt.KeyProvider,
+	cfg Config,
 ) *Auth {
+	dummyPassHash := sync.OnceValue(func() []byte {
+		hash, err := hasher.Hash(dummyPassword)
+		if err != nil {
+			panic(err)
+		}
+
+		return []byte(hash)
+	})
+	// Warm up, so the first unknown-user login isn't slower than others.
+	go dummyPassHash()
+
+	events := newEventBroker()
+
+	var appCache *cachedAppProvider
+	if cfg.AppCacheTTL > 0 {
+		appCache = newCachedAppProvider(appProvider, cfg.AppCacheTTL)
+		appProvider = appCache
+	}
+
+	var admins *adminCache
+	if cfg.AdminCacheTTL > 0 && cfg.AdminCacheSize > 0 {
+		admins = newAdminCache(cfg.AdminCacheTTL, cfg.AdminCacheSize)
+	}
+
 	return &Auth{
-		usrSaver:    userSaver,
-		usrProvider: userProvider,
-		log:         log,
-		appProvider: appProvider,
-		tokenTTL:    tokenTTL,
+		usrSaver:        userSaver,
+		usrProvider:     userProvider,
+		usrUpdater:      userUpdater,
+		roleProvider:    roleProvider,
+		log:             log,
+		appProvider:     appProvider,
+		appCache:        appCache,
+		adminCache:      admins,
+		refreshStorage:  refreshStorage,
+		revocationStore: revocationStore,
+		sessions:        sessions,
+		userTokens:      userTokens,
+		idempotencyKeys: idempotencyKeys,
+		transactor:      transactor,
+		loginLimiter:    loginLimiter,
+		audit:           teeAudit{audit, events},
+		events:          events,
+		hasher:          hasher,
+		keys:            keys,
+		bcryptPool:      bcryptpool.New(cfg.BcryptWorkers),
+		cfg:             cfg,
+		dummyPassHash:   dummyPassHash,
 	}
 }
 
+// LockoutError is returned by Login while login is locked out after too many
+// failed attempts. It matches ErrTooManyAttempts. RetryAfter is how long the
+// lockout lasts; zero if unknown or not to be disclosed.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
 // Login checks if user with given credentials exists in the system and returns access token.
+// login is email or, if it has no "@", username.
 //
+// Arguments are validated before storage is touched: malformed login fails
+// with ErrInvalidEmail or ErrInvalidUsername, empty password with
+// ErrPasswordRequired and non-positive appID with ErrInvalidAppID.
+// Zero appID means Config.DefaultAppID.
 // If user exists, but password is incorrect, returns error.
 // If user doesn't exist, returns error.
+// If there were too many failed attempts for login, returns *LockoutError
+// matching ErrTooManyAttempts.
+// If verified email is required and user hasn't verified it, returns ErrEmailNotVerified.
 func (a *Auth) Login(
 	ctx context.Context,
-	email string,
+	login string,
 	password string,
 	appID int,
-) (string, error) {
+) (_ string, err error) {
 	const op = "Auth.Login"
 
+	var userID int64
+
+	ctx, span := tracer.Start(ctx, op)
+	defer func() {
+		metrics.LoginTotal.WithLabelValues(loginResult(err)).Inc()
+		a.audit.Record(ctx, models.AuditEvent{
+			Type:   models.AuditLogin,
+			Time:   time.Now(),
+			UserID: userID,
+			Email:  login,
+			Result: loginResult(err),
+		})
+		endSpan(span, err)
+	}()
+
 	log := a.log.With(
 		slog.String("op", op),
-		slog.String("username", email),
+		slog.String("username", login),
+		slog.String("client_ip", requestinfo.ClientIP(ctx)),
+		slog.String("user_agent", requestinfo.UserAgent(ctx)),
 	)
 
 	log.Info("attempting to login user")
 
-	user, err := a.usrProvider.User(ctx, email)
+	byEmail := isEmailLogin(login)
+	if byEmail {
+		err = validateEmail(login)
+	} else {
+		err = validateUsername(login)
+	}
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			a.log.Warn("user not found", sl.Err(err))
+		log.Info("invalid login")
 
-			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if password == "" {
+		log.Info("empty password")
+
+		return "", fmt.Errorf("%s: %w", op, ErrPasswordRequired)
+	}
+	appID = a.appIDOrDefault(appID)
+	if appID <= 0 {
+		log.Info("invalid app id", slog.Int("app_id", appID))
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+	}
+
+	// Unknown app is rejected before any password work is spent on it.
+	spanCtx, phase := tracer.Start(ctx, "storage.App")
+	app, err := a.appByID(spanCtx, log, appID)
+	endSpan(phase, err)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
 
-		a.log.Error("failed to get user", sl.Err(err))
+	spanCtx, phase = tracer.Start(ctx, "storage.User")
+	user, err := a.userByLogin(spanCtx, login, byEmail)
+	endSpan(phase, err)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		log.Error("failed to get user", sl.Err(err))
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		a.log.Info("invalid credentials", sl.Err(err))
+	// Failures are counted per user, whichever login it's named by.
+	limiterKey := lockoutKey(user.ID, login)
+	if !a.loginLimiter.Allow(limiterKey) {
+		lockout := &LockoutError{}
+		if a.cfg.LockoutRetryAfter {
+			lockout.RetryAfter = a.loginLimiter.RetryAfter(limiterKey)
+		}
+
+		log.Warn("too many login attempts", slog.Duration("retry_after", lockout.RetryAfter))
+
+		return "", fmt.Errorf("%s: %w", op, lockout)
+	}
+
+	if err != nil {
+		log.Warn("user not found", sl.Err(err))
+
+		// Spend the same time as for a wrong password, so response
+		// timing doesn't tell whether the login is registered.
+		_ = a.comparePassword(ctx, a.dummyPassHash(), password)
+		a.loginLimiter.Fail(limiterKey)
 
 		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
-	app, err := a.appProvider.App(ctx, appID)
+	userID = user.ID
+
+	spanCtx, phase = tracer.Start(ctx, "bcrypt.Compare")
+	err = a.comparePassword(spanCtx, user.PassHash, password)
+	endSpan(phase, err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		log.Info("request cancelled while comparing password", sl.Err(ctxErr))
+
+		return "", fmt.Errorf("%s: %w", op, ctxErr)
+	}
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		log.Info("invalid credentials", sl.Err(err))
+
+		a.loginLimiter.Fail(limiterKey)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 	}
 
-	log.Info("user logged in successfully")
+	a.loginLimiter.Reset(limiterKey)
 
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+	a.rehashPassword(ctx, log, user, password)
+
+	if a.cfg.RequireVerifiedEmail && !user.EmailVerified {
+		log.Info("email not verified")
+
+		return "", fmt.Errorf("%s: %w", op, ErrEmailNotVerified)
+	}
+
+	_, phase = tracer.Start(ctx, "jwt.NewToken")
+	token, err := jwt.NewToken(user, app, a.cfg.Issuer, a.accessTokenTTL(app), a.keys)
+	endSpan(phase, err)
 	if err != nil {
-		a.log.Error("failed to generate token", sl.Err(err))
+		log.Error("failed to generate token", sl.Err(err))
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return token, nil
+	if err := a.saveSession(ctx, user.ID, app.ID, token); err != nil {
+		log.Error("failed to save session", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in successfully")
+
+	return token.Signed, nil
 }
 
 // RegisterNewUser registers new user in the system and returns user ID.
 // If user with given username already exists, returns error.
+// If email is malformed, returns ErrInvalidEmail.
+// If password doesn't satisfy password policy, returns *WeakPasswordError
+// matching ErrWeakPassword, or ErrPasswordTooLong.
 func (a *Auth) RegisterNewUser(ctx context.Context, email string, pass string) (int64, error) {
-	const op = "Auth.RegisterNewUser"
+	return a.register(ctx, "Auth.RegisterNewUser", email, "", pass, nil)
+}
+
+// RegisterNewUserWithUsername is RegisterNewUser that also sets username
+// user can log in with. If username is taken, returns ErrUsernameTaken.
+func (a *Auth) RegisterNewUserWithUsername(ctx context.Context, email, username, pass string) (int64, error) {
+	return a.register(ctx, "Auth.RegisterNewUserWithUsername", email, username, pass, nil)
+}
+
+// RegisterWithRole registers new user already granted roles, e.g. to
+// provision an admin in one call. The user and its roles are saved
+// atomically. Caller must be an admin (claims in ctx, see authctx),
+// otherwise ErrPermissionDenied is returned. Unknown role fails with
+// ErrUnknownRole and nothing is saved.
+func (a *Auth) RegisterWithRole(ctx context.Context, email, pass string, roles []string) (int64, error) {
+	const op = "Auth.RegisterWithRole"
+
+	if err := a.requireAdmin(ctx, op); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := a.register(ctx, op, email, "", pass, roles)
+	if err != nil {
+		return 0, err
+	}
+
+	// IsAdmin of this ID may have been cached before the user existed.
+	a.InvalidateUserRoles(id)
+
+	return id, nil
+}
+
+// AssignRole grants role to existing user. Only admins can call it. Cached
+// IsAdmin result of user is dropped, so the change applies at once.
+//
+// If user doesn't exist, returns ErrUserNotFound; if role doesn't,
+// ErrUnknownRole.
+func (a *Auth) AssignRole(ctx context.Context, userID int64, role string) error {
+	const op = "Auth.AssignRole"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.String("role", role),
+	)
+
+	if err := a.requireAdmin(ctx, op); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := a.usrProvider.UserByID(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.usrUpdater.AssignRole(ctx, userID, role); err != nil {
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Info("unknown role")
+
+			return fmt.Errorf("%s: %w", op, ErrUnknownRole)
+		}
+
+		log.Error("failed to assign role", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.InvalidateUserRoles(userID)
+
+	log.Info("role assigned")
+
+	return nil
+}
+
+// requireAdmin returns ErrPermissionDenied unless caller authenticated in
+// ctx (see authctx.ClaimsFromContext) has admin role.
+func (a *Auth) requireAdmin(ctx context.Context, op string) error {
+	claims, ok := authctx.ClaimsFromContext(ctx)
+	if !ok {
+		return ErrPermissionDenied
+	}
+
+	isAdmin, err := a.hasAdminRole(ctx, claims.UID)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		a.log.Error("failed to check if caller is admin",
+			slog.String("op", op),
+			slog.Int64("caller_id", claims.UID),
+			sl.Err(err),
+		)
+
+		return err
+	}
+	if !isAdmin {
+		a.log.Warn("non-admin caller denied",
+			slog.String("op", op),
+			slog.Int64("caller_id", claims.UID),
+		)
+
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// register saves new user; empty username means none, roles are granted
+// in the same transaction.
+func (a *Auth) register(ctx context.Context, op, email, username, pass string, roles []string) (int64, error) {
+	return a.registerWith(ctx, op, email, username, pass, roles, nil)
+}
+
+// registerWith is register that also runs then with saved user ID. User is
+// saved and then runs in one transaction, so if then fails, the user is
+// rolled back. Password is hashed, and metrics and audit are recorded,
+// outside of the transaction. Errors of then are returned as is, then logs
+// them itself.
+func (a *Auth) registerWith(
+	ctx context.Context,
+	op, email, username, pass string,
+	roles []string,
+	then func(ctx context.Context, id int64) error,
+) (id int64, err error) {
+	ctx, span := tracer.Start(ctx, op)
+	defer func() {
+		metrics.RegisterTotal.WithLabelValues(registerResult(err)).Inc()
+		a.audit.Record(ctx, models.AuditEvent{
+			Type:   models.AuditRegister,
+			Time:   time.Now(),
+			UserID: id,
+			Email:  email,
+			Result: registerResult(err),
+		})
+		endSpan(span, err)
+	}()
 
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("email", email),
 	)
+	if username != "" {
+		log = log.With(slog.String("username", username))
+	}
 
 	log.Info("registering user")
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err := validateEmail(email); err != nil {
+		log.Info("invalid email")
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if username != "" {
+		if err := validateUsername(username); err != nil {
+			log.Info("invalid username")
+
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := a.validateNewPassword(pass); err != nil {
+		log.Info("password rejected", sl.Err(err))
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	spanCtx, phase := tracer.Start(ctx, "bcrypt.Hash")
+	passHash, err := a.hashPassword(spanCtx, pass)
+	endSpan(phase, err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		log.Info("request cancelled while hashing password", sl.Err(ctxErr))
+
+		return 0, fmt.Errorf("%s: %w", op, ctxErr)
+	}
 	if err != nil {
 		log.Error("failed to generate password hash", sl.Err(err))
 
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := a.usrSaver.SaveUser(ctx, email, passHash)
+	var saveErr, thenErr error
+
+	save := func(ctx context.Context) error {
+		switch {
+		case len(roles) > 0:
+			id, saveErr = a.usrSaver.SaveUserWithRoles(ctx, email, passHash, roles)
+		case username != "":
+			id, saveErr = a.usrSaver.SaveUserWithUsername(ctx, email, username, passHash)
+		default:
+			id, saveErr = a.usrSaver.SaveUser(ctx, email, passHash)
+		}
+		if saveErr != nil {
+			return saveErr
+		}
+
+		if then != nil {
+			thenErr = then(ctx, id)
+		}
+
+		return thenErr
+	}
+
+	spanCtx, phase = tracer.Start(ctx, "storage.SaveUser")
+	if then == nil {
+		err = save(spanCtx)
+	} else {
+		err = a.transactor.WithTx(spanCtx, save)
+	}
+	endSpan(phase, err)
+	if thenErr != nil {
+		return 0, fmt.Errorf("%s: %w", op, thenErr)
+	}
 	if err != nil {
+		if saveErr == nil {
+			log.Error("failed to commit user", sl.Err(err))
+
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Warn("user already exists", sl.Err(err))
+
+			return 0, fmt.Errorf("%s: %w", op, ErrUserAlreadyExists)
+		}
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			log.Warn("username already taken", sl.Err(err))
+
+			return 0, fmt.Errorf("%s: %w", op, ErrUsernameTaken)
+		}
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Info("unknown role", slog.Any("roles", roles))
+
+			return 0, fmt.Errorf("%s: %w", op, ErrUnknownRole)
+		}
+
 		log.Error("failed to save user", sl.Err(err))
 
 		return 0, fmt.Errorf("%s: %w", op, err)
@@ -145,7 +724,7 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email string, pass string) (
 	return id, nil
 }
 
-// IsAdmin checks if user is admin.
+// IsAdmin checks if user has admin role.
 func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	const op = "Auth.IsAdmin"
 
@@ -156,8 +735,22 @@ func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 
 	log.Info("checking if user is admin")
 
-	isAdmin, err := a.usrProvider.IsAdmin(ctx, userID)
+	isAdmin, err := a.hasAdminRole(ctx, userID)
+	a.audit.Record(ctx, models.AuditEvent{
+		Type:   models.AuditIsAdmin,
+		Time:   time.Now(),
+		UserID: userID,
+		Result: isAdminResult(isAdmin, err),
+	})
 	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return false, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to check if user is admin", sl.Err(err))
+
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -165,3 +758,359 @@ func (a *Auth) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 
 	return isAdmin, nil
 }
+
+// DeleteUser removes user and revokes its unexpired sessions in the same
+// transaction, so their tokens stay rejected even if the user id is
+// reused. Caller must be an admin (claims in ctx, see authctx), otherwise
+// ErrPermissionDenied is returned.
+// If user doesn't exist, returns ErrUserNotFound.
+func (a *Auth) DeleteUser(ctx context.Context, userID int64) error {
+	const op = "Auth.DeleteUser"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.requireAdmin(ctx, op); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("deleting user")
+
+	err := a.transactor.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := a.revokeSessions(ctx, userID); err != nil {
+			return err
+		}
+
+		return a.usrUpdater.DeleteUser(ctx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to delete user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.InvalidateUserRoles(userID)
+
+	log.Info("user deleted")
+
+	return nil
+}
+
+// appIDOrDefault returns appID, or DefaultAppID if appID is zero.
+func (a *Auth) appIDOrDefault(appID int) int {
+	if appID == 0 {
+		return a.cfg.DefaultAppID
+	}
+
+	return appID
+}
+
+// appByID returns app by ID. If there's no such app, returns
+// ErrInvalidAppID: it's a client mistake, not a server failure.
+func (a *Auth) appByID(ctx context.Context, log *slog.Logger, appID int) (models.App, error) {
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", slog.Int("app_id", appID))
+
+			return models.App{}, ErrInvalidAppID
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return models.App{}, err
+	}
+
+	return app, nil
+}
+
+// hasAdminRole is roleProvider.HasRole for admin role behind adminCache.
+func (a *Auth) hasAdminRole(ctx context.Context, userID int64) (bool, error) {
+	if a.adminCache != nil {
+		if isAdmin, ok := a.adminCache.get(userID); ok {
+			return isAdmin, nil
+		}
+	}
+
+	isAdmin, err := a.roleProvider.HasRole(ctx, userID, models.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	if a.adminCache != nil {
+		a.adminCache.set(userID, isAdmin)
+	}
+
+	return isAdmin, nil
+}
+
+// ListUsers returns page of users ordered by id. Non-positive limit means
+// default page size, limit above maximum is clamped. Password hashes are
+// never returned. Caller must be an admin (claims in ctx, see authctx),
+// otherwise ErrPermissionDenied is returned.
+func (a *Auth) ListUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	const op = "Auth.ListUsers"
+
+	if err := a.requireAdmin(ctx, op); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case limit <= 0:
+		limit = defaultListUsersLimit
+	case limit > maxListUsersLimit:
+		limit = maxListUsersLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("limit", limit),
+		slog.Int("offset", offset),
+	)
+
+	log.Info("listing users")
+
+	users, err := a.usrProvider.ListUsers(ctx, limit, offset)
+	if err != nil {
+		log.Error("failed to list users", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range users {
+		users[i].PassHash = nil
+	}
+
+	return users, nil
+}
+
+// BatchIsAdmin checks admin role for several users with a single storage
+// query. Unknown user IDs are returned in missing instead of failing the
+// whole batch.
+func (a *Auth) BatchIsAdmin(ctx context.Context, userIDs []int64) (admins map[int64]bool, missing []int64, err error) {
+	const op = "Auth.BatchIsAdmin"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("count", len(userIDs)),
+	)
+
+	log.Info("checking if users are admins")
+
+	if len(userIDs) == 0 {
+		return map[int64]bool{}, nil, nil
+	}
+
+	admins, err = a.roleProvider.HasRoleBatch(ctx, userIDs, models.RoleAdmin)
+	if err != nil {
+		log.Error("failed to check if users are admins", sl.Err(err))
+
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, id := range userIDs {
+		if _, ok := admins[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	log.Info("checked if users are admins", slog.Int("missing", len(missing)))
+
+	return admins, missing, nil
+}
+
+// IssueRefreshToken creates new refresh token for user and app and returns it.
+func (a *Auth) IssueRefreshToken(ctx context.Context, userID int64, appID int) (string, error) {
+	const op = "Auth.IssueRefreshToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.Int("app_id", appID),
+	)
+
+	token, err := random.Token(refreshTokenSize)
+	if err != nil {
+		log.Error("failed to generate refresh token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.refreshStorage.SaveRefreshToken(ctx, models.RefreshToken{
+		Token:     token,
+		UserID:    userID,
+		AppID:     appID,
+		ExpiresAt: time.Now().Add(a.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		log.Error("failed to save refresh token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// RegisterNewUserWithRefreshToken is RegisterNewUser that also issues
+// refresh token for appID, so client is signed in right after sign-up.
+// Zero appID means Config.DefaultAppID. User and token are saved in one
+// transaction: if the token can't be saved, the user isn't created either.
+func (a *Auth) RegisterNewUserWithRefreshToken(ctx context.Context, email, pass string, appID int) (int64, string, error) {
+	const op = "Auth.RegisterNewUserWithRefreshToken"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	appID = a.appIDOrDefault(appID)
+	if appID <= 0 {
+		log.Info("invalid app id", slog.Int("app_id", appID))
+
+		return 0, "", fmt.Errorf("%s: %w", op, ErrInvalidAppID)
+	}
+
+	if _, err := a.appByID(ctx, log, appID); err != nil {
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var refreshToken string
+
+	id, err := a.registerWith(ctx, op, email, "", pass, nil, func(ctx context.Context, id int64) error {
+		var err error
+
+		refreshToken, err = a.IssueRefreshToken(ctx, id, appID)
+
+		return err
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	return id, refreshToken, nil
+}
+
+// RefreshToken validates refresh token and returns new access token for its user.
+// Zero appID means Config.DefaultAppID.
+//
+// If refresh token doesn't exist, is expired, revoked or was issued for
+// another app, returns ErrInvalidRefreshToken.
+func (a *Auth) RefreshToken(ctx context.Context, refreshToken string, appID int) (string, error) {
+	const op = "Auth.RefreshToken"
+
+	appID = a.appIDOrDefault(appID)
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("app_id", appID),
+	)
+
+	log.Info("refreshing access token")
+
+	rt, err := a.refreshStorage.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenNotFound) {
+			log.Warn("refresh token not found", sl.Err(err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+
+		log.Error("failed to get refresh token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rt.Revoked || rt.AppID != appID || !time.Now().Before(rt.ExpiresAt) {
+		log.Warn("refresh token is not valid",
+			slog.Bool("revoked", rt.Revoked),
+			slog.Time("expires_at", rt.ExpiresAt),
+		)
+
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	}
+
+	user, err := a.usrProvider.UserByID(ctx, rt.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.appByID(ctx, log, appID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := jwt.NewToken(user, app, a.cfg.Issuer, a.accessTokenTTL(app), a.keys)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.saveSession(ctx, user.ID, app.ID, token); err != nil {
+		log.Error("failed to save session", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("access token refreshed", slog.Int64("user_id", user.ID))
+
+	return token.Signed, nil
+}
+
+// HasPermission checks if any role of user grants permission.
+func (a *Auth) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	const op = "Auth.HasPermission"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.String("permission", permission),
+	)
+
+	log.Info("checking user permission")
+
+	allowed, err := a.roleProvider.HasPermission(ctx, userID, permission)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return false, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to check user permission", sl.Err(err))
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("checked user permission", slog.Bool("allowed", allowed))
+
+	return allowed, nil
+}
+
+// accessTokenTTL returns app's token TTL override or the global default.
+func (a *Auth) accessTokenTTL(app models.App) time.Duration {
+	if app.TokenTTL > 0 {
+		return app.TokenTTL
+	}
+
+	return a.cfg.AccessTokenTTL
+}
@@ -0,0 +1,847 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/hasher"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/ratelimit"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"sso/internal/storage/migrate"
+	"sso/internal/storage/sqlite"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testIssuer   = "sso-test"
+	testPassword = "Secret123"
+)
+
+type nopAudit struct{}
+
+func (nopAudit) Record(context.Context, models.AuditEvent) {}
+
+// newTestStorage returns sqlite storage backed by a fresh migrated db file.
+func newTestStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sso.db")
+
+	if _, err := migrate.Up(storage.DriverSQLite, path, "../../../migrations", migrate.DefaultTable); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	s, err := sqlite.New(path, storage.PoolConfig{MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Stop() })
+
+	return s
+}
+
+// newTestAuth returns Auth backed entirely by store. Zero AccessTokenTTL and
+// Issuer in cfg get test defaults, nil limiter allows 100 failures a minute.
+func newTestAuth(t *testing.T, store *sqlite.Storage, limiter auth.LoginLimiter, cfg auth.Config) *auth.Auth {
+	t.Helper()
+
+	return newTestAuthWith(t, store, testDeps{limiter: limiter}, cfg)
+}
+
+// testDeps overrides Auth dependencies newTestAuthWith takes from store or
+// defaults; nil ones are left to it.
+type testDeps struct {
+	saver    auth.UserSaver
+	provider auth.UserProvider
+	updater  auth.UserUpdater
+	roles    auth.RoleProvider
+	apps     auth.AppProvider
+	refresh  auth.RefreshTokenStorage
+	revoked  auth.RevocationStore
+	keys     auth.IdempotencyStore
+	limiter  auth.LoginLimiter
+	audit    auth.AuditLogger
+	hasher   auth.Hasher
+	signing  jwt.KeyProvider
+}
+
+// newTestAuthWith is newTestAuth with dependencies replaced by deps.
+func newTestAuthWith(t *testing.T, store *sqlite.Storage, deps testDeps, cfg auth.Config) *auth.Auth {
+	t.Helper()
+
+	if cfg.AccessTokenTTL == 0 {
+		cfg.AccessTokenTTL = time.Hour
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = testIssuer
+	}
+	if deps.saver == nil {
+		deps.saver = store
+	}
+	if deps.provider == nil {
+		deps.provider = store
+	}
+	if deps.updater == nil {
+		deps.updater = store
+	}
+	if deps.roles == nil {
+		deps.roles = store
+	}
+	if deps.apps == nil {
+		deps.apps = store
+	}
+	if deps.refresh == nil {
+		deps.refresh = store
+	}
+	if deps.revoked == nil {
+		deps.revoked = store
+	}
+	if deps.keys == nil {
+		deps.keys = store
+	}
+	if deps.limiter == nil {
+		deps.limiter = ratelimit.NewSlidingWindow(100, time.Minute)
+	}
+	if deps.audit == nil {
+		deps.audit = nopAudit{}
+	}
+	if deps.signing == nil {
+		deps.signing = jwt.StorageKeys{}
+	}
+	if deps.hasher == nil {
+		deps.hasher = hasher.New(hasher.NewBcrypt(bcrypt.MinCost))
+	}
+
+	return auth.New(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		deps.saver,
+		deps.provider,
+		deps.updater,
+		deps.roles,
+		deps.apps,
+		deps.refresh,
+		deps.revoked,
+		store,
+		store,
+		deps.keys,
+		store,
+		deps.limiter,
+		deps.audit,
+		deps.hasher,
+		deps.signing,
+		cfg,
+	)
+}
+
+// parseTestToken verifies token issued for app by newTestAuth.
+func parseTestToken(t *testing.T, store *sqlite.Storage, appID int, token string) *jwt.Claims {
+	t.Helper()
+
+	app, err := store.App(context.Background(), appID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	claims, err := jwt.ParseToken(token, app, jwt.WithIssuer(testIssuer))
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+
+	return claims
+}
+
+// failingSaver fails to save users with err.
+type failingSaver struct {
+	*sqlite.Storage
+	err error
+}
+
+func (s failingSaver) SaveUser(context.Context, string, []byte) (int64, error) {
+	return 0, fmt.Errorf("storage.sqlite.SaveUser: %w", s.err)
+}
+
+func TestRegisterStorageErrors(t *testing.T) {
+	store := newTestStorage(t)
+	errDown := errors.New("storage is down")
+
+	tests := []struct {
+		name     string
+		err      error
+		want     error
+		wantLeak bool
+	}{
+		{"user exists", storage.ErrUserExists, auth.ErrUserAlreadyExists, false},
+		{"other error", errDown, errDown, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthWith(t, store, testDeps{saver: failingSaver{store, tt.err}}, auth.Config{})
+
+			_, err := a.RegisterNewUser(context.Background(), "user@example.com", testPassword)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("RegisterNewUser: got %v, want %v", err, tt.want)
+			}
+			if leaked := errors.Is(err, tt.err); leaked != tt.wantLeak {
+				t.Errorf("RegisterNewUser error %v wraps storage error: %t, want %t", err, leaked, tt.wantLeak)
+			}
+		})
+	}
+}
+
+func TestLoginAppTokenTTL(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{AccessTokenTTL: time.Hour})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	override, err := store.SaveApp(ctx, "mobile", "mobile-secret", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	global, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		appID int
+		want  time.Duration
+	}{
+		{"app override", override, 5 * time.Minute},
+		{"no override", global, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := a.Login(ctx, "user@example.com", testPassword, tt.appID)
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			claims := parseTestToken(t, store, tt.appID, token)
+			if got := claims.ExpiresAt.Sub(claims.IssuedAt.Time); got != tt.want {
+				t.Errorf("token lifetime = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	token, err := a.Login(ctx, "user@example.com", testPassword, appID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	claims := parseTestToken(t, store, appID, token)
+	if claims.UID != uid || claims.Email != "user@example.com" || claims.AppID != appID {
+		t.Errorf("claims uid=%d email=%q app=%d, want %d, user@example.com, %d",
+			claims.UID, claims.Email, claims.AppID, uid, appID)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		appID    int
+		want     error
+	}{
+		{"wrong password", "user@example.com", "Wrong1234", appID, auth.ErrInvalidCredentials},
+		{"unknown user", "nobody@example.com", testPassword, appID, auth.ErrInvalidCredentials},
+		{"empty password", "user@example.com", "", appID, auth.ErrPasswordRequired},
+		{"invalid app id", "user@example.com", testPassword, -1, auth.ErrInvalidAppID},
+		{"unknown app", "user@example.com", testPassword, appID + 1, auth.ErrInvalidAppID},
+		{"unknown app with wrong password", "user@example.com", "Wrong1234", appID + 1, auth.ErrInvalidAppID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.Login(ctx, tt.email, tt.password, tt.appID)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Login: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id   int64
+		want bool
+	}{{user, false}, {admin, true}} {
+		got, err := a.IsAdmin(ctx, tc.id)
+		if err != nil {
+			t.Fatalf("IsAdmin(%d): %v", tc.id, err)
+		}
+		if got != tc.want {
+			t.Errorf("IsAdmin(%d) = %t, want %t", tc.id, got, tc.want)
+		}
+	}
+
+	if _, err := a.IsAdmin(ctx, admin+1); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("IsAdmin of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestRegisterWithRole(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	roles := []string{models.RoleAdmin}
+	if _, err := a.RegisterWithRole(ctx, "new@example.com", testPassword, roles); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("RegisterWithRole unauthenticated: got %v, want ErrPermissionDenied", err)
+	}
+	if _, err := a.RegisterWithRole(asCaller(user), "new@example.com", testPassword, roles); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("RegisterWithRole by non-admin: got %v, want ErrPermissionDenied", err)
+	}
+	if _, err := a.RegisterWithRole(asCaller(admin), "new@example.com", testPassword, []string{"nope"}); !errors.Is(err, auth.ErrUnknownRole) {
+		t.Errorf("RegisterWithRole with unknown role: got %v, want ErrUnknownRole", err)
+	}
+	if _, err := store.User(ctx, "new@example.com"); err == nil {
+		t.Fatal("user saved despite unknown role")
+	}
+
+	id, err := a.RegisterWithRole(asCaller(admin), "new@example.com", testPassword, roles)
+	if err != nil {
+		t.Fatalf("RegisterWithRole: %v", err)
+	}
+	isAdmin, err := a.IsAdmin(ctx, id)
+	if err != nil {
+		t.Fatalf("IsAdmin: %v", err)
+	}
+	if !isAdmin {
+		t.Error("user registered with admin role is not admin")
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{AdminCacheTTL: time.Hour, AdminCacheSize: 10})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	if err := a.AssignRole(ctx, user, models.RoleAdmin); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("AssignRole unauthenticated: got %v, want ErrPermissionDenied", err)
+	}
+	if err := a.AssignRole(asCaller(user), user, models.RoleAdmin); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("AssignRole by non-admin: got %v, want ErrPermissionDenied", err)
+	}
+	if err := a.AssignRole(asCaller(admin), user, "nope"); !errors.Is(err, auth.ErrUnknownRole) {
+		t.Errorf("AssignRole of unknown role: got %v, want ErrUnknownRole", err)
+	}
+	if err := a.AssignRole(asCaller(admin), user+100, models.RoleAdmin); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("AssignRole to unknown user: got %v, want ErrUserNotFound", err)
+	}
+
+	// Cache the "not admin" answer, then check the grant isn't hidden by it.
+	if isAdmin, err := a.IsAdmin(ctx, user); err != nil || isAdmin {
+		t.Fatalf("IsAdmin before AssignRole = %t, %v; want false", isAdmin, err)
+	}
+	if err := a.AssignRole(asCaller(admin), user, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	if isAdmin, err := a.IsAdmin(ctx, user); err != nil || !isAdmin {
+		t.Errorf("IsAdmin after AssignRole = %t, %v; want true", isAdmin, err)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	admin, err := store.SaveUser(ctx, "admin@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	// More users than the max page, so the clamp is observable.
+	for i := range 600 {
+		if _, err := store.SaveUser(ctx, fmt.Sprintf("user%d@example.com", i), []byte("hash")); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+	}
+
+	if _, err := a.ListUsers(asCaller(admin+1), 10, 0); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("ListUsers by non-admin: got %v, want ErrPermissionDenied", err)
+	}
+
+	tests := []struct {
+		name          string
+		limit, offset int
+		want          int
+	}{
+		{"default limit", 0, 0, 50},
+		{"clamped limit", 1000, 0, 500},
+		{"negative offset", 10, -5, 10},
+		{"last page", 10, 595, 6},
+		{"past end", 10, 601, 0},
+	}
+	for _, tt := range tests {
+		users, err := a.ListUsers(asCaller(admin), tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("%s: ListUsers: %v", tt.name, err)
+		}
+		if len(users) != tt.want {
+			t.Errorf("%s: got %d users, want %d", tt.name, len(users), tt.want)
+		}
+		for _, user := range users {
+			if user.PassHash != nil {
+				t.Fatalf("%s: user %d returned with password hash", tt.name, user.ID)
+			}
+		}
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	token, err := a.Login(ctx, "user@example.com", testPassword, appID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if err := a.DeleteUser(asCaller(uid), uid); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("DeleteUser by non-admin: got %v, want ErrPermissionDenied", err)
+	}
+
+	if err := a.DeleteUser(asCaller(admin), uid); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := store.UserByID(ctx, uid); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("UserByID of deleted user: got %v, want ErrUserNotFound", err)
+	}
+	if err := a.DeleteUser(asCaller(admin), uid); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("DeleteUser of deleted user: got %v, want ErrUserNotFound", err)
+	}
+
+	// SQLite hands the freed id to the next user; the old token must not
+	// authenticate as them.
+	if _, err := a.RegisterNewUser(ctx, "next@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if _, err := a.ValidateToken(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("ValidateToken of deleted user's token: got %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestBatchIsAdmin(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	absent := admin + 1
+
+	admins, missing, err := a.BatchIsAdmin(ctx, []int64{user, absent, admin})
+	if err != nil {
+		t.Fatalf("BatchIsAdmin: %v", err)
+	}
+	if len(admins) != 2 || admins[user] || !admins[admin] {
+		t.Errorf("admins = %v, want %d: false, %d: true", admins, user, admin)
+	}
+	if len(missing) != 1 || missing[0] != absent {
+		t.Errorf("missing = %v, want [%d]", missing, absent)
+	}
+
+	admins, missing, err = a.BatchIsAdmin(ctx, nil)
+	if err != nil || len(admins) != 0 || len(missing) != 0 {
+		t.Errorf("BatchIsAdmin(nil) = %v, %v, %v; want empty", admins, missing, err)
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id   int64
+		want bool
+	}{{user, false}, {admin, true}} {
+		got, err := a.HasPermission(ctx, tc.id, "users.delete")
+		if err != nil {
+			t.Fatalf("HasPermission(%d): %v", tc.id, err)
+		}
+		if got != tc.want {
+			t.Errorf("HasPermission(%d) = %t, want %t", tc.id, got, tc.want)
+		}
+	}
+
+	if _, err := a.HasPermission(ctx, admin+1, "users.delete"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("HasPermission of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+// countingRoles counts role lookups reaching store.
+type countingRoles struct {
+	*sqlite.Storage
+	calls int
+}
+
+func (c *countingRoles) HasRole(ctx context.Context, userID int64, role string) (bool, error) {
+	c.calls++
+
+	return c.Storage.HasRole(ctx, userID, role)
+}
+
+func TestIsAdminCache(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	roles := &countingRoles{Storage: store}
+	a := newTestAuthWith(t, store, testDeps{roles: roles}, auth.Config{AdminCacheTTL: time.Minute, AdminCacheSize: 10})
+
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	isAdmin := func(want bool, wantCalls int) {
+		t.Helper()
+
+		got, err := a.IsAdmin(ctx, uid)
+		if err != nil {
+			t.Fatalf("IsAdmin: %v", err)
+		}
+		if got != want || roles.calls != wantCalls {
+			t.Errorf("IsAdmin = %t after %d storage reads, want %t after %d", got, roles.calls, want, wantCalls)
+		}
+	}
+
+	isAdmin(false, 1)
+	isAdmin(false, 1)
+
+	if err := store.AssignRole(ctx, uid, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	// Stale until roles are invalidated.
+	isAdmin(false, 1)
+
+	a.InvalidateUserRoles(uid)
+	isAdmin(true, 2)
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, ratelimit.NewSlidingWindow(2, time.Minute), auth.Config{})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := a.Login(ctx, "user@example.com", "Wrong1234", appID); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Login %d with wrong password: got %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); !errors.Is(err, auth.ErrTooManyAttempts) {
+		t.Fatalf("Login after limit: got %v, want ErrTooManyAttempts", err)
+	}
+
+	// Failures are counted per user, other users aren't affected.
+	if _, err := a.RegisterNewUser(ctx, "other@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if _, err := a.Login(ctx, "other@example.com", testPassword, appID); err != nil {
+		t.Errorf("Login of other user: %v", err)
+	}
+}
+
+func TestLoginLockoutSharedByLogins(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, ratelimit.NewSlidingWindow(2, time.Minute), auth.Config{})
+
+	if _, err := a.RegisterNewUserWithUsername(ctx, "user@example.com", "user", testPassword); err != nil {
+		t.Fatalf("RegisterNewUserWithUsername: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	// Switching between email and username doesn't double the attempts.
+	for _, login := range []string{"user@example.com", "user"} {
+		if _, err := a.Login(ctx, login, "Wrong1234", appID); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Login as %q with wrong password: got %v, want ErrInvalidCredentials", login, err)
+		}
+	}
+	for _, login := range []string{"user@example.com", "user"} {
+		if _, err := a.Login(ctx, login, testPassword, appID); !errors.Is(err, auth.ErrTooManyAttempts) {
+			t.Errorf("Login as %q after limit: got %v, want ErrTooManyAttempts", login, err)
+		}
+	}
+
+	// Unknown logins are counted case-insensitively.
+	for _, login := range []string{"Nobody@example.com", "nobody@EXAMPLE.com"} {
+		if _, err := a.Login(ctx, login, "Wrong1234", appID); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Login as %q: got %v, want ErrInvalidCredentials", login, err)
+		}
+	}
+	if _, err := a.Login(ctx, "nobody@example.com", "Wrong1234", appID); !errors.Is(err, auth.ErrTooManyAttempts) {
+		t.Errorf("Login of unknown user after limit: got %v, want ErrTooManyAttempts", err)
+	}
+}
+
+// hookedHasher runs onCompare before each comparison.
+type hookedHasher struct {
+	auth.Hasher
+	onCompare func()
+}
+
+func (h hookedHasher) Compare(hash, password string) error {
+	h.onCompare()
+
+	return h.Hasher.Compare(hash, password)
+}
+
+func TestLoginCancelledMidCompare(t *testing.T) {
+	store := newTestStorage(t)
+	limiter := ratelimit.NewSlidingWindow(1, time.Minute)
+
+	if _, err := newTestAuth(t, store, limiter, auth.Config{}).RegisterNewUser(context.Background(), "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(context.Background(), "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	a := newTestAuthWith(t, store, testDeps{
+		limiter: limiter,
+		hasher: hookedHasher{
+			Hasher: hasher.New(hasher.NewBcrypt(bcrypt.MinCost)),
+			onCompare: func() {
+				cancel()
+				<-release
+			},
+		},
+	}, auth.Config{})
+
+	if _, err := a.Login(ctx, "user@example.com", "Wrong1234", appID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Login cancelled mid-compare: got %v, want context.Canceled", err)
+	}
+
+	// Cancelled attempt isn't a failure: the only allowed one is still left.
+	if _, err := newTestAuth(t, store, limiter, auth.Config{}).Login(context.Background(), "user@example.com", testPassword, appID); err != nil {
+		t.Errorf("Login after cancelled attempt: %v", err)
+	}
+}
+
+// blockingHashHasher runs onHash before each hashing.
+type blockingHashHasher struct {
+	auth.Hasher
+	onHash func()
+}
+
+func (h blockingHashHasher) Hash(password string) (string, error) {
+	h.onHash()
+
+	return h.Hasher.Hash(password)
+}
+
+func TestRegisterCancelledMidHash(t *testing.T) {
+	store := newTestStorage(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	a := newTestAuthWith(t, store, testDeps{
+		hasher: blockingHashHasher{
+			Hasher: hasher.New(hasher.NewBcrypt(bcrypt.MinCost)),
+			onHash: func() {
+				cancel()
+				<-release
+			},
+		},
+	}, auth.Config{})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RegisterNewUser cancelled mid-hash: got %v, want context.Canceled", err)
+	}
+	if _, err := store.User(context.Background(), "user@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("User after cancelled registration: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestLoginLockoutRetryAfter(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+
+	if _, err := newTestAuth(t, store, nil, auth.Config{}).RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	for _, disclose := range []bool{true, false} {
+		limiter := ratelimit.NewSlidingWindow(1, time.Minute)
+		a := newTestAuth(t, store, limiter, auth.Config{LockoutRetryAfter: disclose})
+
+		if _, err := a.Login(ctx, "user@example.com", "Wrong1234", appID); !errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Fatalf("Login with wrong password: got %v, want ErrInvalidCredentials", err)
+		}
+
+		_, err := a.Login(ctx, "user@example.com", testPassword, appID)
+
+		var lockout *auth.LockoutError
+		if !errors.As(err, &lockout) || !errors.Is(err, auth.ErrTooManyAttempts) {
+			t.Fatalf("Login after limit: got %v, want LockoutError matching ErrTooManyAttempts", err)
+		}
+
+		if disclose && (lockout.RetryAfter <= 0 || lockout.RetryAfter > time.Minute) {
+			t.Errorf("RetryAfter = %s, want within the 1m window", lockout.RetryAfter)
+		}
+		if !disclose && lockout.RetryAfter != 0 {
+			t.Errorf("RetryAfter with lockout_retry_after off = %s, want 0", lockout.RetryAfter)
+		}
+	}
+}
+
+func TestLoginDefaultApp(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	if _, err := newTestAuth(t, store, nil, auth.Config{}).RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	a := newTestAuth(t, store, nil, auth.Config{DefaultAppID: appID})
+	token, err := a.Login(ctx, "user@example.com", testPassword, 0)
+	if err != nil {
+		t.Fatalf("Login without app id: %v", err)
+	}
+	if claims := parseTestToken(t, store, appID, token); claims.AppID != appID {
+		t.Errorf("token app id = %d, want default %d", claims.AppID, appID)
+	}
+
+	noDefault := newTestAuth(t, store, nil, auth.Config{})
+	if _, err := noDefault.Login(ctx, "user@example.com", testPassword, 0); !errors.Is(err, auth.ErrInvalidAppID) {
+		t.Errorf("Login without app id and default: got %v, want ErrInvalidAppID", err)
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"sso/internal/lib/authctx"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+// ChangePassword replaces password of logged in user. Caller must be that
+// user (claims in ctx, see authctx), otherwise ErrPermissionDenied is
+// returned. If old password is wrong, returns ErrInvalidCredentials.
+// If new password doesn't satisfy password policy, returns ErrWeakPassword.
+func (a *Auth) ChangePassword(ctx context.Context, userID int64, oldPassword string, newPassword string) error {
+	const op = "Auth.ChangePassword"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	if claims, ok := authctx.ClaimsFromContext(ctx); !ok || claims.UID != userID {
+		log.Warn("caller is not the user")
+
+		return fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	log.Info("changing password")
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.comparePassword(ctx, user.PassHash, oldPassword)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s: %w", op, ctxErr)
+	}
+	if err != nil {
+		log.Info("invalid old password", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if err := a.validateNewPassword(newPassword); err != nil {
+		log.Info("password rejected", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.hashPassword(ctx, newPassword)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.usrUpdater.UpdatePasswordHash(ctx, userID, passHash); err != nil {
+		log.Error("failed to update password hash", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+
+	return nil
+}
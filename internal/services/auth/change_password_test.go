@@ -0,0 +1,55 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/services/auth"
+)
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{PasswordPolicy: auth.PasswordPolicy{MinLength: 8}})
+
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	const newPassword = "N3w-passw0rd!"
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		old, new string
+		want     error
+	}{
+		{"unauthenticated", ctx, testPassword, newPassword, auth.ErrPermissionDenied},
+		{"other user", asCaller(uid + 1), testPassword, newPassword, auth.ErrPermissionDenied},
+		{"wrong old password", asCaller(uid), "wrong-password", newPassword, auth.ErrInvalidCredentials},
+		{"weak new password", asCaller(uid), testPassword, "short", auth.ErrWeakPassword},
+	}
+	for _, tt := range tests {
+		if err := a.ChangePassword(tt.ctx, uid, tt.old, tt.new); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login with old password after rejected changes: %v", err)
+	}
+
+	if err := a.ChangePassword(asCaller(uid), uid, testPassword, newPassword); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("Login with old password: got %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := a.Login(ctx, "user@example.com", newPassword, appID); err != nil {
+		t.Errorf("Login with new password: %v", err)
+	}
+}
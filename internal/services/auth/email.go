@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"errors"
+	"net/mail"
+)
+
+var ErrInvalidEmail = errors.New("invalid email")
+
+// validateEmail rejects empty and syntactically invalid addresses.
+// Display names ("Bob <bob@example.com>") are not accepted either.
+func validateEmail(email string) error {
+	if email == "" {
+		return ErrInvalidEmail
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	return nil
+}
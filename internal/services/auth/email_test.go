@@ -0,0 +1,32 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/services/auth"
+)
+
+func TestInvalidEmail(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	for _, email := range []string{
+		"",
+		"user@",
+		"user@@example.com",
+		"Bob <bob@example.com>",
+		" user@example.com",
+	} {
+		if _, err := a.RegisterNewUser(ctx, email, testPassword); !errors.Is(err, auth.ErrInvalidEmail) {
+			t.Errorf("RegisterNewUser(%q): got %v, want ErrInvalidEmail", email, err)
+		}
+	}
+
+	// Logins with "@" are emails and validated the same way.
+	if _, err := a.Login(ctx, "user@@example.com", testPassword, 1); !errors.Is(err, auth.ErrInvalidEmail) {
+		t.Errorf("Login with invalid email: got %v, want ErrInvalidEmail", err)
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"sso/internal/domain/models"
+)
+
+// eventBufferSize is how many events a subscriber may lag behind before it's
+// dropped.
+const eventBufferSize = 64
+
+// eventBroker fans out audit events to live subscribers. Publishing never
+// blocks: subscriber whose buffer is full is dropped and its channel closed.
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[chan models.AuditEvent]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subs: make(map[chan models.AuditEvent]struct{})}
+}
+
+// Record implements AuditLogger.
+func (b *eventBroker) Record(_ context.Context, event models.AuditEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *eventBroker) subscribe() chan models.AuditEvent {
+	ch := make(chan models.AuditEvent, eventBufferSize)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch
+}
+
+func (b *eventBroker) unsubscribe(ch chan models.AuditEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// teeAudit records event in every logger.
+type teeAudit []AuditLogger
+
+func (t teeAudit) Record(ctx context.Context, event models.AuditEvent) {
+	for _, l := range t {
+		l.Record(ctx, event)
+	}
+}
+
+// WatchAuthEvents streams login, register and admin check events as they
+// happen until ctx is done. The channel is closed when ctx is done or when
+// the caller falls too far behind. Caller must be an admin (claims in ctx,
+// see authctx), otherwise ErrPermissionDenied is returned.
+func (a *Auth) WatchAuthEvents(ctx context.Context) (<-chan models.AuditEvent, error) {
+	const op = "Auth.WatchAuthEvents"
+
+	if err := a.requireAdmin(ctx, op); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ch := a.events.subscribe()
+
+	go func() {
+		<-ctx.Done()
+		a.events.unsubscribe(ch)
+	}()
+
+	return ch, nil
+}
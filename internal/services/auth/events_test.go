@@ -0,0 +1,103 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/services/auth"
+)
+
+func TestWatchAuthEvents(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	if _, err := a.WatchAuthEvents(asCaller(user)); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Fatalf("WatchAuthEvents by non-admin: got %v, want ErrPermissionDenied", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(asCaller(admin))
+	events, err := a.WatchAuthEvents(watchCtx)
+	if err != nil {
+		t.Fatalf("WatchAuthEvents: %v", err)
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != models.AuditLogin || ev.UserID != user || ev.Result != "success" {
+			t.Errorf("event = %+v, want successful login of user %d", ev, user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event after login")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("unexpected event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
+
+func TestWatchAuthEventsDropsSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(asCaller(admin))
+	defer cancel()
+	events, err := a.WatchAuthEvents(watchCtx)
+	if err != nil {
+		t.Fatalf("WatchAuthEvents: %v", err)
+	}
+
+	// Nobody reads events, so the buffer overflows; IsAdmin must not block.
+	const calls = 200
+	for range calls {
+		if _, err := a.IsAdmin(ctx, admin); err != nil {
+			t.Fatalf("IsAdmin: %v", err)
+		}
+	}
+
+	n := 0
+	for range events {
+		n++
+	}
+	if n >= calls {
+		t.Errorf("received all %d events, want slow subscriber dropped", n)
+	}
+}
@@ -0,0 +1,81 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"sso/internal/lib/hasher"
+	"sso/internal/services/auth"
+)
+
+// fakeHasher stores passwords as is and records calls for testPassword.
+type fakeHasher struct {
+	mu       sync.Mutex
+	hashed   int
+	compared int
+}
+
+func (h *fakeHasher) Hash(password string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if password == testPassword {
+		h.hashed++
+	}
+
+	return "$fake$" + password, nil
+}
+
+func (h *fakeHasher) Compare(hash, password string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if password == testPassword {
+		h.compared++
+	}
+	if hash != "$fake$"+password {
+		return hasher.ErrMismatch
+	}
+
+	return nil
+}
+
+func (h *fakeHasher) NeedsRehash(string) bool { return false }
+
+// Owns makes fakeHasher a hasher.Algorithm.
+func (h *fakeHasher) Owns(hash string) bool { return strings.HasPrefix(hash, "$fake$") }
+
+func TestAuthUsesHasher(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	h := &fakeHasher{}
+	a := newTestAuthWith(t, store, testDeps{hasher: h}, auth.Config{})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if h.hashed != 1 || h.compared != 0 {
+		t.Errorf("after register: Hash called %d, Compare %d times; want 1 and 0", h.hashed, h.compared)
+	}
+
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if string(user.PassHash) != "$fake$"+testPassword {
+		t.Errorf("stored hash = %q, want hasher output", user.PassHash)
+	}
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if h.hashed != 1 || h.compared != 1 {
+		t.Errorf("after login: Hash called %d, Compare %d times; want 1 and 1", h.hashed, h.compared)
+	}
+}
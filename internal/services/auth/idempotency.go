@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+// ErrIdempotencyKeyReused is returned when idempotency key is repeated with
+// a different request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with different request")
+
+// IdempotencyStore keeps results of requests made with idempotency key.
+// SaveIdempotencyKey must return storage.ErrIdempotencyKeyExists if the key
+// is already saved and not expired.
+type IdempotencyStore interface {
+	SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error
+	IdempotencyKey(ctx context.Context, key string) (models.IdempotencyKey, error)
+}
+
+// RegisterNewUserIdempotent is RegisterNewUser for client retries: if the
+// same key was used for the same email within Config.IdempotencyKeyTTL,
+// the already created user ID is returned instead of an error. User and key
+// are saved in one transaction, so a user is never created without its key.
+//
+// Concurrent requests with the same key are settled by the key's uniqueness
+// in storage: the one that saves the key first wins, the others get its
+// result, or ErrIdempotencyKeyReused if it was made for another email.
+func (a *Auth) RegisterNewUserIdempotent(ctx context.Context, key string, email string, pass string) (int64, error) {
+	const op = "Auth.RegisterNewUserIdempotent"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	if id, ok, err := a.idempotentResult(ctx, log, key, email); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	} else if ok {
+		return id, nil
+	}
+
+	id, err := a.registerWith(ctx, op, email, "", pass, nil, func(ctx context.Context, id int64) error {
+		err := a.idempotencyKeys.SaveIdempotencyKey(ctx, models.IdempotencyKey{
+			Key:       key,
+			Email:     email,
+			UserID:    id,
+			ExpiresAt: time.Now().Add(a.cfg.IdempotencyKeyTTL),
+		})
+		if err != nil && !errors.Is(err, storage.ErrIdempotencyKeyExists) {
+			log.Error("failed to save idempotency key", sl.Err(err))
+		}
+
+		return err
+	})
+	if errors.Is(err, ErrUserAlreadyExists) || errors.Is(err, storage.ErrIdempotencyKeyExists) {
+		// Concurrent request with the same key may have saved its user and
+		// key first, ours were rolled back then.
+		if saved, ok, lookupErr := a.idempotentResult(ctx, log, key, email); lookupErr != nil {
+			return 0, fmt.Errorf("%s: %w", op, lookupErr)
+		} else if ok {
+			return saved, nil
+		}
+	}
+	if errors.Is(err, storage.ErrIdempotencyKeyExists) {
+		log.Warn("idempotency key taken by concurrent request")
+
+		return 0, fmt.Errorf("%s: %w", op, ErrIdempotencyKeyReused)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// idempotentResult returns user saved with not expired idempotency key and
+// true. If there's no such key, returns false; if it was saved for another
+// email, returns ErrIdempotencyKeyReused.
+func (a *Auth) idempotentResult(ctx context.Context, log *slog.Logger, key string, email string) (int64, bool, error) {
+	saved, err := a.idempotencyKeys.IdempotencyKey(ctx, key)
+	switch {
+	case err == nil && time.Now().Before(saved.ExpiresAt):
+		if saved.Email != email {
+			log.Warn("idempotency key reused for different email")
+
+			return 0, false, ErrIdempotencyKeyReused
+		}
+
+		log.Info("repeated request, returning saved result", slog.Int64("user_id", saved.UserID))
+
+		return saved.UserID, true, nil
+	case err != nil && !errors.Is(err, storage.ErrIdempotencyKeyNotFound):
+		log.Error("failed to get idempotency key", sl.Err(err))
+
+		return 0, false, err
+	}
+
+	return 0, false, nil
+}
@@ -0,0 +1,185 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"sso/internal/storage/sqlite"
+)
+
+var errKeyStoreDown = errors.New("idempotency store is down")
+
+// failingKeys fails to save idempotency keys.
+type failingKeys struct {
+	*sqlite.Storage
+}
+
+func (failingKeys) SaveIdempotencyKey(context.Context, models.IdempotencyKey) error {
+	return errKeyStoreDown
+}
+
+// missingKeys misses saved keys on the first lookup, as if another request
+// saved the key right after it.
+type missingKeys struct {
+	*sqlite.Storage
+	missed bool
+}
+
+func (k *missingKeys) IdempotencyKey(ctx context.Context, key string) (models.IdempotencyKey, error) {
+	if !k.missed {
+		k.missed = true
+
+		return models.IdempotencyKey{}, storage.ErrIdempotencyKeyNotFound
+	}
+
+	return k.Storage.IdempotencyKey(ctx, key)
+}
+
+func TestRegisterIdempotentRepeatReturnsSameUser(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, newTestStorage(t), nil, auth.Config{IdempotencyKeyTTL: time.Hour})
+
+	first, err := a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("first RegisterNewUserIdempotent: %v", err)
+	}
+
+	second, err := a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("repeated RegisterNewUserIdempotent: %v", err)
+	}
+	if second != first {
+		t.Errorf("repeated request returned user %d, want %d", second, first)
+	}
+
+	_, err = a.RegisterNewUserIdempotent(ctx, "key-1", "other@example.com", testPassword)
+	if !errors.Is(err, auth.ErrIdempotencyKeyReused) {
+		t.Errorf("key reused for other email: got %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestRegisterIdempotentDifferentKeys(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, newTestStorage(t), nil, auth.Config{IdempotencyKeyTTL: time.Hour})
+
+	if _, err := a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUserIdempotent: %v", err)
+	}
+
+	_, err := a.RegisterNewUserIdempotent(ctx, "key-2", "user@example.com", testPassword)
+	if !errors.Is(err, auth.ErrUserAlreadyExists) {
+		t.Errorf("same email with other key: got %v, want ErrUserAlreadyExists", err)
+	}
+
+	if _, err := a.RegisterNewUserIdempotent(ctx, "key-3", "other@example.com", testPassword); err != nil {
+		t.Errorf("other email with other key: %v", err)
+	}
+}
+
+func TestRegisterIdempotentExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, newTestStorage(t), nil, auth.Config{IdempotencyKeyTTL: -time.Second})
+
+	if _, err := a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUserIdempotent: %v", err)
+	}
+
+	// Expired key is no longer a replay, the request runs again.
+	_, err := a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword)
+	if !errors.Is(err, auth.ErrUserAlreadyExists) {
+		t.Errorf("repeat after key expiry: got %v, want ErrUserAlreadyExists", err)
+	}
+}
+
+func TestRegisterIdempotentRollsBackUserWhenKeyNotSaved(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	rec := &recordingAudit{}
+	a := newTestAuthWith(t, store, testDeps{keys: failingKeys{store}, audit: rec}, auth.Config{IdempotencyKeyTTL: time.Hour})
+
+	_, err := a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword)
+	if !errors.Is(err, errKeyStoreDown) {
+		t.Fatalf("RegisterNewUserIdempotent: got %v, want errKeyStoreDown", err)
+	}
+
+	if _, err := store.User(ctx, "user@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Fatalf("user after failed key save: got %v, want ErrUserNotFound", err)
+	}
+	// Audit is recorded after rollback, so it doesn't name the rolled back user.
+	if events := rec.take(); len(events) != 1 || events[0].UserID != 0 || events[0].Result == "success" {
+		t.Errorf("audit after failed key save = %+v, want one failure without user", events)
+	}
+
+	// The retry must be able to create the user.
+	retry := newTestAuth(t, store, nil, auth.Config{IdempotencyKeyTTL: time.Hour})
+	if _, err := retry.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword); err != nil {
+		t.Fatalf("retry after rollback: %v", err)
+	}
+}
+
+func TestRegisterIdempotentConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{IdempotencyKeyTTL: time.Hour})
+
+	const requests = 8
+
+	ids := make([]int64, requests)
+	errs := make([]error, requests)
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ids[i], errs[i] = a.RegisterNewUserIdempotent(ctx, "key-1", "user@example.com", testPassword)
+		}()
+	}
+	wg.Wait()
+
+	for i := range requests {
+		if errs[i] != nil {
+			t.Errorf("request %d: %v", i, errs[i])
+		} else if ids[i] != ids[0] {
+			t.Errorf("request %d returned user %d, want %d", i, ids[i], ids[0])
+		}
+	}
+}
+
+func TestRegisterIdempotentKeyTakenForOtherEmail(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{IdempotencyKeyTTL: time.Hour})
+
+	// Key saved by a request that raced ours: our key lookup missed it, so
+	// the user is saved and then rolled back when the key turns out taken.
+	err := store.SaveIdempotencyKey(ctx, models.IdempotencyKey{
+		Key:       "key-1",
+		Email:     "first@example.com",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("SaveIdempotencyKey: %v", err)
+	}
+
+	missing := newTestAuthWith(t, store, testDeps{keys: &missingKeys{Storage: store}}, auth.Config{IdempotencyKeyTTL: time.Hour})
+	_, err = missing.RegisterNewUserIdempotent(ctx, "key-1", "second@example.com", testPassword)
+	if !errors.Is(err, auth.ErrIdempotencyKeyReused) {
+		t.Fatalf("RegisterNewUserIdempotent: got %v, want ErrIdempotencyKeyReused", err)
+	}
+	if _, err := store.User(ctx, "second@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("user after taken key: got %v, want ErrUserNotFound", err)
+	}
+
+	if _, err := a.RegisterNewUserIdempotent(ctx, "key-2", "second@example.com", testPassword); err != nil {
+		t.Errorf("retry with own key: %v", err)
+	}
+}
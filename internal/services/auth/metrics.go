@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+
+	"sso/internal/metrics"
+	"sso/internal/storage"
+)
+
+func loginResult(err error) string {
+	switch {
+	case err == nil:
+		return metrics.ResultSuccess
+	case errors.Is(err, ErrInvalidCredentials):
+		return metrics.ResultInvalidCredentials
+	case errors.Is(err, ErrInvalidEmail), errors.Is(err, ErrInvalidUsername),
+		errors.Is(err, ErrPasswordRequired), errors.Is(err, ErrInvalidAppID):
+		return metrics.ResultInvalidArgument
+	case errors.Is(err, ErrTooManyAttempts):
+		return metrics.ResultTooManyAttempts
+	default:
+		return metrics.ResultError
+	}
+}
+
+// isAdminResult is audit result of admin check.
+func isAdminResult(isAdmin bool, err error) string {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		return "not_found"
+	case err != nil:
+		return metrics.ResultError
+	case isAdmin:
+		return "admin"
+	default:
+		return "not_admin"
+	}
+}
+
+func registerResult(err error) string {
+	switch {
+	case err == nil:
+		return metrics.ResultSuccess
+	case errors.Is(err, ErrInvalidEmail), errors.Is(err, ErrInvalidUsername),
+		errors.Is(err, ErrWeakPassword), errors.Is(err, ErrPasswordTooLong):
+		return metrics.ResultInvalidArgument
+	case errors.Is(err, ErrUserAlreadyExists):
+		return metrics.ResultAlreadyExists
+	default:
+		return metrics.ResultError
+	}
+}
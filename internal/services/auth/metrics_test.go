@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"sso/internal/metrics"
+	"sso/internal/services/auth"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counterValue returns value of counter name with given result label
+// from the default registry.
+func counterValue(t *testing.T, name, result string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "result" && l.GetValue() == result {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestAuthMetrics(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	// Counters are global, so only their increase is checked.
+	registered := counterValue(t, "sso_register_total", metrics.ResultSuccess)
+	duplicates := counterValue(t, "sso_register_total", metrics.ResultAlreadyExists)
+	logins := counterValue(t, "sso_login_total", metrics.ResultSuccess)
+	failures := counterValue(t, "sso_login_total", metrics.ResultInvalidCredentials)
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	_, _ = a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, _ = a.Login(ctx, "user@example.com", "Wrong1234", appID)
+
+	for _, tc := range []struct {
+		name   string
+		before float64
+		after  float64
+	}{
+		{"register_total{success}", registered, counterValue(t, "sso_register_total", metrics.ResultSuccess)},
+		{"register_total{already_exists}", duplicates, counterValue(t, "sso_register_total", metrics.ResultAlreadyExists)},
+		{"login_total{success}", logins, counterValue(t, "sso_login_total", metrics.ResultSuccess)},
+		{"login_total{invalid_credentials}", failures, counterValue(t, "sso_login_total", metrics.ResultInvalidCredentials)},
+	} {
+		if tc.after-tc.before != 1 {
+			t.Errorf("%s increased by %v, want 1", tc.name, tc.after-tc.before)
+		}
+	}
+}
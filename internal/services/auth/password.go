@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrWeakPassword = errors.New("weak password")
+
+// WeakPasswordError reports which password policy rule failed.
+// It matches ErrWeakPassword with errors.Is.
+type WeakPasswordError struct {
+	Rule string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, e.Rule)
+}
+
+func (e *WeakPasswordError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// PasswordPolicy describes requirements for new passwords.
+// Zero MaxLength means no upper limit.
+type PasswordPolicy struct {
+	MinLength    int
+	MaxLength    int
+	RequireDigit bool
+	RequireUpper bool
+	RequireLower bool
+}
+
+// Validate checks password against policy and returns *WeakPasswordError
+// for the first failed rule.
+func (p PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		return &WeakPasswordError{Rule: fmt.Sprintf("must be at least %d characters long", p.MinLength)}
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return &WeakPasswordError{Rule: fmt.Sprintf("must be at most %d characters long", p.MaxLength)}
+	}
+
+	var hasDigit, hasUpper, hasLower bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		}
+	}
+
+	if p.RequireDigit && !hasDigit {
+		return &WeakPasswordError{Rule: "must contain a digit"}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		return &WeakPasswordError{Rule: "must contain an upper case letter"}
+	}
+
+	if p.RequireLower && !hasLower {
+		return &WeakPasswordError{Rule: "must contain a lower case letter"}
+	}
+
+	return nil
+}
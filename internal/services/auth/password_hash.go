@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+)
+
+// MaxPasswordBytes is the longest input bcrypt takes into account.
+const MaxPasswordBytes = 72
+
+// dummyPassword is what Auth.dummyPassHash is hash of.
+const dummyPassword = "dummy password"
+
+// ErrPasswordTooLong is returned for passwords over MaxPasswordBytes when
+// pre-hashing is off.
+var ErrPasswordTooLong = fmt.Errorf("password must be at most %d bytes long", MaxPasswordBytes)
+
+// PasswordInput returns what is fed to bcrypt for password. With prehash the
+// password is replaced by base64 of its SHA-256, so every byte of a long
+// password counts; without it passwords over MaxPasswordBytes are rejected
+// with ErrPasswordTooLong instead of being silently truncated by bcrypt.
+func PasswordInput(password string, prehash bool) ([]byte, error) {
+	if prehash {
+		sum := sha256.Sum256([]byte(password))
+
+		return []byte(base64.StdEncoding.EncodeToString(sum[:])), nil
+	}
+
+	if len(password) > MaxPasswordBytes {
+		return nil, ErrPasswordTooLong
+	}
+
+	return []byte(password), nil
+}
+
+// validateNewPassword checks password policy and bcrypt length limit.
+func (a *Auth) validateNewPassword(password string) error {
+	if err := a.cfg.PasswordPolicy.Validate(password); err != nil {
+		return err
+	}
+
+	_, err := PasswordInput(password, a.cfg.PrehashPasswords)
+
+	return err
+}
+
+// hashPassword hashes password checked by validateNewPassword on the bcrypt
+// pool. It returns ctx.Err() if ctx is done first.
+func (a *Auth) hashPassword(ctx context.Context, password string) ([]byte, error) {
+	input, err := PasswordInput(password, a.cfg.PrehashPasswords)
+	if err != nil {
+		return nil, err
+	}
+
+	var hash string
+	err = a.bcryptPool.Do(ctx, func() error {
+		var err error
+		hash, err = a.hasher.Hash(string(input))
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(hash), nil
+}
+
+// comparePassword checks password against stored hash on the bcrypt pool.
+// Passwords over MaxPasswordBytes without prehash never match: bcrypt would
+// compare only their first bytes. They still cost one comparison, so timing
+// doesn't tell them apart, and ErrPasswordTooLong is returned.
+func (a *Auth) comparePassword(ctx context.Context, hash []byte, password string) error {
+	input, err := PasswordInput(password, a.cfg.PrehashPasswords)
+	if err != nil {
+		_ = a.bcryptPool.Do(ctx, func() error {
+			return a.hasher.Compare(string(a.dummyPassHash()), dummyPassword)
+		})
+
+		return err
+	}
+
+	return a.bcryptPool.Do(ctx, func() error {
+		return a.hasher.Compare(string(hash), string(input))
+	})
+}
+
+// rehashPassword replaces user's stored hash with a fresh one of password
+// when RehashOnLogin is set and hasher considers the stored one weak.
+// Password is already verified, so failures are only logged.
+func (a *Auth) rehashPassword(ctx context.Context, log *slog.Logger, user models.User, password string) {
+	if !a.cfg.RehashOnLogin || !a.hasher.NeedsRehash(string(user.PassHash)) {
+		return
+	}
+
+	passHash, err := a.hashPassword(ctx, password)
+	if err != nil {
+		log.Warn("failed to rehash password", sl.Err(err))
+
+		return
+	}
+
+	if err := a.usrUpdater.UpdatePasswordHash(ctx, user.ID, passHash); err != nil {
+		log.Warn("failed to save rehashed password", sl.Err(err))
+
+		return
+	}
+
+	log.Info("password rehashed")
+}
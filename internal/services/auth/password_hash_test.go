@@ -0,0 +1,70 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"sso/internal/services/auth"
+)
+
+func TestLoginRejectsPasswordOverBcryptLimit(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	password := "Aa1" + strings.Repeat("x", auth.MaxPasswordBytes-3)
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", password); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", password, appID); err != nil {
+		t.Fatalf("Login with exact password: %v", err)
+	}
+
+	// bcrypt would ignore everything past MaxPasswordBytes and accept this.
+	_, err = a.Login(ctx, "user@example.com", password+"anything", appID)
+	if !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("Login with longer password: got %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLoginPrehashAcceptsLongPassword(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{PrehashPasswords: true})
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	password := "Aa1" + strings.Repeat("x", 2*auth.MaxPasswordBytes)
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", password); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", password, appID); err != nil {
+		t.Fatalf("Login with exact password: %v", err)
+	}
+
+	_, err = a.Login(ctx, "user@example.com", password+"x", appID)
+	if !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("Login with different long password: got %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestRegisterRejectsPasswordOverBcryptLimit(t *testing.T) {
+	a := newTestAuth(t, newTestStorage(t), nil, auth.Config{})
+
+	_, err := a.RegisterNewUser(context.Background(), "user@example.com", strings.Repeat("x", auth.MaxPasswordBytes+1))
+	if !errors.Is(err, auth.ErrPasswordTooLong) {
+		t.Fatalf("RegisterNewUser: got %v, want ErrPasswordTooLong", err)
+	}
+}
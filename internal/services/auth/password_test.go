@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/services/auth"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := auth.PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    16,
+		RequireDigit: true,
+		RequireUpper: true,
+		RequireLower: true,
+	}
+
+	tests := []struct {
+		password string
+		wantRule string
+	}{
+		{"Secret123", ""},
+		{"Sec1", "must be at least 8 characters long"},
+		{"Secret1234567890x", "must be at most 16 characters long"},
+		{"SecretPass", "must contain a digit"},
+		{"secret123", "must contain an upper case letter"},
+		{"SECRET123", "must contain a lower case letter"},
+	}
+
+	for _, tt := range tests {
+		err := policy.Validate(tt.password)
+		if tt.wantRule == "" {
+			if err != nil {
+				t.Errorf("Validate(%q): %v", tt.password, err)
+			}
+
+			continue
+		}
+
+		var weakErr *auth.WeakPasswordError
+		if !errors.As(err, &weakErr) || !errors.Is(err, auth.ErrWeakPassword) {
+			t.Errorf("Validate(%q): got %v, want WeakPasswordError", tt.password, err)
+
+			continue
+		}
+		if weakErr.Rule != tt.wantRule {
+			t.Errorf("Validate(%q) rule = %q, want %q", tt.password, weakErr.Rule, tt.wantRule)
+		}
+	}
+}
+
+func TestRegisterRejectsWeakPassword(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{
+		PasswordPolicy: auth.PasswordPolicy{MinLength: 8, RequireDigit: true},
+	})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", "password"); !errors.Is(err, auth.ErrWeakPassword) {
+		t.Fatalf("RegisterNewUser with weak password: got %v, want ErrWeakPassword", err)
+	}
+	if _, err := store.User(ctx, "user@example.com"); err == nil {
+		t.Error("user with weak password was saved")
+	}
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", "password1"); err != nil {
+		t.Errorf("RegisterNewUser with valid password: %v", err)
+	}
+}
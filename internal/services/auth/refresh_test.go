@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"sso/internal/storage/sqlite"
+)
+
+func TestRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{RefreshTokenTTL: time.Hour})
+
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	otherApp, err := store.SaveApp(ctx, "cli", "cli-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	refresh, err := a.IssueRefreshToken(ctx, uid, appID)
+	if err != nil {
+		t.Fatalf("IssueRefreshToken: %v", err)
+	}
+
+	access, err := a.RefreshToken(ctx, refresh, appID)
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if claims := parseTestToken(t, store, appID, access); claims.UID != uid || claims.AppID != appID {
+		t.Errorf("refreshed token uid=%d app=%d, want %d, %d", claims.UID, claims.AppID, uid, appID)
+	}
+	sessions, err := store.Sessions(ctx, uid)
+	if err != nil {
+		t.Fatalf("Sessions: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Errorf("got %d sessions after refresh, want 1", len(sessions))
+	}
+
+	for name, tc := range map[string]struct {
+		token string
+		appID int
+	}{
+		"unknown token": {"garbage", appID},
+		"other app":     {refresh, otherApp},
+	} {
+		if _, err := a.RefreshToken(ctx, tc.token, tc.appID); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+			t.Errorf("RefreshToken with %s: got %v, want ErrInvalidRefreshToken", name, err)
+		}
+	}
+
+	if err := a.RevokeAllSessions(asCaller(uid), uid); err != nil {
+		t.Fatalf("RevokeAllSessions: %v", err)
+	}
+	if _, err := a.RefreshToken(ctx, refresh, appID); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("RefreshToken after RevokeAllSessions: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshTokenExpired(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{RefreshTokenTTL: time.Hour})
+
+	uid, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	err = store.SaveRefreshToken(ctx, models.RefreshToken{
+		Token:     "expired",
+		UserID:    uid,
+		AppID:     appID,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+
+	if _, err := a.RefreshToken(ctx, "expired", appID); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Errorf("RefreshToken with expired token: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+var errRefreshStoreDown = errors.New("refresh token store is down")
+
+// failingRefreshTokens fails to save refresh tokens.
+type failingRefreshTokens struct {
+	*sqlite.Storage
+}
+
+func (failingRefreshTokens) SaveRefreshToken(context.Context, models.RefreshToken) error {
+	return errRefreshStoreDown
+}
+
+func TestRegisterNewUserWithRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{RefreshTokenTTL: time.Hour})
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	uid, refresh, err := a.RegisterNewUserWithRefreshToken(ctx, "user@example.com", testPassword, appID)
+	if err != nil {
+		t.Fatalf("RegisterNewUserWithRefreshToken: %v", err)
+	}
+	access, err := a.RefreshToken(ctx, refresh, appID)
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if claims := parseTestToken(t, store, appID, access); claims.UID != uid {
+		t.Errorf("refreshed token uid=%d, want %d", claims.UID, uid)
+	}
+
+	if _, _, err := a.RegisterNewUserWithRefreshToken(ctx, "other@example.com", testPassword, appID+1); !errors.Is(err, auth.ErrInvalidAppID) {
+		t.Errorf("unknown app: got %v, want ErrInvalidAppID", err)
+	}
+	if _, err := store.User(ctx, "other@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("user for unknown app: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestRegisterNewUserWithRefreshTokenRollsBackUser(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuthWith(t, store, testDeps{refresh: failingRefreshTokens{store}}, auth.Config{RefreshTokenTTL: time.Hour})
+
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	_, _, err = a.RegisterNewUserWithRefreshToken(ctx, "user@example.com", testPassword, appID)
+	if !errors.Is(err, errRefreshStoreDown) {
+		t.Fatalf("RegisterNewUserWithRefreshToken: got %v, want errRefreshStoreDown", err)
+	}
+	if _, err := store.User(ctx, "user@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("user after failed token save: got %v, want ErrUserNotFound", err)
+	}
+}
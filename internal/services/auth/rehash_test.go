@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/lib/hasher"
+	"sso/internal/services/auth"
+	"sso/internal/storage/sqlite"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// failingUpdater fails to update password hashes.
+type failingUpdater struct{ *sqlite.Storage }
+
+func (failingUpdater) UpdatePasswordHash(context.Context, int64, []byte) error {
+	return errors.New("storage is down")
+}
+
+// storedCost returns bcrypt cost of password hash stored for email.
+func storedCost(t *testing.T, store *sqlite.Storage, email string) int {
+	t.Helper()
+
+	user, err := store.User(context.Background(), email)
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+
+	cost, err := bcrypt.Cost(user.PassHash)
+	if err != nil {
+		t.Fatalf("bcrypt.Cost: %v", err)
+	}
+
+	return cost
+}
+
+func TestLoginRehash(t *testing.T) {
+	ctx := context.Background()
+	stronger := hasher.New(hasher.NewBcrypt(bcrypt.MinCost + 1))
+
+	tests := []struct {
+		name     string
+		rehash   bool
+		updater  auth.UserUpdater
+		wantCost int
+	}{
+		{"rehash on login", true, nil, bcrypt.MinCost + 1},
+		{"rehash off", false, nil, bcrypt.MinCost},
+		{"failed rehash doesn't fail login", true, failingUpdater{}, bcrypt.MinCost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStorage(t)
+
+			if _, err := newTestAuth(t, store, nil, auth.Config{}).RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+				t.Fatalf("RegisterNewUser: %v", err)
+			}
+			appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+			if err != nil {
+				t.Fatalf("SaveApp: %v", err)
+			}
+
+			a := newTestAuthWith(t, store, testDeps{hasher: stronger, updater: tt.updater}, auth.Config{RehashOnLogin: tt.rehash})
+			if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+
+			if got := storedCost(t, store, "user@example.com"); got != tt.wantCost {
+				t.Errorf("stored hash cost = %d, want %d", got, tt.wantCost)
+			}
+			if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+				t.Errorf("Login after rehash: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginRehashLegacyAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	legacy := hasher.NewBcrypt(bcrypt.MinCost)
+
+	if _, err := newTestAuth(t, store, nil, auth.Config{}).RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	// Stored bcrypt hash is legacy for hasher with fake primary algorithm.
+	a := newTestAuthWith(t, store, testDeps{hasher: hasher.New(&fakeHasher{}, legacy)}, auth.Config{RehashOnLogin: true})
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if string(user.PassHash) != "$fake$"+testPassword {
+		t.Errorf("stored hash = %q, want rehashed with primary algorithm", user.PassHash)
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/random"
+	"sso/internal/storage"
+)
+
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// RequestPasswordReset issues time-limited password reset token for email.
+//
+// To avoid user enumeration, unknown email gets token too: it looks the
+// same but is never stored, so it can't be used.
+func (a *Auth) RequestPasswordReset(ctx context.Context, email string) (string, error) {
+	const op = "Auth.RequestPasswordReset"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	log.Info("requesting password reset")
+
+	token, err := random.Token(userTokenSize)
+	if err != nil {
+		log.Error("failed to generate reset token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("user not found, returning unusable token")
+
+			return token, nil
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.userTokens.SaveUserToken(ctx, models.UserToken{
+		Token:     token,
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposePasswordReset,
+		ExpiresAt: time.Now().Add(a.cfg.PasswordResetTokenTTL),
+	})
+	if err != nil {
+		log.Error("failed to save reset token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// ResetPassword sets new password for owner of reset token.
+// Token can be used only once.
+func (a *Auth) ResetPassword(ctx context.Context, resetToken string, newPassword string) error {
+	const op = "Auth.ResetPassword"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("resetting password")
+
+	ut, err := a.userTokens.UserToken(ctx, resetToken, models.TokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserTokenNotFound) {
+			log.Warn("reset token not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+
+		log.Error("failed to get reset token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !time.Now().Before(ut.ExpiresAt) {
+		log.Warn("reset token expired", slog.Time("expires_at", ut.ExpiresAt))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+	}
+
+	if err := a.validateNewPassword(newPassword); err != nil {
+		log.Info("password rejected", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	passHash, err := a.hashPassword(ctx, newPassword)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Token is deleted first in the same transaction: it can't be left
+	// usable after the password has changed, and of concurrent resets with
+	// it only the one that deleted it goes on.
+	err = a.transactor.WithTx(ctx, func(ctx context.Context) error {
+		if err := a.userTokens.DeleteUserToken(ctx, resetToken); err != nil {
+			return fmt.Errorf("delete reset token: %w", err)
+		}
+
+		if err := a.usrUpdater.UpdatePasswordHash(ctx, ut.UserID, passHash); err != nil {
+			return fmt.Errorf("update password hash: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserTokenNotFound) {
+			log.Warn("reset token already used", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+
+		log.Error("failed to reset password", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset", slog.Int64("user_id", ut.UserID))
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/services/auth"
+)
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{
+		PasswordResetTokenTTL: time.Hour,
+		PasswordPolicy:        auth.PasswordPolicy{MinLength: 8},
+	})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	const newPassword = "N3w-passw0rd!"
+
+	unknown, err := a.RequestPasswordReset(ctx, "nobody@example.com")
+	if err != nil || unknown == "" {
+		t.Fatalf("RequestPasswordReset of unknown email = %q, %v; want token", unknown, err)
+	}
+
+	token, err := a.RequestPasswordReset(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if len(token) != len(unknown) {
+		t.Errorf("token lengths differ: %d for user, %d for unknown email", len(token), len(unknown))
+	}
+
+	for name, tok := range map[string]string{"unknown email": unknown, "garbage": "garbage"} {
+		if err := a.ResetPassword(ctx, tok, newPassword); !errors.Is(err, auth.ErrInvalidResetToken) {
+			t.Errorf("ResetPassword with %s token: got %v, want ErrInvalidResetToken", name, err)
+		}
+	}
+	if err := a.ResetPassword(ctx, token, "short"); !errors.Is(err, auth.ErrWeakPassword) {
+		t.Errorf("ResetPassword with weak password: got %v, want ErrWeakPassword", err)
+	}
+
+	if err := a.ResetPassword(ctx, token, newPassword); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, err := a.Login(ctx, "user@example.com", newPassword, appID); err != nil {
+		t.Errorf("Login with new password: %v", err)
+	}
+	if err := a.ResetPassword(ctx, token, "An0ther-passw0rd"); !errors.Is(err, auth.ErrInvalidResetToken) {
+		t.Errorf("ResetPassword with used token: got %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestResetPasswordExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{PasswordResetTokenTTL: time.Nanosecond})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	token, err := a.RequestPasswordReset(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if err := a.ResetPassword(ctx, token, "N3w-passw0rd!"); !errors.Is(err, auth.ErrInvalidResetToken) {
+		t.Errorf("ResetPassword with expired token: got %v, want ErrInvalidResetToken", err)
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/authctx"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/requestinfo"
+	"sso/internal/storage"
+)
+
+// saveSession records token issued to user for app, with caller address
+// taken from ctx.
+func (a *Auth) saveSession(ctx context.Context, userID int64, appID int, token jwt.Token) error {
+	return a.sessions.SaveSession(ctx, models.Session{
+		JTI:        token.ID,
+		UserID:     userID,
+		AppID:      appID,
+		IssuedAt:   token.IssuedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastSeenIP: requestinfo.ClientIP(ctx),
+	})
+}
+
+// ListSessions returns user's unexpired, unrevoked sessions, newest first.
+// Caller must be an admin, otherwise ErrPermissionDenied is returned.
+func (a *Auth) ListSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	const op = "Auth.ListSessions"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	if err := a.requireAdmin(ctx, op); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("listing sessions")
+
+	sessions, err := a.sessions.Sessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to list sessions", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	active := sessions[:0]
+	for _, session := range sessions {
+		if session.ExpiresAt.After(now) {
+			active = append(active, session)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeAllSessions logs user out everywhere: token version of user is
+// incremented and every unexpired session is revoked, so all access tokens
+// issued so far are rejected, and so are refresh tokens, so no new ones can
+// be obtained without logging in again. Caller must be the user or an admin,
+// otherwise ErrPermissionDenied is returned.
+func (a *Auth) RevokeAllSessions(ctx context.Context, userID int64) error {
+	const op = "Auth.RevokeAllSessions"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+	)
+
+	if claims, ok := authctx.ClaimsFromContext(ctx); !ok || claims.UID != userID {
+		if err := a.requireAdmin(ctx, op); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("revoking all sessions")
+
+	revoked := 0
+
+	err := a.transactor.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if revoked, err = a.revokeSessions(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := a.refreshStorage.RevokeRefreshTokens(ctx, userID); err != nil {
+			return err
+		}
+
+		return a.usrUpdater.IncrementTokenVersion(ctx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to revoke sessions", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("all sessions revoked", slog.Int("revoked", revoked))
+
+	return nil
+}
+
+// revokeSessions revokes jti of every unexpired session of user and
+// returns how many were revoked.
+func (a *Auth) revokeSessions(ctx context.Context, userID int64) (int, error) {
+	sessions, err := a.sessions.Sessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	revoked := 0
+	for _, session := range sessions {
+		if !session.ExpiresAt.After(now) {
+			continue
+		}
+
+		if err := a.revocationStore.RevokeToken(ctx, session.JTI, session.ExpiresAt); err != nil {
+			return 0, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
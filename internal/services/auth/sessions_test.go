@@ -0,0 +1,128 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/authctx"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/requestinfo"
+	"sso/internal/services/auth"
+)
+
+// asCaller returns ctx authenticated as user id.
+func asCaller(id int64) context.Context {
+	return authctx.WithClaims(context.Background(), &jwt.Claims{UID: id})
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	other, err := a.RegisterNewUser(ctx, "other@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	denied := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"unauthenticated", ctx},
+		{"other user", asCaller(other)},
+	}
+	for _, tt := range denied {
+		if err := a.RevokeAllSessions(tt.ctx, user.ID); !errors.Is(err, auth.ErrPermissionDenied) {
+			t.Errorf("RevokeAllSessions by %s: got %v, want ErrPermissionDenied", tt.name, err)
+		}
+	}
+	if _, err := a.ValidateToken(ctx, token); err != nil {
+		t.Fatalf("ValidateToken after denied revocations: %v", err)
+	}
+
+	if err := a.RevokeAllSessions(asCaller(user.ID), user.ID); err != nil {
+		t.Fatalf("RevokeAllSessions by user: %v", err)
+	}
+	if _, _, err := a.Authenticate(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("Authenticate after revocation: got %v, want ErrTokenRevoked", err)
+	}
+
+	if err := a.RevokeAllSessions(asCaller(admin), other); err != nil {
+		t.Errorf("RevokeAllSessions by admin: %v", err)
+	}
+	if err := a.RevokeAllSessions(asCaller(admin), admin+1); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("RevokeAllSessions of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := a.RegisterNewUser(ctx, "user@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	admin, err := a.RegisterNewUser(ctx, "admin@example.com", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	if err := store.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	loginCtx := requestinfo.WithInfo(ctx, requestinfo.Info{ClientIP: "10.0.0.7"})
+	token, err := a.Login(loginCtx, "user@example.com", testPassword, appID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	claims := parseTestToken(t, store, appID, token)
+
+	if _, err := a.ListSessions(asCaller(user), user); !errors.Is(err, auth.ErrPermissionDenied) {
+		t.Errorf("ListSessions by non-admin: got %v, want ErrPermissionDenied", err)
+	}
+
+	sessions, err := a.ListSessions(asCaller(admin), user)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("ListSessions = %+v, want one session", sessions)
+	}
+	if got := sessions[0]; got.JTI != claims.ID || got.AppID != appID || got.LastSeenIP != "10.0.0.7" {
+		t.Errorf("session = %+v, want jti %q of app %d from 10.0.0.7", got, claims.ID, appID)
+	}
+
+	// RevokeAllSessions revokes recorded sessions, which then aren't listed.
+	if err := a.RevokeAllSessions(asCaller(user), user); err != nil {
+		t.Fatalf("RevokeAllSessions: %v", err)
+	}
+	revoked, err := store.IsRevoked(ctx, claims.ID)
+	if err != nil || !revoked {
+		t.Errorf("IsRevoked of session jti = %t, %v; want revoked", revoked, err)
+	}
+	if sessions, err := a.ListSessions(asCaller(admin), user); err != nil || len(sessions) != 0 {
+		t.Errorf("ListSessions after revocation = %+v, %v; want none", sessions, err)
+	}
+}
@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/services/auth"
+	"sso/internal/storage/sqlite"
+)
+
+// downStorage is storage whose ping fails.
+type downStorage struct {
+	*sqlite.Storage
+}
+
+func (downStorage) Ping(context.Context) error {
+	return errors.New("storage down")
+}
+
+// lockedLimiter locks out every key.
+type lockedLimiter struct{}
+
+func (lockedLimiter) Allow(string) bool               { return false }
+func (lockedLimiter) RetryAfter(string) time.Duration { return time.Minute }
+func (lockedLimiter) Fail(string)                     {}
+func (lockedLimiter) Reset(string)                    {}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+
+	for _, s := range newTestAuth(t, store, nil, auth.Config{}).Status(ctx) {
+		if !s.OK {
+			t.Errorf("%s: not OK on healthy Auth: %s", s.Name, s.Message)
+		}
+	}
+
+	a := newTestAuthWith(t, store, testDeps{
+		provider: downStorage{store},
+		limiter:  lockedLimiter{},
+	}, auth.Config{})
+
+	got := map[string]bool{}
+	for _, s := range a.Status(ctx) {
+		got[s.Name] = s.OK
+	}
+	want := map[string]bool{
+		auth.SubsystemStorage:     false,
+		auth.SubsystemTokens:      true,
+		auth.SubsystemRateLimiter: false,
+	}
+	for name, ok := range want {
+		if got[name] != ok {
+			t.Errorf("%s: OK = %t, want %t", name, got[name], ok)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/storage"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// Policies for tokens whose revocation status can't be checked.
+const (
+	// RevocationFailClosed rejects such tokens.
+	RevocationFailClosed = "fail-closed"
+	// RevocationFailOpen accepts them, so revocation store outage doesn't
+	// take authentication down; revoked tokens work until it recovers.
+	RevocationFailOpen = "fail-open"
+)
+
+// ValidateToken checks token signature, expiry and revocation status
+// and returns its claims.
+func (a *Auth) ValidateToken(ctx context.Context, token string) (*jwt.Claims, error) {
+	const op = "Auth.ValidateToken"
+
+	claims, _, err := a.Authenticate(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return claims, nil
+}
+
+// Authenticate is ValidateToken that also returns app the token was issued
+// for.
+func (a *Auth) Authenticate(ctx context.Context, token string) (*jwt.Claims, models.App, error) {
+	const op = "Auth.Authenticate"
+
+	log := a.log.With(slog.String("op", op))
+
+	appID, err := jwt.AppID(token)
+	if err != nil {
+		log.Info("failed to read app id from token", sl.Err(err))
+
+		return nil, models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	app, err := a.appProvider.App(ctx, appID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Info("app not found", sl.Err(err))
+
+			return nil, models.App{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+
+		log.Error("failed to get app", sl.Err(err))
+
+		return nil, models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	parseOpts := []jwt.ParseOption{
+		jwt.WithLeeway(a.cfg.ClockSkewLeeway),
+		jwt.WithIssuer(a.cfg.Issuer),
+		jwt.WithKeyProvider(a.keys),
+	}
+	if len(a.cfg.TokenAlgorithms) > 0 {
+		parseOpts = append(parseOpts, jwt.WithAlgorithms(a.cfg.TokenAlgorithms...))
+	}
+
+	claims, err := jwt.ParseToken(token, app, parseOpts...)
+	if err != nil {
+		log.Info("failed to parse token", sl.Err(err))
+
+		return nil, models.App{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidToken, err)
+	}
+
+	revoked, err := a.isRevoked(ctx, claims)
+	if err != nil {
+		if a.cfg.RevocationFailurePolicy == RevocationFailOpen && ctx.Err() == nil {
+			log.Warn("failed to check token revocation, accepting token (fail-open)",
+				slog.String("jti", claims.ID),
+				sl.Err(err),
+			)
+
+			return claims, app, nil
+		}
+
+		log.Error("failed to check token revocation", sl.Err(err))
+
+		return nil, models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if revoked {
+		log.Info("token revoked", slog.String("jti", claims.ID))
+
+		return nil, models.App{}, fmt.Errorf("%s: %w", op, ErrTokenRevoked)
+	}
+
+	return claims, app, nil
+}
+
+// isRevoked reports whether token was revoked by jti or by raising token
+// version of its user. Tokens of deleted users count as revoked.
+func (a *Auth) isRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	revoked, err := a.revocationStore.IsRevoked(ctx, claims.ID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	user, err := a.usrProvider.UserByID(ctx, claims.UID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return claims.TokenVersion < user.TokenVersion, nil
+}
+
+// Logout revokes token so it can't be used until it expires.
+func (a *Auth) Logout(ctx context.Context, token string) error {
+	const op = "Auth.Logout"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("logging out")
+
+	claims, err := a.ValidateToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.revocationStore.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		log.Error("failed to revoke token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged out", slog.Int64("user_id", claims.UID))
+
+	return nil
+}
+
+// IntrospectionResponse describes token in the spirit of RFC 7662.
+// Only Active is set for inactive tokens.
+type IntrospectionResponse struct {
+	Active    bool
+	UID       int64
+	Email     string
+	AppID     int
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+// Introspect reports whether token is active (valid, not expired, not
+// revoked) and, if so, what it contains. Inactive token is not an error.
+func (a *Auth) Introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	const op = "Auth.Introspect"
+
+	claims, err := a.ValidateToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+			return &IntrospectionResponse{Active: false}, nil
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &IntrospectionResponse{
+		Active: true,
+		UID:    claims.UID,
+		Email:  claims.Email,
+		AppID:  claims.AppID,
+	}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.IssuedAt != nil {
+		resp.IssuedAt = claims.IssuedAt.Time
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,352 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/jwt"
+	"sso/internal/services/auth"
+	"sso/internal/storage"
+	"sso/internal/storage/sqlite"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// issueTestToken registers user and app in store and returns token for them
+// expiring after ttl, which may be negative.
+func issueTestToken(t *testing.T, store *sqlite.Storage, ttl time.Duration) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	if _, err := newTestAuth(t, store, nil, auth.Config{}).RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	app, err := store.App(ctx, appID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	token, err := jwt.NewToken(user, app, testIssuer, ttl, nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	return token.Signed
+}
+
+func TestValidateTokenClockSkewLeeway(t *testing.T) {
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, -10*time.Second)
+
+	tests := []struct {
+		name   string
+		leeway time.Duration
+		want   error
+	}{
+		{"expired within leeway", 30 * time.Second, nil},
+		{"expired beyond leeway", 0, jwt.ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuth(t, store, nil, auth.Config{ClockSkewLeeway: tt.leeway})
+
+			_, err := a.ValidateToken(context.Background(), token)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ValidateToken: got %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && !errors.Is(err, auth.ErrInvalidToken) {
+				t.Errorf("ValidateToken: got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestValidateTokenIssuer(t *testing.T) {
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+
+	if _, err := newTestAuth(t, store, nil, auth.Config{}).ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("ValidateToken with matching issuer: %v", err)
+	}
+
+	other := newTestAuth(t, store, nil, auth.Config{Issuer: "other-sso"})
+	if _, err := other.ValidateToken(context.Background(), token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("ValidateToken with other issuer: got %v, want ErrInvalidToken", err)
+	}
+}
+
+// countingApps counts app lookups reaching store.
+type countingApps struct {
+	*sqlite.Storage
+	calls atomic.Int32
+}
+
+func (c *countingApps) App(ctx context.Context, appID int) (models.App, error) {
+	c.calls.Add(1)
+
+	return c.Storage.App(ctx, appID)
+}
+
+func TestAuthenticateCachesApp(t *testing.T) {
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	apps := &countingApps{Storage: store}
+	a := newTestAuthWith(t, store, testDeps{apps: apps}, auth.Config{AppCacheTTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		_, app, err := a.Authenticate(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Authenticate %d: %v", i, err)
+		}
+		if app.Name != "web" {
+			t.Errorf("Authenticate %d app = %q, want web", i, app.Name)
+		}
+	}
+
+	if got := apps.calls.Load(); got != 1 {
+		t.Errorf("app looked up in storage %d times, want 1", got)
+	}
+}
+
+// failingUserByID is store whose UserByID fails with err; with cancel set
+// it cancels request instead and fails with its error.
+type failingUserByID struct {
+	*sqlite.Storage
+	err    error
+	cancel context.CancelFunc
+}
+
+func (s failingUserByID) UserByID(ctx context.Context, _ int64) (models.User, error) {
+	if s.cancel != nil {
+		s.cancel()
+
+		return models.User{}, ctx.Err()
+	}
+
+	return models.User{}, s.err
+}
+
+func TestValidateTokenRevocationFailurePolicy(t *testing.T) {
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	errDown := errors.New("storage is down")
+
+	tests := []struct {
+		name    string
+		policy  string
+		err     error
+		wantErr error
+	}{
+		{"fail-closed by default", "", errDown, errDown},
+		{"fail-closed", auth.RevocationFailClosed, errDown, errDown},
+		{"fail-open", auth.RevocationFailOpen, errDown, nil},
+		{"fail-open on deleted user", auth.RevocationFailOpen, storage.ErrUserNotFound, auth.ErrTokenRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthWith(t, store, testDeps{provider: failingUserByID{Storage: store, err: tt.err}},
+				auth.Config{RevocationFailurePolicy: tt.policy})
+
+			if _, err := a.ValidateToken(context.Background(), token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateToken: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Cancelled request isn't storage outage, fail-open doesn't accept it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newTestAuthWith(t, store, testDeps{provider: failingUserByID{Storage: store, cancel: cancel}},
+		auth.Config{RevocationFailurePolicy: auth.RevocationFailOpen})
+	if _, err := a.ValidateToken(ctx, token); !errors.Is(err, context.Canceled) {
+		t.Errorf("ValidateToken of cancelled request: got %v, want context.Canceled", err)
+	}
+}
+
+// staticKeys signs and verifies all tokens with key kept outside storage.
+type staticKeys struct{ key []byte }
+
+func (p staticKeys) SigningKey(models.App) (jwt.SigningKey, error) {
+	return jwt.SigningKey{Method: gojwt.SigningMethodHS256, KID: "static", Key: p.key}, nil
+}
+
+func (p staticKeys) VerificationKey(*gojwt.Token, models.App, time.Time) (interface{}, error) {
+	return p.key, nil
+}
+
+func TestAuthKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuthWith(t, store, testDeps{signing: staticKeys{key: []byte("external-key")}}, auth.Config{})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	token, err := a.Login(ctx, "user@example.com", testPassword, appID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := a.ValidateToken(ctx, token); err != nil {
+		t.Errorf("ValidateToken with same provider: %v", err)
+	}
+	if _, err := newTestAuth(t, store, nil, auth.Config{}).ValidateToken(ctx, token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("ValidateToken with storage keys: got %v, want ErrInvalidToken", err)
+	}
+
+	external := newTestAuthWith(t, store, testDeps{signing: jwt.ExternalKeys{}}, auth.Config{})
+	if _, err := external.Login(ctx, "user@example.com", testPassword, appID); !errors.Is(err, jwt.ErrKeyProviderNotConfigured) {
+		t.Errorf("Login with ExternalKeys: got %v, want ErrKeyProviderNotConfigured", err)
+	}
+}
+
+func TestValidateTokenAlgorithms(t *testing.T) {
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+
+	if _, err := newTestAuth(t, store, nil, auth.Config{TokenAlgorithms: []string{"HS256"}}).ValidateToken(context.Background(), token); err != nil {
+		t.Errorf("ValidateToken of allowed algorithm: %v", err)
+	}
+
+	es256Only := newTestAuth(t, store, nil, auth.Config{TokenAlgorithms: []string{"ES256"}})
+	if _, err := es256Only.ValidateToken(context.Background(), token); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("ValidateToken of disallowed algorithm: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestValidateTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	if _, err := a.ValidateToken(ctx, token); err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if err := store.IncrementTokenVersion(ctx, user.ID); err != nil {
+		t.Fatalf("IncrementTokenVersion: %v", err)
+	}
+
+	if _, err := a.ValidateToken(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("ValidateToken of older version: got %v, want ErrTokenRevoked", err)
+	}
+
+	appID, err := jwt.AppID(token)
+	if err != nil {
+		t.Fatalf("AppID: %v", err)
+	}
+	fresh, err := a.Login(ctx, "user@example.com", testPassword, appID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := a.ValidateToken(ctx, fresh); err != nil {
+		t.Errorf("ValidateToken of token issued after increment: %v", err)
+	}
+}
+
+func TestValidateTokenRevokedJTI(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	claims, err := a.ValidateToken(ctx, token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if err := store.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+
+	if _, err := a.ValidateToken(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("ValidateToken of revoked jti: got %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	appID, err := jwt.AppID(token)
+	if err != nil {
+		t.Fatalf("AppID: %v", err)
+	}
+
+	resp, err := a.Introspect(ctx, token)
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	if !resp.Active || resp.UID != user.ID || resp.Email != user.Email || resp.AppID != appID {
+		t.Errorf("Introspect = %+v, want active token of user %d, app %d", resp, user.ID, appID)
+	}
+	if !resp.IssuedAt.Before(resp.ExpiresAt) || resp.ExpiresAt.Sub(resp.IssuedAt) != time.Hour {
+		t.Errorf("Introspect iat %s, exp %s; want exp an hour after iat", resp.IssuedAt, resp.ExpiresAt)
+	}
+
+	expired := issueTestToken(t, newTestStorage(t), -time.Minute)
+	if err := store.IncrementTokenVersion(ctx, user.ID); err != nil {
+		t.Fatalf("IncrementTokenVersion: %v", err)
+	}
+
+	for name, tok := range map[string]string{"revoked": token, "expired": expired, "malformed": "not-a-token"} {
+		resp, err := a.Introspect(ctx, tok)
+		if err != nil {
+			t.Fatalf("Introspect of %s token: %v", name, err)
+		}
+		if *resp != (auth.IntrospectionResponse{}) {
+			t.Errorf("Introspect of %s token = %+v, want only active=false", name, resp)
+		}
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	token := issueTestToken(t, store, time.Hour)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	if err := a.Logout(ctx, "not-a-token"); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Logout of malformed token: got %v, want ErrInvalidToken", err)
+	}
+
+	if err := a.Logout(ctx, token); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := a.ValidateToken(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("ValidateToken after logout: got %v, want ErrTokenRevoked", err)
+	}
+	if err := a.Logout(ctx, token); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("second Logout: got %v, want ErrTokenRevoked", err)
+	}
+}
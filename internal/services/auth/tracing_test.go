@@ -0,0 +1,55 @@
+package auth_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"sso/internal/services/auth"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestLoginSpans(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	// Global provider can be installed once per process, so it stays after
+	// the test; without exporter it only records spans.
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+	}
+	want := []string{"storage.App", "storage.User", "bcrypt.Compare", "jwt.NewToken", "Auth.Login"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ended spans = %v, want %v", names, want)
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", "Wrong1234", appID); err == nil {
+		t.Fatal("Login with wrong password succeeded")
+	}
+
+	ended := recorder.Ended()
+	if last := ended[len(ended)-1]; last.Name() != "Auth.Login" || last.Status().Code != codes.Error {
+		t.Errorf("span of failed login = %s with status %v, want Auth.Login with Error", last.Name(), last.Status())
+	}
+}
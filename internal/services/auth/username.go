@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"sso/internal/domain/models"
+)
+
+var ErrInvalidUsername = errors.New("invalid username")
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+)
+
+// validateUsername accepts 3-32 ASCII letters, digits, '.', '_' and '-'.
+// '@' is never allowed, so login can tell usernames from emails.
+func validateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return ErrInvalidUsername
+	}
+
+	for _, r := range username {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '-':
+		default:
+			return ErrInvalidUsername
+		}
+	}
+
+	return nil
+}
+
+// isEmailLogin reports whether login identifier is email rather than
+// username.
+func isEmailLogin(login string) bool {
+	return strings.Contains(login, "@")
+}
+
+// lockoutKey returns login limiter key: user ID when the user is known, so
+// email and username share one counter, or case-folded login otherwise.
+func lockoutKey(userID int64, login string) string {
+	if userID > 0 {
+		return "user:" + strconv.FormatInt(userID, 10)
+	}
+
+	return "login:" + strings.ToLower(login)
+}
+
+func (a *Auth) userByLogin(ctx context.Context, login string, byEmail bool) (models.User, error) {
+	if byEmail {
+		return a.usrProvider.User(ctx, login)
+	}
+
+	return a.usrProvider.UserByUsername(ctx, login)
+}
@@ -0,0 +1,81 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/services/auth"
+)
+
+func TestLoginByEmailOrUsername(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{})
+
+	uid, err := a.RegisterNewUserWithUsername(ctx, "user@example.com", "user_1", testPassword)
+	if err != nil {
+		t.Fatalf("RegisterNewUserWithUsername: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	for _, login := range []string{"user@example.com", "user_1"} {
+		token, err := a.Login(ctx, login, testPassword, appID)
+		if err != nil {
+			t.Fatalf("Login(%q): %v", login, err)
+		}
+		if claims := parseTestToken(t, store, appID, token); claims.UID != uid {
+			t.Errorf("Login(%q) token uid = %d, want %d", login, claims.UID, uid)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		login string
+		want  error
+	}{
+		{"unknown username", "nobody", auth.ErrInvalidCredentials},
+		{"invalid username", "no spaces", auth.ErrInvalidUsername},
+		{"too short username", "ab", auth.ErrInvalidUsername},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.Login(ctx, tt.login, testPassword, appID); !errors.Is(err, tt.want) {
+				t.Errorf("Login: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterUsernameCollision(t *testing.T) {
+	ctx := context.Background()
+	a := newTestAuth(t, newTestStorage(t), nil, auth.Config{})
+
+	if _, err := a.RegisterNewUserWithUsername(ctx, "user@example.com", "user_1", testPassword); err != nil {
+		t.Fatalf("RegisterNewUserWithUsername: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		username string
+		want     error
+	}{
+		{"taken username", "other@example.com", "user_1", auth.ErrUsernameTaken},
+		{"taken email", "user@example.com", "user_2", auth.ErrUserAlreadyExists},
+		{"invalid username", "third@example.com", "user@1", auth.ErrInvalidUsername},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.RegisterNewUserWithUsername(ctx, tt.email, tt.username, testPassword)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("RegisterNewUserWithUsername: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/metrics"
+	"sso/internal/services/auth"
+	"sso/internal/storage/sqlite"
+)
+
+// touchedLimiter records whether limiter was consulted.
+type touchedLimiter struct{ touched bool }
+
+func (l *touchedLimiter) Allow(string) bool               { l.touched = true; return true }
+func (l *touchedLimiter) RetryAfter(string) time.Duration { return 0 }
+func (l *touchedLimiter) Fail(string)                     { l.touched = true }
+func (l *touchedLimiter) Reset(string)                    { l.touched = true }
+
+// touchedProvider records whether users were looked up.
+type touchedProvider struct {
+	*sqlite.Storage
+	touched bool
+}
+
+func (p *touchedProvider) User(ctx context.Context, email string) (models.User, error) {
+	p.touched = true
+
+	return p.Storage.User(ctx, email)
+}
+
+func (p *touchedProvider) UserByUsername(ctx context.Context, username string) (models.User, error) {
+	p.touched = true
+
+	return p.Storage.UserByUsername(ctx, username)
+}
+
+func TestLoginValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		appID    int
+		want     error
+	}{
+		{"malformed email", "user@", testPassword, 1, auth.ErrInvalidEmail},
+		{"malformed username", "a b", testPassword, 1, auth.ErrInvalidUsername},
+		{"empty password", "user@example.com", "", 1, auth.ErrPasswordRequired},
+		{"zero app id", "user@example.com", testPassword, 0, auth.ErrInvalidAppID},
+		{"negative app id", "user@example.com", testPassword, -1, auth.ErrInvalidAppID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStorage(t)
+			limiter := &touchedLimiter{}
+			provider := &touchedProvider{Storage: store}
+			a := newTestAuthWith(t, store, testDeps{provider: provider, limiter: limiter}, auth.Config{})
+
+			invalid := counterValue(t, "sso_login_total", metrics.ResultInvalidArgument)
+
+			if _, err := a.Login(context.Background(), tt.login, tt.password, tt.appID); !errors.Is(err, tt.want) {
+				t.Fatalf("Login: got %v, want %v", err, tt.want)
+			}
+			if limiter.touched || provider.touched {
+				t.Errorf("limiter touched %t, storage touched %t; want neither", limiter.touched, provider.touched)
+			}
+			if got := counterValue(t, "sso_login_total", metrics.ResultInvalidArgument) - invalid; got != 1 {
+				t.Errorf("invalid_argument logins grew by %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{PasswordPolicy: auth.PasswordPolicy{MinLength: 8}})
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     error
+	}{
+		{"malformed email", "user@", testPassword, auth.ErrInvalidEmail},
+		{"weak password", "user@example.com", "short", auth.ErrWeakPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := counterValue(t, "sso_register_total", metrics.ResultInvalidArgument)
+
+			if _, err := a.RegisterNewUser(context.Background(), tt.email, tt.password); !errors.Is(err, tt.want) {
+				t.Fatalf("RegisterNewUser: got %v, want %v", err, tt.want)
+			}
+			if got := counterValue(t, "sso_register_total", metrics.ResultInvalidArgument) - invalid; got != 1 {
+				t.Errorf("invalid_argument registrations grew by %v, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/lib/logger/sl"
+	"sso/internal/lib/random"
+	"sso/internal/storage"
+)
+
+var ErrInvalidVerificationToken = errors.New("invalid verification token")
+
+// RequestVerification issues short-lived token confirming ownership of email.
+// Delivering it to the user (e.g. by mail) is up to the caller.
+func (a *Auth) RequestVerification(ctx context.Context, email string) (string, error) {
+	const op = "Auth.RequestVerification"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.String("email", email),
+	)
+
+	log.Info("requesting email verification")
+
+	user, err := a.usrProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+
+			return "", fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := random.Token(userTokenSize)
+	if err != nil {
+		log.Error("failed to generate verification token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.userTokens.SaveUserToken(ctx, models.UserToken{
+		Token:     token,
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeEmailVerification,
+		ExpiresAt: time.Now().Add(a.cfg.VerificationTokenTTL),
+	})
+	if err != nil {
+		log.Error("failed to save verification token", sl.Err(err))
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// ConfirmVerification marks email of token owner as verified.
+// Token can be used only once.
+func (a *Auth) ConfirmVerification(ctx context.Context, token string) error {
+	const op = "Auth.ConfirmVerification"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("confirming email verification")
+
+	ut, err := a.userTokens.UserToken(ctx, token, models.TokenPurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserTokenNotFound) {
+			log.Warn("verification token not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+		}
+
+		log.Error("failed to get verification token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !time.Now().Before(ut.ExpiresAt) {
+		log.Warn("verification token expired", slog.Time("expires_at", ut.ExpiresAt))
+
+		return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+	}
+
+	err = a.transactor.WithTx(ctx, func(ctx context.Context) error {
+		if err := a.userTokens.DeleteUserToken(ctx, token); err != nil {
+			return fmt.Errorf("delete verification token: %w", err)
+		}
+
+		if err := a.userTokens.SetEmailVerified(ctx, ut.UserID); err != nil {
+			return fmt.Errorf("mark email as verified: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrUserTokenNotFound) {
+			log.Warn("verification token already used", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+		}
+
+		log.Error("failed to confirm verification", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email verified", slog.Int64("user_id", ut.UserID))
+
+	return nil
+}
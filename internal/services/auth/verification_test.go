@@ -0,0 +1,80 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sso/internal/services/auth"
+)
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{
+		VerificationTokenTTL: time.Hour,
+		RequireVerifiedEmail: true,
+	})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+	appID, err := store.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); !errors.Is(err, auth.ErrEmailNotVerified) {
+		t.Fatalf("Login before verification: got %v, want ErrEmailNotVerified", err)
+	}
+
+	if _, err := a.RequestVerification(ctx, "nobody@example.com"); !errors.Is(err, auth.ErrUserNotFound) {
+		t.Errorf("RequestVerification of unknown email: got %v, want ErrUserNotFound", err)
+	}
+	if err := a.ConfirmVerification(ctx, "garbage"); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("ConfirmVerification with garbage token: got %v, want ErrInvalidVerificationToken", err)
+	}
+
+	token, err := a.RequestVerification(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("RequestVerification: %v", err)
+	}
+	if err := a.ConfirmVerification(ctx, token); err != nil {
+		t.Fatalf("ConfirmVerification: %v", err)
+	}
+	if err := a.ConfirmVerification(ctx, token); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("ConfirmVerification with used token: got %v, want ErrInvalidVerificationToken", err)
+	}
+
+	if _, err := a.Login(ctx, "user@example.com", testPassword, appID); err != nil {
+		t.Errorf("Login after verification: %v", err)
+	}
+}
+
+func TestEmailVerificationExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t)
+	a := newTestAuth(t, store, nil, auth.Config{VerificationTokenTTL: time.Nanosecond})
+
+	if _, err := a.RegisterNewUser(ctx, "user@example.com", testPassword); err != nil {
+		t.Fatalf("RegisterNewUser: %v", err)
+	}
+
+	token, err := a.RequestVerification(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("RequestVerification: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if err := a.ConfirmVerification(ctx, token); !errors.Is(err, auth.ErrInvalidVerificationToken) {
+		t.Errorf("ConfirmVerification with expired token: got %v, want ErrInvalidVerificationToken", err)
+	}
+	user, err := store.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if user.EmailVerified {
+		t.Error("email verified with expired token")
+	}
+}
@@ -0,0 +1,100 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"sso/internal/storage/memory"
+	"sso/internal/storage/postgres"
+	"sso/internal/storage/sqlite"
+)
+
+// Storage is implemented by every storage driver.
+type Storage interface {
+	SaveUser(ctx context.Context, email string, passHash []byte) (int64, error)
+	SaveUserWithUsername(ctx context.Context, email, username string, passHash []byte) (int64, error)
+	User(ctx context.Context, email string) (models.User, error)
+	UserByUsername(ctx context.Context, username string) (models.User, error)
+	UserByID(ctx context.Context, userID int64) (models.User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]models.User, error)
+	UpdatePasswordHash(ctx context.Context, userID int64, passHash []byte) error
+	IncrementTokenVersion(ctx context.Context, userID int64) error
+	DeleteUser(ctx context.Context, userID int64) error
+
+	HasRole(ctx context.Context, userID int64, role string) (bool, error)
+	HasRoleBatch(ctx context.Context, userIDs []int64, role string) (map[int64]bool, error)
+	AnyUserHasRole(ctx context.Context, role string) (bool, error)
+	HasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+	AssignRole(ctx context.Context, userID int64, role string) error
+	SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (int64, error)
+	Role(ctx context.Context, name string) (models.Role, error)
+
+	SaveApp(ctx context.Context, name string, secret string, tokenTTL time.Duration) (int, error)
+	App(ctx context.Context, id int) (models.App, error)
+	UpsertApp(ctx context.Context, app models.App) error
+	SaveAppKey(ctx context.Context, appID int, key models.AppKey) error
+	RotateAppKey(ctx context.Context, appID int, key models.AppKey, retireAt time.Time) error
+	DeleteAppKey(ctx context.Context, appID int, kid string) error
+	SetAppKeyPair(ctx context.Context, appID int, privateKey, publicKey string) error
+
+	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
+	RefreshToken(ctx context.Context, token string) (models.RefreshToken, error)
+	RevokeRefreshTokens(ctx context.Context, userID int64) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	SaveSession(ctx context.Context, session models.Session) error
+	Sessions(ctx context.Context, userID int64) ([]models.Session, error)
+
+	SaveUserToken(ctx context.Context, token models.UserToken) error
+	UserToken(ctx context.Context, token string, purpose string) (models.UserToken, error)
+	DeleteUserToken(ctx context.Context, token string) error
+	SetEmailVerified(ctx context.Context, userID int64) error
+
+	SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error
+	IdempotencyKey(ctx context.Context, key string) (models.IdempotencyKey, error)
+
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+
+	// WithTx runs fn in a transaction; storage calls made with ctx passed
+	// to fn are committed together or not at all.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
+	Ping(ctx context.Context) error
+	Stop() error
+}
+
+var (
+	_ Storage = (*sqlite.Storage)(nil)
+	_ Storage = (*postgres.Storage)(nil)
+	_ Storage = (*memory.Storage)(nil)
+)
+
+// New opens storage for driver. storagePath is SQLite file path or
+// Postgres DSN; it's ignored by memory driver.
+func New(driver, storagePath string, pool storage.PoolConfig) (Storage, error) {
+	const op = "storage.backend.New"
+
+	var (
+		s   Storage
+		err error
+	)
+
+	switch driver {
+	case storage.DriverSQLite, "":
+		s, err = sqlite.New(storagePath, pool)
+	case storage.DriverPostgres:
+		s, err = postgres.New(storagePath, pool)
+	case storage.DriverMemory:
+		s = memory.New()
+	default:
+		return nil, fmt.Errorf("%s: unknown storage driver %q", op, driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
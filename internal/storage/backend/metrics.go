@@ -0,0 +1,291 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/metrics"
+	"sso/internal/storage"
+)
+
+// instrumented records duration and errors of every storage call.
+type instrumented struct {
+	next Storage
+}
+
+// WithMetrics wraps s so each call is observed in
+// sso_storage_query_duration_seconds and failures are counted in
+// sso_storage_errors_total. It can be stacked with other decorators.
+func WithMetrics(s Storage) Storage {
+	return &instrumented{next: s}
+}
+
+func observe(method string, start time.Time, err *error) {
+	metrics.StorageQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	if *err != nil {
+		metrics.StorageErrorsTotal.WithLabelValues(method, errorKind(*err)).Inc()
+	}
+}
+
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound),
+		errors.Is(err, storage.ErrAppNotFound),
+		errors.Is(err, storage.ErrRoleNotFound),
+		errors.Is(err, storage.ErrRefreshTokenNotFound),
+		errors.Is(err, storage.ErrUserTokenNotFound),
+		errors.Is(err, storage.ErrIdempotencyKeyNotFound):
+		return "not_found"
+	case errors.Is(err, storage.ErrUserExists),
+		errors.Is(err, storage.ErrAppExists),
+		errors.Is(err, storage.ErrAppKeyExists):
+		return "already_exists"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	default:
+		return "other"
+	}
+}
+
+func (s *instrumented) SaveUser(ctx context.Context, email string, passHash []byte) (res int64, err error) {
+	defer observe("SaveUser", time.Now(), &err)
+
+	return s.next.SaveUser(ctx, email, passHash)
+}
+
+func (s *instrumented) User(ctx context.Context, email string) (res models.User, err error) {
+	defer observe("User", time.Now(), &err)
+
+	return s.next.User(ctx, email)
+}
+
+func (s *instrumented) SaveUserWithUsername(ctx context.Context, email, username string, passHash []byte) (res int64, err error) {
+	defer observe("SaveUserWithUsername", time.Now(), &err)
+
+	return s.next.SaveUserWithUsername(ctx, email, username, passHash)
+}
+
+func (s *instrumented) UserByUsername(ctx context.Context, username string) (res models.User, err error) {
+	defer observe("UserByUsername", time.Now(), &err)
+
+	return s.next.UserByUsername(ctx, username)
+}
+
+func (s *instrumented) UserByID(ctx context.Context, userID int64) (res models.User, err error) {
+	defer observe("UserByID", time.Now(), &err)
+
+	return s.next.UserByID(ctx, userID)
+}
+
+func (s *instrumented) ListUsers(ctx context.Context, limit, offset int) (res []models.User, err error) {
+	defer observe("ListUsers", time.Now(), &err)
+
+	return s.next.ListUsers(ctx, limit, offset)
+}
+
+func (s *instrumented) UpdatePasswordHash(ctx context.Context, userID int64, passHash []byte) (err error) {
+	defer observe("UpdatePasswordHash", time.Now(), &err)
+
+	return s.next.UpdatePasswordHash(ctx, userID, passHash)
+}
+
+func (s *instrumented) IncrementTokenVersion(ctx context.Context, userID int64) (err error) {
+	defer observe("IncrementTokenVersion", time.Now(), &err)
+
+	return s.next.IncrementTokenVersion(ctx, userID)
+}
+
+func (s *instrumented) DeleteUser(ctx context.Context, userID int64) (err error) {
+	defer observe("DeleteUser", time.Now(), &err)
+
+	return s.next.DeleteUser(ctx, userID)
+}
+
+func (s *instrumented) HasRole(ctx context.Context, userID int64, role string) (res bool, err error) {
+	defer observe("HasRole", time.Now(), &err)
+
+	return s.next.HasRole(ctx, userID, role)
+}
+
+func (s *instrumented) HasRoleBatch(ctx context.Context, userIDs []int64, role string) (res map[int64]bool, err error) {
+	defer observe("HasRoleBatch", time.Now(), &err)
+
+	return s.next.HasRoleBatch(ctx, userIDs, role)
+}
+
+func (s *instrumented) AnyUserHasRole(ctx context.Context, role string) (res bool, err error) {
+	defer observe("AnyUserHasRole", time.Now(), &err)
+
+	return s.next.AnyUserHasRole(ctx, role)
+}
+
+func (s *instrumented) HasPermission(ctx context.Context, userID int64, permission string) (res bool, err error) {
+	defer observe("HasPermission", time.Now(), &err)
+
+	return s.next.HasPermission(ctx, userID, permission)
+}
+
+func (s *instrumented) AssignRole(ctx context.Context, userID int64, role string) (err error) {
+	defer observe("AssignRole", time.Now(), &err)
+
+	return s.next.AssignRole(ctx, userID, role)
+}
+
+func (s *instrumented) SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (res int64, err error) {
+	defer observe("SaveUserWithRoles", time.Now(), &err)
+
+	return s.next.SaveUserWithRoles(ctx, email, passHash, roles)
+}
+
+func (s *instrumented) Role(ctx context.Context, name string) (res models.Role, err error) {
+	defer observe("Role", time.Now(), &err)
+
+	return s.next.Role(ctx, name)
+}
+
+func (s *instrumented) SaveApp(ctx context.Context, name string, secret string, tokenTTL time.Duration) (res int, err error) {
+	defer observe("SaveApp", time.Now(), &err)
+
+	return s.next.SaveApp(ctx, name, secret, tokenTTL)
+}
+
+func (s *instrumented) App(ctx context.Context, id int) (res models.App, err error) {
+	defer observe("App", time.Now(), &err)
+
+	return s.next.App(ctx, id)
+}
+
+func (s *instrumented) UpsertApp(ctx context.Context, app models.App) (err error) {
+	defer observe("UpsertApp", time.Now(), &err)
+
+	return s.next.UpsertApp(ctx, app)
+}
+
+func (s *instrumented) SaveAppKey(ctx context.Context, appID int, key models.AppKey) (err error) {
+	defer observe("SaveAppKey", time.Now(), &err)
+
+	return s.next.SaveAppKey(ctx, appID, key)
+}
+
+func (s *instrumented) RotateAppKey(ctx context.Context, appID int, key models.AppKey, retireAt time.Time) (err error) {
+	defer observe("RotateAppKey", time.Now(), &err)
+
+	return s.next.RotateAppKey(ctx, appID, key, retireAt)
+}
+
+func (s *instrumented) DeleteAppKey(ctx context.Context, appID int, kid string) (err error) {
+	defer observe("DeleteAppKey", time.Now(), &err)
+
+	return s.next.DeleteAppKey(ctx, appID, kid)
+}
+
+func (s *instrumented) SetAppKeyPair(ctx context.Context, appID int, privateKey, publicKey string) (err error) {
+	defer observe("SetAppKeyPair", time.Now(), &err)
+
+	return s.next.SetAppKeyPair(ctx, appID, privateKey, publicKey)
+}
+
+func (s *instrumented) SaveRefreshToken(ctx context.Context, token models.RefreshToken) (err error) {
+	defer observe("SaveRefreshToken", time.Now(), &err)
+
+	return s.next.SaveRefreshToken(ctx, token)
+}
+
+func (s *instrumented) RefreshToken(ctx context.Context, token string) (res models.RefreshToken, err error) {
+	defer observe("RefreshToken", time.Now(), &err)
+
+	return s.next.RefreshToken(ctx, token)
+}
+
+func (s *instrumented) RevokeRefreshTokens(ctx context.Context, userID int64) (err error) {
+	defer observe("RevokeRefreshTokens", time.Now(), &err)
+
+	return s.next.RevokeRefreshTokens(ctx, userID)
+}
+
+func (s *instrumented) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) (err error) {
+	defer observe("RevokeToken", time.Now(), &err)
+
+	return s.next.RevokeToken(ctx, jti, expiresAt)
+}
+
+func (s *instrumented) IsRevoked(ctx context.Context, jti string) (res bool, err error) {
+	defer observe("IsRevoked", time.Now(), &err)
+
+	return s.next.IsRevoked(ctx, jti)
+}
+
+func (s *instrumented) SaveSession(ctx context.Context, session models.Session) (err error) {
+	defer observe("SaveSession", time.Now(), &err)
+
+	return s.next.SaveSession(ctx, session)
+}
+
+func (s *instrumented) Sessions(ctx context.Context, userID int64) (res []models.Session, err error) {
+	defer observe("Sessions", time.Now(), &err)
+
+	return s.next.Sessions(ctx, userID)
+}
+
+func (s *instrumented) SaveUserToken(ctx context.Context, token models.UserToken) (err error) {
+	defer observe("SaveUserToken", time.Now(), &err)
+
+	return s.next.SaveUserToken(ctx, token)
+}
+
+func (s *instrumented) UserToken(ctx context.Context, token string, purpose string) (res models.UserToken, err error) {
+	defer observe("UserToken", time.Now(), &err)
+
+	return s.next.UserToken(ctx, token, purpose)
+}
+
+func (s *instrumented) DeleteUserToken(ctx context.Context, token string) (err error) {
+	defer observe("DeleteUserToken", time.Now(), &err)
+
+	return s.next.DeleteUserToken(ctx, token)
+}
+
+func (s *instrumented) SetEmailVerified(ctx context.Context, userID int64) (err error) {
+	defer observe("SetEmailVerified", time.Now(), &err)
+
+	return s.next.SetEmailVerified(ctx, userID)
+}
+
+func (s *instrumented) SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey) (err error) {
+	defer observe("SaveIdempotencyKey", time.Now(), &err)
+
+	return s.next.SaveIdempotencyKey(ctx, key)
+}
+
+func (s *instrumented) IdempotencyKey(ctx context.Context, key string) (res models.IdempotencyKey, err error) {
+	defer observe("IdempotencyKey", time.Now(), &err)
+
+	return s.next.IdempotencyKey(ctx, key)
+}
+
+func (s *instrumented) SaveAuditEvent(ctx context.Context, event models.AuditEvent) (err error) {
+	defer observe("SaveAuditEvent", time.Now(), &err)
+
+	return s.next.SaveAuditEvent(ctx, event)
+}
+
+func (s *instrumented) WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer observe("WithTx", time.Now(), &err)
+
+	return s.next.WithTx(ctx, fn)
+}
+
+func (s *instrumented) Ping(ctx context.Context) (err error) {
+	defer observe("Ping", time.Now(), &err)
+
+	return s.next.Ping(ctx)
+}
+
+func (s *instrumented) Stop() error {
+	return s.next.Stop()
+}
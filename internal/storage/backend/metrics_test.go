@@ -0,0 +1,94 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sso/internal/storage"
+	"sso/internal/storage/memory"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricValue returns sample count of histogram or value of counter name
+// whose labels include labels, from the default registry.
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			matched := 0
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; ok && v == l.GetValue() {
+					matched++
+				}
+			}
+			if matched != len(labels) {
+				continue
+			}
+			if h := m.GetHistogram(); h != nil {
+				return float64(h.GetSampleCount())
+			}
+
+			return m.GetCounter().GetValue()
+		}
+	}
+
+	return 0
+}
+
+func TestWithMetrics(t *testing.T) {
+	ctx := context.Background()
+	s := WithMetrics(memory.New())
+
+	if _, err := s.SaveUser(ctx, "user@example.com", []byte("hash")); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user := map[string]string{"method": "User"}
+	notFound := map[string]string{"method": "User", "kind": "not_found"}
+	samples := metricValue(t, "sso_storage_query_duration_seconds", user)
+	errs := metricValue(t, "sso_storage_errors_total", notFound)
+
+	if _, err := s.User(ctx, "user@example.com"); err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if got := metricValue(t, "sso_storage_query_duration_seconds", user); got != samples+1 {
+		t.Errorf("User latency samples = %v, want %v", got, samples+1)
+	}
+
+	if _, err := s.User(ctx, "nobody@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Fatalf("User of unknown email: got %v, want ErrUserNotFound", err)
+	}
+	if got := metricValue(t, "sso_storage_errors_total", notFound); got != errs+1 {
+		t.Errorf("User not_found errors = %v, want %v", got, errs+1)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{storage.ErrAppNotFound, "not_found"},
+		{storage.ErrUserExists, "already_exists"},
+		{context.Canceled, "canceled"},
+		{context.DeadlineExceeded, "deadline_exceeded"},
+		{errors.New("disk full"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := errorKind(tt.err); got != tt.want {
+			t.Errorf("errorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage/postgres"
+	"sso/internal/storage/sqlite"
+)
+
+// RetryPolicy configures retries of transient storage errors.
+type RetryPolicy struct {
+	// MaxAttempts is total number of attempts; 1 or less disables retries.
+	MaxAttempts int
+	// BaseDelay is delay before the second attempt; it doubles after each
+	// further attempt, with jitter.
+	BaseDelay time.Duration
+}
+
+// retrying retries calls failing with transient errors (busy database,
+// serialization failures, connection errors before query was sent).
+// Constraint violations and not-found errors are returned at once.
+type retrying struct {
+	next   Storage
+	policy RetryPolicy
+}
+
+// WithRetry wraps s so transient errors are retried with exponential backoff.
+// It can be stacked with other decorators.
+func WithRetry(s Storage, policy RetryPolicy) Storage {
+	if policy.MaxAttempts <= 1 {
+		return s
+	}
+
+	return &retrying{next: s, policy: policy}
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return sqlite.IsTransient(err) || postgres.IsTransient(err)
+}
+
+func retry[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !isTransient(err) {
+			return res, err
+		}
+
+		timer := time.NewTimer(backoff(policy.BaseDelay, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return res, fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func retryErr(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	_, err := retry(ctx, policy, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+
+	return err
+}
+
+// backoff returns delay after attempt: base * 2^(attempt-1), randomly
+// reduced by up to half so concurrent retries spread out.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << (attempt - 1)
+
+	return d/2 + rand.N(d/2+1)
+}
+
+func (s *retrying) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.next.SaveUser(ctx, email, passHash)
+	})
+}
+
+func (s *retrying) User(ctx context.Context, email string) (models.User, error) {
+	return retry(ctx, s.policy, func() (models.User, error) {
+		return s.next.User(ctx, email)
+	})
+}
+
+func (s *retrying) SaveUserWithUsername(ctx context.Context, email, username string, passHash []byte) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.next.SaveUserWithUsername(ctx, email, username, passHash)
+	})
+}
+
+func (s *retrying) UserByUsername(ctx context.Context, username string) (models.User, error) {
+	return retry(ctx, s.policy, func() (models.User, error) {
+		return s.next.UserByUsername(ctx, username)
+	})
+}
+
+func (s *retrying) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	return retry(ctx, s.policy, func() (models.User, error) {
+		return s.next.UserByID(ctx, userID)
+	})
+}
+
+func (s *retrying) ListUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	return retry(ctx, s.policy, func() ([]models.User, error) {
+		return s.next.ListUsers(ctx, limit, offset)
+	})
+}
+
+func (s *retrying) UpdatePasswordHash(ctx context.Context, userID int64, passHash []byte) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.UpdatePasswordHash(ctx, userID, passHash)
+	})
+}
+
+func (s *retrying) IncrementTokenVersion(ctx context.Context, userID int64) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.IncrementTokenVersion(ctx, userID)
+	})
+}
+
+func (s *retrying) DeleteUser(ctx context.Context, userID int64) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.DeleteUser(ctx, userID)
+	})
+}
+
+func (s *retrying) HasRole(ctx context.Context, userID int64, role string) (bool, error) {
+	return retry(ctx, s.policy, func() (bool, error) {
+		return s.next.HasRole(ctx, userID, role)
+	})
+}
+
+func (s *retrying) HasRoleBatch(ctx context.Context, userIDs []int64, role string) (map[int64]bool, error) {
+	return retry(ctx, s.policy, func() (map[int64]bool, error) {
+		return s.next.HasRoleBatch(ctx, userIDs, role)
+	})
+}
+
+func (s *retrying) AnyUserHasRole(ctx context.Context, role string) (bool, error) {
+	return retry(ctx, s.policy, func() (bool, error) {
+		return s.next.AnyUserHasRole(ctx, role)
+	})
+}
+
+func (s *retrying) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	return retry(ctx, s.policy, func() (bool, error) {
+		return s.next.HasPermission(ctx, userID, permission)
+	})
+}
+
+func (s *retrying) AssignRole(ctx context.Context, userID int64, role string) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.AssignRole(ctx, userID, role)
+	})
+}
+
+func (s *retrying) SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.next.SaveUserWithRoles(ctx, email, passHash, roles)
+	})
+}
+
+func (s *retrying) Role(ctx context.Context, name string) (models.Role, error) {
+	return retry(ctx, s.policy, func() (models.Role, error) {
+		return s.next.Role(ctx, name)
+	})
+}
+
+func (s *retrying) SaveApp(ctx context.Context, name string, secret string, tokenTTL time.Duration) (int, error) {
+	return retry(ctx, s.policy, func() (int, error) {
+		return s.next.SaveApp(ctx, name, secret, tokenTTL)
+	})
+}
+
+func (s *retrying) App(ctx context.Context, id int) (models.App, error) {
+	return retry(ctx, s.policy, func() (models.App, error) {
+		return s.next.App(ctx, id)
+	})
+}
+
+func (s *retrying) UpsertApp(ctx context.Context, app models.App) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.UpsertApp(ctx, app)
+	})
+}
+
+func (s *retrying) SaveAppKey(ctx context.Context, appID int, key models.AppKey) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SaveAppKey(ctx, appID, key)
+	})
+}
+
+func (s *retrying) RotateAppKey(ctx context.Context, appID int, key models.AppKey, retireAt time.Time) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.RotateAppKey(ctx, appID, key, retireAt)
+	})
+}
+
+func (s *retrying) DeleteAppKey(ctx context.Context, appID int, kid string) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.DeleteAppKey(ctx, appID, kid)
+	})
+}
+
+func (s *retrying) SetAppKeyPair(ctx context.Context, appID int, privateKey, publicKey string) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SetAppKeyPair(ctx, appID, privateKey, publicKey)
+	})
+}
+
+func (s *retrying) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SaveRefreshToken(ctx, token)
+	})
+}
+
+func (s *retrying) RefreshToken(ctx context.Context, token string) (models.RefreshToken, error) {
+	return retry(ctx, s.policy, func() (models.RefreshToken, error) {
+		return s.next.RefreshToken(ctx, token)
+	})
+}
+
+func (s *retrying) RevokeRefreshTokens(ctx context.Context, userID int64) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.RevokeRefreshTokens(ctx, userID)
+	})
+}
+
+func (s *retrying) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.RevokeToken(ctx, jti, expiresAt)
+	})
+}
+
+func (s *retrying) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return retry(ctx, s.policy, func() (bool, error) {
+		return s.next.IsRevoked(ctx, jti)
+	})
+}
+
+func (s *retrying) SaveSession(ctx context.Context, session models.Session) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SaveSession(ctx, session)
+	})
+}
+
+func (s *retrying) Sessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	return retry(ctx, s.policy, func() ([]models.Session, error) {
+		return s.next.Sessions(ctx, userID)
+	})
+}
+
+func (s *retrying) SaveUserToken(ctx context.Context, token models.UserToken) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SaveUserToken(ctx, token)
+	})
+}
+
+func (s *retrying) UserToken(ctx context.Context, token string, purpose string) (models.UserToken, error) {
+	return retry(ctx, s.policy, func() (models.UserToken, error) {
+		return s.next.UserToken(ctx, token, purpose)
+	})
+}
+
+func (s *retrying) DeleteUserToken(ctx context.Context, token string) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.DeleteUserToken(ctx, token)
+	})
+}
+
+func (s *retrying) SetEmailVerified(ctx context.Context, userID int64) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SetEmailVerified(ctx, userID)
+	})
+}
+
+func (s *retrying) SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SaveIdempotencyKey(ctx, key)
+	})
+}
+
+func (s *retrying) IdempotencyKey(ctx context.Context, key string) (models.IdempotencyKey, error) {
+	return retry(ctx, s.policy, func() (models.IdempotencyKey, error) {
+		return s.next.IdempotencyKey(ctx, key)
+	})
+}
+
+func (s *retrying) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.SaveAuditEvent(ctx, event)
+	})
+}
+
+// WithTx retries whole transaction, so fn must be safe to run again.
+func (s *retrying) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.WithTx(ctx, fn)
+	})
+}
+
+func (s *retrying) Ping(ctx context.Context) error {
+	return retryErr(ctx, s.policy, func() error {
+		return s.next.Ping(ctx)
+	})
+}
+
+func (s *retrying) Stop() error {
+	return s.next.Stop()
+}
@@ -0,0 +1,133 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"sso/internal/storage/memory"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var errBusy = fmt.Errorf("storage.sqlite.User: %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+
+// flakyStorage fails User with errs in turn before answering from memory.
+type flakyStorage struct {
+	*memory.Storage
+	errs  []error
+	calls int
+}
+
+func (s *flakyStorage) User(ctx context.Context, email string) (models.User, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+
+		return models.User{}, err
+	}
+
+	return s.Storage.User(ctx, email)
+}
+
+func newFlakyStorage(t *testing.T, errs ...error) *flakyStorage {
+	t.Helper()
+
+	s := &flakyStorage{Storage: memory.New(), errs: errs}
+	if _, err := s.SaveUser(context.Background(), "user@example.com", []byte("hash")); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	return s
+}
+
+var testPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+func TestWithRetry(t *testing.T) {
+	flaky := newFlakyStorage(t, errBusy, errBusy)
+
+	user, err := WithRetry(flaky, testPolicy).User(context.Background(), "user@example.com")
+	if err != nil {
+		t.Fatalf("User after two busy errors: %v", err)
+	}
+	if user.Email != "user@example.com" || flaky.calls != 3 {
+		t.Errorf("User = %+v after %d calls, want user after 3", user, flaky.calls)
+	}
+}
+
+func TestWithRetryGivesUp(t *testing.T) {
+	flaky := newFlakyStorage(t, errBusy, errBusy, errBusy)
+
+	_, err := WithRetry(flaky, testPolicy).User(context.Background(), "user@example.com")
+	if !isTransient(err) {
+		t.Fatalf("User: got %v, want busy error", err)
+	}
+	if flaky.calls != testPolicy.MaxAttempts {
+		t.Errorf("User called %d times, want %d", flaky.calls, testPolicy.MaxAttempts)
+	}
+}
+
+func TestWithRetrySkipsPermanentErrors(t *testing.T) {
+	for _, permanent := range []error{
+		fmt.Errorf("storage.sqlite.User: %w", storage.ErrUserNotFound),
+		fmt.Errorf("storage.sqlite.User: %w", sqlite3.Error{Code: sqlite3.ErrConstraint}),
+		context.Canceled,
+	} {
+		flaky := newFlakyStorage(t, permanent)
+
+		_, err := WithRetry(flaky, testPolicy).User(context.Background(), "user@example.com")
+		if !errors.Is(err, permanent) || flaky.calls != 1 {
+			t.Errorf("User with %v: got %v after %d calls, want it at once", permanent, err, flaky.calls)
+		}
+	}
+}
+
+func TestWithRetryStopsOnCancel(t *testing.T) {
+	flaky := newFlakyStorage(t, errBusy, errBusy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := WithRetry(flaky, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}).User(ctx, "user@example.com")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("User with cancelled ctx: got %v, want context.Canceled", err)
+	}
+}
+
+func TestWithRetryCancelledDuringBackoff(t *testing.T) {
+	flaky := newFlakyStorage(t, errBusy, errBusy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := WithRetry(flaky, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour}).User(ctx, "user@example.com")
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errBusy) {
+		t.Errorf("User cancelled during backoff: got %v, want context.DeadlineExceeded wrapping last error", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("storage called %d times, want 1", flaky.calls)
+	}
+}
+
+func TestWithRetryDisabled(t *testing.T) {
+	s := memory.New()
+
+	if got := WithRetry(s, RetryPolicy{MaxAttempts: 1}); got != Storage(s) {
+		t.Errorf("WithRetry with one attempt = %T, want storage unwrapped", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 4; attempt++ {
+		full := time.Millisecond << (attempt - 1)
+
+		if d := backoff(time.Millisecond, attempt); d < full/2 || d > full {
+			t.Errorf("backoff after attempt %d = %s, want between %s and %s", attempt, d, full/2, full)
+		}
+	}
+}
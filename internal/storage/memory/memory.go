@@ -0,0 +1,630 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+// Storage keeps everything in maps guarded by a mutex. Data is lost on
+// Stop; it's meant for tests and local development.
+type Storage struct {
+	// txMu runs transactions one at a time, see WithTx.
+	txMu sync.Mutex
+	mu   sync.RWMutex
+
+	users        map[int64]models.User
+	userIDs      map[string]int64
+	usernames    map[string]int64
+	lastUserID   int64
+	roles        map[string]models.Role
+	userRoles    map[int64]map[string]struct{}
+	apps         map[int]models.App
+	appKeyOwners map[string]int
+
+	refreshTokens   map[string]models.RefreshToken
+	revokedTokens   map[string]time.Time
+	sessions        map[string]models.Session
+	userTokens      map[string]models.UserToken
+	idempotencyKeys map[string]models.IdempotencyKey
+	auditLog        []models.AuditEvent
+}
+
+// New returns empty storage with the same built-in roles migrations create.
+func New() *Storage {
+	return &Storage{
+		users:     make(map[int64]models.User),
+		userIDs:   make(map[string]int64),
+		usernames: make(map[string]int64),
+		userRoles: make(map[int64]map[string]struct{}),
+		roles: map[string]models.Role{
+			models.RoleAdmin: {ID: 1, Name: models.RoleAdmin, Permissions: []string{models.PermissionAll}},
+		},
+		apps:         make(map[int]models.App),
+		appKeyOwners: make(map[string]int),
+
+		refreshTokens:   make(map[string]models.RefreshToken),
+		revokedTokens:   make(map[string]time.Time),
+		sessions:        make(map[string]models.Session),
+		userTokens:      make(map[string]models.UserToken),
+		idempotencyKeys: make(map[string]models.IdempotencyKey),
+	}
+}
+
+// Ping always succeeds.
+func (s *Storage) Ping(_ context.Context) error {
+	return nil
+}
+
+func (s *Storage) Stop() error {
+	return nil
+}
+
+// SaveUser saves user.
+func (s *Storage) SaveUser(_ context.Context, email string, passHash []byte) (int64, error) {
+	return s.saveUser("storage.memory.SaveUser", email, "", passHash)
+}
+
+// SaveUserWithUsername saves user with username.
+func (s *Storage) SaveUserWithUsername(_ context.Context, email, username string, passHash []byte) (int64, error) {
+	return s.saveUser("storage.memory.SaveUserWithUsername", email, username, passHash)
+}
+
+func (s *Storage) saveUser(op, email, username string, passHash []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.insertUser(op, email, username, passHash)
+}
+
+// insertUser saves user; s.mu must be held.
+func (s *Storage) insertUser(op, email, username string, passHash []byte) (int64, error) {
+	if _, ok := s.userIDs[email]; ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+	if _, ok := s.usernames[username]; ok && username != "" {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUsernameTaken)
+	}
+
+	s.lastUserID++
+	id := s.lastUserID
+
+	s.users[id] = models.User{ID: id, Email: email, Username: username, PassHash: cloneBytes(passHash)}
+	s.userIDs[email] = id
+	if username != "" {
+		s.usernames[username] = id
+	}
+
+	return id, nil
+}
+
+// User returns user by email.
+func (s *Storage) User(_ context.Context, email string) (models.User, error) {
+	const op = "storage.memory.User"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.userIDs[email]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return copyUser(s.users[id]), nil
+}
+
+// UserByID returns user by id.
+func (s *Storage) UserByID(_ context.Context, userID int64) (models.User, error) {
+	const op = "storage.memory.UserByID"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return copyUser(user), nil
+}
+
+// UserByUsername returns user by username.
+func (s *Storage) UserByUsername(_ context.Context, username string) (models.User, error) {
+	const op = "storage.memory.UserByUsername"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.usernames[username]
+	if !ok || username == "" {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return copyUser(s.users[id]), nil
+}
+
+// ListUsers returns users ordered by id, without password hashes.
+func (s *Storage) ListUsers(_ context.Context, limit, offset int) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]int64, 0, len(s.users))
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if offset >= len(ids) {
+		return nil, nil
+	}
+	ids = ids[offset:]
+	if limit < len(ids) {
+		ids = ids[:limit]
+	}
+
+	users := make([]models.User, 0, len(ids))
+	for _, id := range ids {
+		user := s.users[id]
+		user.PassHash = nil
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// UpdatePasswordHash replaces password hash of user.
+func (s *Storage) UpdatePasswordHash(_ context.Context, userID int64, passHash []byte) error {
+	const op = "storage.memory.UpdatePasswordHash"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	user.PassHash = cloneBytes(passHash)
+	s.users[userID] = user
+
+	return nil
+}
+
+// IncrementTokenVersion increments token version of user.
+func (s *Storage) IncrementTokenVersion(_ context.Context, userID int64) error {
+	const op = "storage.memory.IncrementTokenVersion"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	user.TokenVersion++
+	s.users[userID] = user
+
+	return nil
+}
+
+// DeleteUser deletes user together with its tokens, roles and
+// idempotency keys.
+func (s *Storage) DeleteUser(_ context.Context, userID int64) error {
+	const op = "storage.memory.DeleteUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	delete(s.users, userID)
+	delete(s.userIDs, user.Email)
+	delete(s.usernames, user.Username)
+	delete(s.userRoles, userID)
+
+	for k, t := range s.refreshTokens {
+		if t.UserID == userID {
+			delete(s.refreshTokens, k)
+		}
+	}
+	for k, t := range s.userTokens {
+		if t.UserID == userID {
+			delete(s.userTokens, k)
+		}
+	}
+	for jti, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, jti)
+		}
+	}
+	for k, key := range s.idempotencyKeys {
+		if key.UserID == userID {
+			delete(s.idempotencyKeys, k)
+		}
+	}
+
+	return nil
+}
+
+// SetEmailVerified marks user email as verified.
+func (s *Storage) SetEmailVerified(_ context.Context, userID int64) error {
+	const op = "storage.memory.SetEmailVerified"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	user.EmailVerified = true
+	s.users[userID] = user
+
+	return nil
+}
+
+// SaveApp saves app under the next free id.
+func (s *Storage) SaveApp(_ context.Context, name string, secret string, tokenTTL time.Duration) (int, error) {
+	const op = "storage.memory.SaveApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.appNameTaken(name, 0) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+	}
+
+	id := 1
+	for appID := range s.apps {
+		if appID >= id {
+			id = appID + 1
+		}
+	}
+
+	s.apps[id] = models.App{ID: id, Name: name, Secret: secret, TokenTTL: tokenTTL}
+
+	return id, nil
+}
+
+// UpsertApp creates app with given id or updates its name, secret and token
+// TTL.
+func (s *Storage) UpsertApp(_ context.Context, app models.App) error {
+	const op = "storage.memory.UpsertApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.appNameTaken(app.Name, app.ID) {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+	}
+
+	existing := s.apps[app.ID]
+	existing.ID = app.ID
+	existing.Name = app.Name
+	existing.Secret = app.Secret
+	existing.TokenTTL = app.TokenTTL
+	s.apps[app.ID] = existing
+
+	return nil
+}
+
+// App returns app by id together with its signing keys.
+func (s *Storage) App(_ context.Context, id int) (models.App, error) {
+	const op = "storage.memory.App"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.apps[id]
+	if !ok {
+		return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	app.Keys = append([]models.AppKey(nil), app.Keys...)
+
+	return app, nil
+}
+
+// SaveAppKey adds signing key to app. If key is active, other app keys are
+// deactivated.
+func (s *Storage) SaveAppKey(_ context.Context, appID int, key models.AppKey) error {
+	const op = "storage.memory.SaveAppKey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	if _, ok := s.appKeyOwners[key.ID]; ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppKeyExists)
+	}
+
+	keys := make([]models.AppKey, 0, len(app.Keys)+1)
+	for _, k := range app.Keys {
+		if key.Active {
+			k.Active = false
+		}
+
+		keys = append(keys, k)
+	}
+
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	app.Keys = append(keys, key)
+	s.apps[appID] = app
+	s.appKeyOwners[key.ID] = appID
+
+	return nil
+}
+
+// RotateAppKey saves key as the only active app key. Other keys and legacy
+// secret keep verifying tokens until retireAt; earlier expiry is kept.
+func (s *Storage) RotateAppKey(_ context.Context, appID int, key models.AppKey, retireAt time.Time) error {
+	const op = "storage.memory.RotateAppKey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	if _, ok := s.appKeyOwners[key.ID]; ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppKeyExists)
+	}
+
+	if app.SecretExpiresAt.IsZero() {
+		app.SecretExpiresAt = retireAt
+	}
+
+	keys := make([]models.AppKey, 0, len(app.Keys)+1)
+	for _, k := range app.Keys {
+		k.Active = false
+		if k.ExpiresAt.IsZero() {
+			k.ExpiresAt = retireAt
+		}
+
+		keys = append(keys, k)
+	}
+
+	key.Active = true
+	key.ExpiresAt = time.Time{}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	app.Keys = append(keys, key)
+	s.apps[appID] = app
+	s.appKeyOwners[key.ID] = appID
+
+	return nil
+}
+
+// DeleteAppKey removes app key. Tokens signed with it stop verifying.
+func (s *Storage) DeleteAppKey(_ context.Context, appID int, kid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if owner, ok := s.appKeyOwners[kid]; !ok || owner != appID {
+		return nil
+	}
+
+	app := s.apps[appID]
+
+	keys := make([]models.AppKey, 0, len(app.Keys))
+	for _, k := range app.Keys {
+		if k.ID != kid {
+			keys = append(keys, k)
+		}
+	}
+
+	app.Keys = keys
+	s.apps[appID] = app
+	delete(s.appKeyOwners, kid)
+
+	return nil
+}
+
+// SetAppKeyPair replaces ES256 key pair of app.
+func (s *Storage) SetAppKeyPair(_ context.Context, appID int, privateKey, publicKey string) error {
+	const op = "storage.memory.SetAppKeyPair"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	app.PrivateKey = privateKey
+	app.PublicKey = publicKey
+	s.apps[appID] = app
+
+	return nil
+}
+
+// SaveRefreshToken saves refresh token.
+func (s *Storage) SaveRefreshToken(_ context.Context, token models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshTokens[token.Token] = token
+
+	return nil
+}
+
+// RefreshToken returns refresh token by its value.
+func (s *Storage) RefreshToken(_ context.Context, token string) (models.RefreshToken, error) {
+	const op = "storage.memory.RefreshToken"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rt, ok := s.refreshTokens[token]
+	if !ok {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
+	}
+
+	return rt, nil
+}
+
+// RevokeRefreshTokens marks all refresh tokens of user as revoked.
+func (s *Storage) RevokeRefreshTokens(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, t := range s.refreshTokens {
+		if t.UserID == userID {
+			t.Revoked = true
+			s.refreshTokens[k] = t
+		}
+	}
+
+	return nil
+}
+
+// RevokeToken marks token with given jti as revoked.
+func (s *Storage) RevokeToken(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revokedTokens[jti]; !ok {
+		s.revokedTokens[jti] = expiresAt
+	}
+
+	return nil
+}
+
+// IsRevoked checks if token with given jti is revoked.
+func (s *Storage) IsRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.revokedTokens[jti]
+
+	return ok, nil
+}
+
+// SaveUserToken saves one-time user token.
+func (s *Storage) SaveUserToken(_ context.Context, token models.UserToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.userTokens[token.Token] = token
+
+	return nil
+}
+
+// UserToken returns one-time user token by its value and purpose.
+func (s *Storage) UserToken(_ context.Context, token string, purpose string) (models.UserToken, error) {
+	const op = "storage.memory.UserToken"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ut, ok := s.userTokens[token]
+	if !ok || ut.Purpose != purpose {
+		return models.UserToken{}, fmt.Errorf("%s: %w", op, storage.ErrUserTokenNotFound)
+	}
+
+	return ut, nil
+}
+
+// DeleteUserToken deletes one-time user token. If it's already gone,
+// returns ErrUserTokenNotFound.
+func (s *Storage) DeleteUserToken(_ context.Context, token string) error {
+	const op = "storage.memory.DeleteUserToken"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userTokens[token]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserTokenNotFound)
+	}
+
+	delete(s.userTokens, token)
+
+	return nil
+}
+
+// SaveIdempotencyKey saves idempotency key. An expired key with the same
+// value is replaced; if the key exists and is not expired, returns
+// storage.ErrIdempotencyKeyExists.
+func (s *Storage) SaveIdempotencyKey(_ context.Context, key models.IdempotencyKey) error {
+	const op = "storage.memory.SaveIdempotencyKey"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.idempotencyKeys[key.Key]; ok && time.Now().Before(old.ExpiresAt) {
+		return fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyExists)
+	}
+
+	s.idempotencyKeys[key.Key] = key
+
+	return nil
+}
+
+// IdempotencyKey returns idempotency key by its value.
+func (s *Storage) IdempotencyKey(_ context.Context, key string) (models.IdempotencyKey, error) {
+	const op = "storage.memory.IdempotencyKey"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.idempotencyKeys[key]
+	if !ok {
+		return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyNotFound)
+	}
+
+	return k, nil
+}
+
+// SaveAuditEvent appends event to audit log.
+func (s *Storage) SaveAuditEvent(_ context.Context, event models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.auditLog = append(s.auditLog, event)
+
+	return nil
+}
+
+// appNameTaken reports whether app other than exceptID is named name.
+func (s *Storage) appNameTaken(name string, exceptID int) bool {
+	for id, app := range s.apps {
+		if id != exceptID && app.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func copyUser(u models.User) models.User {
+	u.PassHash = cloneBytes(u.PassHash)
+
+	return u
+}
+
+func cloneBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+// HasRole checks if user has role with given name.
+func (s *Storage) HasRole(_ context.Context, userID int64, role string) (bool, error) {
+	const op = "storage.memory.HasRole"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	_, ok := s.userRoles[userID][role]
+
+	return ok, nil
+}
+
+// AnyUserHasRole reports whether at least one user has role.
+func (s *Storage) AnyUserHasRole(_ context.Context, role string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, roles := range s.userRoles {
+		if _, ok := roles[role]; ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// HasRoleBatch checks role membership for several users at once.
+// Users that don't exist are absent from the result.
+func (s *Storage) HasRoleBatch(_ context.Context, userIDs []int64, role string) (map[int64]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		if _, ok := s.users[id]; !ok {
+			continue
+		}
+
+		_, res[id] = s.userRoles[id][role]
+	}
+
+	return res, nil
+}
+
+// HasPermission checks if any role of user grants permission.
+func (s *Storage) HasPermission(_ context.Context, userID int64, permission string) (bool, error) {
+	const op = "storage.memory.HasPermission"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	for name := range s.userRoles[userID] {
+		for _, p := range s.roles[name].Permissions {
+			if p == permission || p == models.PermissionAll {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// AssignRole grants role with given name to user.
+func (s *Storage) AssignRole(_ context.Context, userID int64, role string) error {
+	const op = "storage.memory.AssignRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[role]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	if s.userRoles[userID] == nil {
+		s.userRoles[userID] = make(map[string]struct{})
+	}
+	s.userRoles[userID][role] = struct{}{}
+
+	return nil
+}
+
+// SaveUserWithRoles saves user and grants it roles at once. If some role
+// doesn't exist, nothing is saved and ErrRoleNotFound is returned.
+func (s *Storage) SaveUserWithRoles(_ context.Context, email string, passHash []byte, roles []string) (int64, error) {
+	const op = "storage.memory.SaveUserWithRoles"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, role := range roles {
+		if _, ok := s.roles[role]; !ok {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+	}
+
+	id, err := s.insertUser(op, email, "", passHash)
+	if err != nil {
+		return 0, err
+	}
+
+	s.userRoles[id] = make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		s.userRoles[id][role] = struct{}{}
+	}
+
+	return id, nil
+}
+
+// Role returns role with its permissions by name.
+func (s *Storage) Role(_ context.Context, name string) (models.Role, error) {
+	const op = "storage.memory.Role"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, ok := s.roles[name]
+	if !ok {
+		return models.Role{}, fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+
+	role.Permissions = append([]string(nil), role.Permissions...)
+
+	return role, nil
+}
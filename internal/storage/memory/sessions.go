@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"sso/internal/domain/models"
+)
+
+// SaveSession records access token issued to user.
+func (s *Storage) SaveSession(_ context.Context, session models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.JTI] = session
+
+	return nil
+}
+
+// Sessions returns user's sessions whose tokens aren't revoked, newest
+// first. Expired sessions are included.
+func (s *Storage) Sessions(_ context.Context, userID int64) ([]models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var sessions []models.Session
+	for jti, session := range s.sessions {
+		if session.UserID != userID {
+			continue
+		}
+		if _, ok := s.revokedTokens[jti]; ok {
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].IssuedAt.After(sessions[j].IssuedAt)
+	})
+
+	return sessions, nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+type txKey struct{}
+
+// WithTx runs fn in a transaction: if fn returns error, everything stored
+// since WithTx started is rolled back. Transactions run one at a time, and
+// WithTx inside fn joins the outer one.
+//
+// Rollback restores a snapshot, so writes made outside of a transaction
+// while fn runs are dropped too. That's fine for tests and local runs the
+// storage is meant for.
+func (s *Storage) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.RLock()
+	snap := s.snapshot()
+	s.mu.RUnlock()
+
+	if err := fn(context.WithValue(ctx, txKey{}, struct{}{})); err != nil {
+		s.mu.Lock()
+		s.restore(snap)
+		s.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// snapshot copies stored data; s.mu must be held.
+func (s *Storage) snapshot() *Storage {
+	userRoles := make(map[int64]map[string]struct{}, len(s.userRoles))
+	for id, roles := range s.userRoles {
+		userRoles[id] = maps.Clone(roles)
+	}
+
+	return &Storage{
+		users:        maps.Clone(s.users),
+		userIDs:      maps.Clone(s.userIDs),
+		usernames:    maps.Clone(s.usernames),
+		lastUserID:   s.lastUserID,
+		roles:        maps.Clone(s.roles),
+		userRoles:    userRoles,
+		apps:         maps.Clone(s.apps),
+		appKeyOwners: maps.Clone(s.appKeyOwners),
+
+		refreshTokens:   maps.Clone(s.refreshTokens),
+		revokedTokens:   maps.Clone(s.revokedTokens),
+		sessions:        maps.Clone(s.sessions),
+		userTokens:      maps.Clone(s.userTokens),
+		idempotencyKeys: maps.Clone(s.idempotencyKeys),
+		auditLog:        slices.Clone(s.auditLog),
+	}
+}
+
+// restore replaces stored data with snapshot; s.mu must be held.
+func (s *Storage) restore(snap *Storage) {
+	s.users = snap.users
+	s.userIDs = snap.userIDs
+	s.usernames = snap.usernames
+	s.lastUserID = snap.lastUserID
+	s.roles = snap.roles
+	s.userRoles = snap.userRoles
+	s.apps = snap.apps
+	s.appKeyOwners = snap.appKeyOwners
+
+	s.refreshTokens = snap.refreshTokens
+	s.revokedTokens = snap.revokedTokens
+	s.sessions = snap.sessions
+	s.userTokens = snap.userTokens
+	s.idempotencyKeys = snap.idempotencyKeys
+	s.auditLog = snap.auditLog
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"sso/internal/storage"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// DefaultTable is name of table recording applied migration versions.
+const DefaultTable = "migrations"
+
+// Up applies ordered SQL migrations from migrationsPath to db at storagePath
+// (SQLite file or Postgres DSN, depending on driver). Applied versions are
+// recorded in table, so re-running is a no-op. Reports whether any migration
+// was applied.
+func Up(driver, storagePath, migrationsPath, table string) (bool, error) {
+	const op = "storage.migrate.Up"
+
+	dbURL, err := databaseURL(driver, storagePath, table)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := migrate.New("file://"+migrationsPath, dbURL)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func databaseURL(driver, storagePath, table string) (string, error) {
+	switch driver {
+	case storage.DriverSQLite, "":
+		return fmt.Sprintf("sqlite3://%s?x-migrations-table=%s", storagePath, table), nil
+	case storage.DriverPostgres:
+		u, err := url.Parse(storagePath)
+		if err != nil {
+			return "", fmt.Errorf("invalid postgres dsn: %w", err)
+		}
+
+		// golang-migrate pgx v5 driver is registered under pgx5 scheme.
+		u.Scheme = "pgx5"
+
+		q := u.Query()
+		q.Set("x-migrations-table", table)
+		u.RawQuery = q.Encode()
+
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
@@ -0,0 +1,66 @@
+package migrate
+
+import (
+	"database/sql"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"sso/internal/storage"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sso.db")
+
+	applied, err := Up(storage.DriverSQLite, path, "../../../migrations", "schema_versions")
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if !applied {
+		t.Error("Up on empty db applied nothing")
+	}
+
+	applied, err = Up(storage.DriverSQLite, path, "../../../migrations", "schema_versions")
+	if err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+	if applied {
+		t.Error("second Up applied migrations again")
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+
+	var version int
+	var dirty bool
+	if err := db.QueryRow("SELECT version, dirty FROM schema_versions").Scan(&version, &dirty); err != nil {
+		t.Fatalf("read schema_versions: %v", err)
+	}
+	if version == 0 || dirty {
+		t.Errorf("schema_versions = version %d, dirty %t; want clean non-zero version", version, dirty)
+	}
+}
+
+func TestDatabaseURL(t *testing.T) {
+	got, err := databaseURL(storage.DriverPostgres, "postgres://sso:secret@db:5432/sso?sslmode=disable", "versions")
+	if err != nil {
+		t.Fatalf("databaseURL: %v", err)
+	}
+
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", got, err)
+	}
+	if u.Scheme != "pgx5" || u.Query().Get("x-migrations-table") != "versions" || u.Query().Get("sslmode") != "disable" {
+		t.Errorf("databaseURL = %q, want pgx5 scheme keeping sslmode and setting table", got)
+	}
+
+	if _, err := databaseURL("mysql", "dsn", DefaultTable); err == nil {
+		t.Error("databaseURL of unknown driver succeeded, want error")
+	}
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveAppKey adds signing key to app. If key is active, other app keys are
+// deactivated in the same transaction; they keep verifying old tokens.
+func (s *Storage) SaveAppKey(ctx context.Context, appID int, key models.AppKey) error {
+	const op = "storage.postgres.SaveAppKey"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if key.Active {
+		if _, err := tx.Exec(ctx, "UPDATE app_keys SET active = FALSE WHERE app_id = $1", appID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO app_keys(kid, app_id, secret, active) VALUES($1, $2, $3, $4)",
+		key.ID, appID, key.Secret, key.Active,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RotateAppKey saves key as the only active app key. Other keys and legacy
+// secret keep verifying tokens until retireAt; earlier expiry is kept.
+func (s *Storage) RotateAppKey(ctx context.Context, appID int, key models.AppKey, retireAt time.Time) error {
+	const op = "storage.postgres.RotateAppKey"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE apps SET secret_expires_at = COALESCE(secret_expires_at, $1) WHERE id = $2",
+		retireAt, appID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE app_keys SET active = FALSE, expires_at = COALESCE(expires_at, $1) WHERE app_id = $2",
+		retireAt, appID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO app_keys(kid, app_id, secret, active) VALUES($1, $2, $3, TRUE)",
+		key.ID, appID, key.Secret,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteAppKey removes app key. Tokens signed with it stop verifying.
+func (s *Storage) DeleteAppKey(ctx context.Context, appID int, kid string) error {
+	const op = "storage.postgres.DeleteAppKey"
+
+	if _, err := s.conn(ctx).Exec(ctx, "DELETE FROM app_keys WHERE app_id = $1 AND kid = $2", appID, kid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetAppKeyPair replaces ES256 key pair of app.
+func (s *Storage) SetAppKeyPair(ctx context.Context, appID int, privateKey, publicKey string) error {
+	const op = "storage.postgres.SetAppKeyPair"
+
+	tag, err := s.conn(ctx).Exec(ctx,
+		"UPDATE apps SET private_key = $1, public_key = $2 WHERE id = $3",
+		privateKey, publicKey, appID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) appKeys(ctx context.Context, appID int) ([]models.AppKey, error) {
+	const op = "storage.postgres.appKeys"
+
+	rows, err := s.conn(ctx).Query(ctx,
+		"SELECT kid, secret, active, created_at, expires_at FROM app_keys WHERE app_id = $1 ORDER BY created_at, kid",
+		appID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AppKey, error) {
+		var (
+			k         models.AppKey
+			expiresAt *time.Time
+		)
+		err := row.Scan(&k.ID, &k.Secret, &k.Active, &k.CreatedAt, &expiresAt)
+		if expiresAt != nil {
+			k.ExpiresAt = *expiresAt
+		}
+
+		return k, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
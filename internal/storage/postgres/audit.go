@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"sso/internal/domain/models"
+)
+
+// SaveAuditEvent appends event to audit log.
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	const op = "storage.postgres.SaveAuditEvent"
+
+	var userID *int64
+	if event.UserID != 0 {
+		userID = &event.UserID
+	}
+
+	_, err := s.conn(ctx).Exec(ctx,
+		"INSERT INTO audit_log(type, user_id, email, source_ip, result, created_at) VALUES($1, $2, $3, $4, $5, $6)",
+		event.Type, userID, event.Email, event.SourceIP, event.Result, event.Time,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveIdempotencyKey saves idempotency key. An expired key with the same
+// value is replaced; if the key exists and is not expired, returns
+// storage.ErrIdempotencyKeyExists.
+func (s *Storage) SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	const op = "storage.postgres.SaveIdempotencyKey"
+
+	tag, err := s.conn(ctx).Exec(ctx, `
+		INSERT INTO idempotency_keys(key, email, user_id, expires_at) VALUES($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		    SET email = EXCLUDED.email, user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at
+		    WHERE idempotency_keys.expires_at <= $5`,
+		key.Key, key.Email, key.UserID, key.ExpiresAt, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyExists)
+	}
+
+	return nil
+}
+
+// IdempotencyKey returns idempotency key by its value.
+func (s *Storage) IdempotencyKey(ctx context.Context, key string) (models.IdempotencyKey, error) {
+	const op = "storage.postgres.IdempotencyKey"
+
+	var k models.IdempotencyKey
+
+	err := s.conn(ctx).QueryRow(ctx,
+		"SELECT key, email, user_id, expires_at FROM idempotency_keys WHERE key = $1",
+		key,
+	).Scan(&k.Key, &k.Email, &k.UserID, &k.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyNotFound)
+		}
+
+		return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return k, nil
+}
@@ -0,0 +1,209 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"sso/internal/storage/migrate"
+	"sso/internal/storage/postgres"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// newTestStorage returns storage over database in SSO_TEST_POSTGRES_DSN,
+// skipping the test when it isn't set. The database isn't cleaned between
+// runs, so tests use unique emails and app names.
+func newTestStorage(t *testing.T) *postgres.Storage {
+	t.Helper()
+
+	dsn := os.Getenv("SSO_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("SSO_TEST_POSTGRES_DSN is not set")
+	}
+
+	if _, err := migrate.Up(storage.DriverPostgres, dsn, "../../../migrations/postgres", migrate.DefaultTable); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	s, err := postgres.New(dsn, storage.PoolConfig{MaxOpenConns: 2})
+	if err != nil {
+		t.Fatalf("postgres.New: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Stop() })
+
+	return s
+}
+
+func unique(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+func TestSaveUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	email := unique("user") + "@example.com"
+
+	id, err := s.SaveUser(ctx, email, []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err := s.User(ctx, email)
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if user.ID != id || string(user.PassHash) != "hash" {
+		t.Errorf("User = %+v, want id %d with saved hash", user, id)
+	}
+
+	if _, err := s.SaveUser(ctx, email, []byte("hash")); !errors.Is(err, storage.ErrUserExists) {
+		t.Errorf("SaveUser of taken email: got %v, want ErrUserExists", err)
+	}
+	if _, err := s.User(ctx, unique("nobody")+"@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("User of unknown email: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestApp(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	name := unique("web")
+
+	id, err := s.SaveApp(ctx, name, name+"-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	app, err := s.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if app.Name != name || app.Secret != name+"-secret" || app.TokenTTL != 15*time.Minute {
+		t.Errorf("App = %+v, want %s with its secret and 15m ttl", app, name)
+	}
+
+	if _, err := s.SaveApp(ctx, name, "other-secret", 0); !errors.Is(err, storage.ErrAppExists) {
+		t.Errorf("SaveApp of taken name: got %v, want ErrAppExists", err)
+	}
+	if _, err := s.App(ctx, -1); !errors.Is(err, storage.ErrAppNotFound) {
+		t.Errorf("App of unknown id: got %v, want ErrAppNotFound", err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postgres.IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncrementTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	id, err := s.SaveUser(ctx, unique("user")+"@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.IncrementTokenVersion(ctx, id); err != nil {
+		t.Fatalf("IncrementTokenVersion: %v", err)
+	}
+
+	user, err := s.UserByID(ctx, id)
+	if err != nil {
+		t.Fatalf("UserByID: %v", err)
+	}
+	if user.TokenVersion != 1 {
+		t.Errorf("TokenVersion = %d, want 1", user.TokenVersion)
+	}
+
+	if err := s.IncrementTokenVersion(ctx, -1); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("IncrementTokenVersion of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	if _, err := s.SaveUser(ctx, unique("user")+"@example.com", []byte("hash")); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	users, err := s.ListUsers(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 1 || users[0].PassHash != nil {
+		t.Errorf("ListUsers(1, 0) = %+v, want one user without hash", users)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	id, err := s.SaveUser(ctx, unique("user")+"@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.DeleteUser(ctx, id); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if _, err := s.UserByID(ctx, id); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("UserByID: got %v, want ErrUserNotFound", err)
+	}
+	if err := s.DeleteUser(ctx, id); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("DeleteUser of deleted user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestSaveIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	userID, err := s.SaveUser(ctx, unique("user")+"@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	key := models.IdempotencyKey{Key: unique("key"), Email: "user@example.com", UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.SaveIdempotencyKey(ctx, key); err != nil {
+		t.Fatalf("SaveIdempotencyKey: %v", err)
+	}
+	if err := s.SaveIdempotencyKey(ctx, key); !errors.Is(err, storage.ErrIdempotencyKeyExists) {
+		t.Errorf("SaveIdempotencyKey of taken key: got %v, want ErrIdempotencyKeyExists", err)
+	}
+
+	expired := models.IdempotencyKey{Key: unique("key"), Email: "user@example.com", UserID: userID, ExpiresAt: time.Now().Add(-time.Second)}
+	if err := s.SaveIdempotencyKey(ctx, expired); err != nil {
+		t.Fatalf("SaveIdempotencyKey: %v", err)
+	}
+	expired.ExpiresAt = time.Now().Add(time.Hour)
+	if err := s.SaveIdempotencyKey(ctx, expired); err != nil {
+		t.Errorf("SaveIdempotencyKey over expired key: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// HasRole checks if user has role with given name.
+func (s *Storage) HasRole(ctx context.Context, userID int64, role string) (bool, error) {
+	const op = "storage.postgres.HasRole"
+
+	var userExists, hasRole bool
+
+	err := s.conn(ctx).QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = $1),
+		       EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN roles r ON r.id = ur.role_id
+		              WHERE ur.user_id = $1 AND r.name = $2)`,
+		userID, role,
+	).Scan(&userExists, &hasRole)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !userExists {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return hasRole, nil
+}
+
+// AnyUserHasRole reports whether at least one user has role.
+func (s *Storage) AnyUserHasRole(ctx context.Context, role string) (bool, error) {
+	const op = "storage.postgres.AnyUserHasRole"
+
+	var exists bool
+
+	err := s.conn(ctx).QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN roles r ON r.id = ur.role_id
+		              WHERE r.name = $1)`,
+		role,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
+}
+
+// HasRoleBatch checks role membership for several users at once.
+// Users that don't exist are absent from the result.
+func (s *Storage) HasRoleBatch(ctx context.Context, userIDs []int64, role string) (map[int64]bool, error) {
+	const op = "storage.postgres.HasRoleBatch"
+
+	res := make(map[int64]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, `
+		SELECT u.id,
+		       EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN roles r ON r.id = ur.role_id
+		              WHERE ur.user_id = u.id AND r.name = $1)
+		FROM users u
+		WHERE u.id = ANY($2)`,
+		role, userIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id      int64
+			hasRole bool
+		)
+		if err := rows.Scan(&id, &hasRole); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		res[id] = hasRole
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// HasPermission checks if any role of user grants permission.
+func (s *Storage) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	const op = "storage.postgres.HasPermission"
+
+	var userExists, allowed bool
+
+	err := s.conn(ctx).QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = $1),
+		       EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN role_permissions rp ON rp.role_id = ur.role_id
+		              WHERE ur.user_id = $1 AND rp.permission IN ($2, $3))`,
+		userID, permission, models.PermissionAll,
+	).Scan(&userExists, &allowed)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !userExists {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return allowed, nil
+}
+
+// AssignRole grants role with given name to user.
+func (s *Storage) AssignRole(ctx context.Context, userID int64, role string) error {
+	return assignRole(ctx, s.conn(ctx), "storage.postgres.AssignRole", userID, role)
+}
+
+// SaveUserWithRoles saves user and grants it roles in one transaction. If
+// some role doesn't exist, nothing is saved and ErrRoleNotFound is returned.
+func (s *Storage) SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (int64, error) {
+	const op = "storage.postgres.SaveUserWithRoles"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := saveUser(ctx, tx, op, email, "", passHash)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, role := range roles {
+		if err := assignRole(ctx, tx, op, id, role); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func assignRole(ctx context.Context, q querier, op string, userID int64, role string) error {
+	var roleID int
+
+	err := q.QueryRow(ctx, "SELECT id FROM roles WHERE name = $1", role).Scan(&roleID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = q.Exec(ctx,
+		"INSERT INTO user_roles(user_id, role_id) VALUES($1, $2) ON CONFLICT DO NOTHING",
+		userID, roleID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Role returns role with its permissions by name.
+func (s *Storage) Role(ctx context.Context, name string) (models.Role, error) {
+	const op = "storage.postgres.Role"
+
+	var role models.Role
+
+	err := s.conn(ctx).QueryRow(ctx, "SELECT id, name FROM roles WHERE name = $1", name).Scan(&role.ID, &role.Name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Role{}, fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.conn(ctx).Query(ctx, "SELECT permission FROM role_permissions WHERE role_id = $1", role.ID)
+	if err != nil {
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	role.Permissions, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return role, nil
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"sso/internal/domain/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveSession records access token issued to user.
+func (s *Storage) SaveSession(ctx context.Context, session models.Session) error {
+	const op = "storage.postgres.SaveSession"
+
+	_, err := s.conn(ctx).Exec(ctx,
+		"INSERT INTO sessions(jti, user_id, app_id, issued_at, expires_at, last_seen_ip) VALUES($1, $2, $3, $4, $5, $6)",
+		session.JTI, session.UserID, session.AppID, session.IssuedAt, session.ExpiresAt, session.LastSeenIP,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Sessions returns user's sessions whose tokens aren't revoked, newest
+// first. Expired sessions are included.
+func (s *Storage) Sessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	const op = "storage.postgres.Sessions"
+
+	rows, err := s.conn(ctx).Query(ctx, `
+		SELECT jti, user_id, app_id, issued_at, expires_at, last_seen_ip FROM sessions s
+		WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM revoked_tokens r WHERE r.jti = s.jti)
+		ORDER BY issued_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Session, error) {
+		var session models.Session
+		err := row.Scan(
+			&session.JTI, &session.UserID, &session.AppID,
+			&session.IssuedAt, &session.ExpiresAt, &session.LastSeenIP,
+		)
+
+		return session, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
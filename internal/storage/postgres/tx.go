@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type txKey struct{}
+
+// querier is *pgxpool.Pool or pgx.Tx, so helpers run inside a transaction
+// or not.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// txn is transaction started by begin. Joined one is committed or rolled
+// back by WithTx that started it, so Commit and Rollback do nothing.
+type txn struct {
+	pgx.Tx
+	joined bool
+}
+
+func (t txn) Commit(ctx context.Context) error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Commit(ctx)
+}
+
+func (t txn) Rollback(ctx context.Context) error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Rollback(ctx)
+}
+
+// WithTx runs fn in a transaction: storage calls made with ctx passed to fn
+// are committed if fn returns nil and rolled back otherwise. WithTx inside
+// fn joins the outer transaction.
+func (s *Storage) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "storage.postgres.WithTx"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx.Tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// begin starts transaction or joins the one ctx got from WithTx.
+func (s *Storage) begin(ctx context.Context) (txn, error) {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return txn{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return txn{}, err
+	}
+
+	return txn{Tx: tx}, nil
+}
+
+// conn returns transaction ctx got from WithTx, or pool outside of one.
+func (s *Storage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return s.db
+}
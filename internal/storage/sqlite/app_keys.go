@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SaveAppKey adds signing key to app. If key is active, other app keys are
+// deactivated in the same transaction; they keep verifying old tokens.
+func (s *Storage) SaveAppKey(ctx context.Context, appID int, key models.AppKey) error {
+	const op = "storage.sqlite.SaveAppKey"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if key.Active {
+		if _, err := tx.ExecContext(ctx, "UPDATE app_keys SET active = FALSE WHERE app_id = ?", appID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO app_keys(kid, app_id, secret, active) VALUES(?, ?, ?, ?)",
+		key.ID, appID, key.Secret, key.Active,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RotateAppKey saves key as the only active app key. Other keys and legacy
+// secret keep verifying tokens until retireAt; earlier expiry is kept.
+func (s *Storage) RotateAppKey(ctx context.Context, appID int, key models.AppKey, retireAt time.Time) error {
+	const op = "storage.sqlite.RotateAppKey"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE apps SET secret_expires_at = COALESCE(secret_expires_at, ?) WHERE id = ?",
+		retireAt, appID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE app_keys SET active = FALSE, expires_at = COALESCE(expires_at, ?) WHERE app_id = ?",
+		retireAt, appID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO app_keys(kid, app_id, secret, active) VALUES(?, ?, ?, TRUE)",
+		key.ID, appID, key.Secret,
+	)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppKeyExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteAppKey removes app key. Tokens signed with it stop verifying.
+func (s *Storage) DeleteAppKey(ctx context.Context, appID int, kid string) error {
+	const op = "storage.sqlite.DeleteAppKey"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "DELETE FROM app_keys WHERE app_id = ? AND kid = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, appID, kid); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetAppKeyPair replaces ES256 key pair of app.
+func (s *Storage) SetAppKeyPair(ctx context.Context, appID int, privateKey, publicKey string) error {
+	const op = "storage.sqlite.SetAppKeyPair"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "UPDATE apps SET private_key = ?, public_key = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, privateKey, publicKey, appID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) appKeys(ctx context.Context, appID int) ([]models.AppKey, error) {
+	const op = "storage.sqlite.appKeys"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx,
+		"SELECT kid, secret, active, created_at, expires_at FROM app_keys WHERE app_id = ? ORDER BY created_at, rowid",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.AppKey
+	for rows.Next() {
+		var (
+			k         models.AppKey
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&k.ID, &k.Secret, &k.Active, &k.CreatedAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		k.ExpiresAt = expiresAt.Time
+
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"sso/internal/domain/models"
+)
+
+// SaveAuditEvent appends event to audit log.
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	const op = "storage.sqlite.SaveAuditEvent"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "INSERT INTO audit_log(type, user_id, email, source_ip, result, created_at) VALUES(?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	userID := sql.NullInt64{Int64: event.UserID, Valid: event.UserID != 0}
+
+	_, err = stmt.ExecContext(ctx, event.Type, userID, event.Email, event.SourceIP, event.Result, event.Time)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
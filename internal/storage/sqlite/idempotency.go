@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+// SaveIdempotencyKey saves idempotency key. An expired key with the same
+// value is replaced; if the key exists and is not expired, returns
+// storage.ErrIdempotencyKeyExists.
+func (s *Storage) SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	const op = "storage.sqlite.SaveIdempotencyKey"
+
+	res, err := s.conn(ctx).ExecContext(ctx,
+		"INSERT INTO idempotency_keys(key, email, user_id, expires_at) VALUES(?, ?, ?, ?) ON CONFLICT(key) DO NOTHING",
+		key.Key, key.Email, key.UserID, key.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n > 0 {
+		return nil
+	}
+
+	old, err := s.IdempotencyKey(ctx, key.Key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if time.Now().Before(old.ExpiresAt) {
+		return fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyExists)
+	}
+
+	// Replace only the row we've seen expired: if another request replaced
+	// it meanwhile, user_id no longer matches and nothing is updated.
+	res, err = s.conn(ctx).ExecContext(ctx,
+		"UPDATE idempotency_keys SET email = ?, user_id = ?, expires_at = ? WHERE key = ? AND user_id = ?",
+		key.Email, key.UserID, key.ExpiresAt, key.Key, old.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyExists)
+	}
+
+	return nil
+}
+
+// IdempotencyKey returns idempotency key by its value.
+func (s *Storage) IdempotencyKey(ctx context.Context, key string) (models.IdempotencyKey, error) {
+	const op = "storage.sqlite.IdempotencyKey"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT key, email, user_id, expires_at FROM idempotency_keys WHERE key = ?")
+	if err != nil {
+		return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var k models.IdempotencyKey
+
+	err = stmt.QueryRowContext(ctx, key).Scan(&k.Key, &k.Email, &k.UserID, &k.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyNotFound)
+		}
+
+		return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return k, nil
+}
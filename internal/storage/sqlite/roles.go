@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+)
+
+// HasRole checks if user has role with given name.
+func (s *Storage) HasRole(ctx context.Context, userID int64, role string) (bool, error) {
+	const op = "storage.sqlite.HasRole"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = ?),
+		       EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN roles r ON r.id = ur.role_id
+		              WHERE ur.user_id = ? AND r.name = ?)`)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var userExists, hasRole bool
+
+	err = stmt.QueryRowContext(ctx, userID, userID, role).Scan(&userExists, &hasRole)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !userExists {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return hasRole, nil
+}
+
+// AnyUserHasRole reports whether at least one user has role.
+func (s *Storage) AnyUserHasRole(ctx context.Context, role string) (bool, error) {
+	const op = "storage.sqlite.AnyUserHasRole"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, `
+		SELECT EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN roles r ON r.id = ur.role_id
+		              WHERE r.name = ?)`)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var exists bool
+	if err := stmt.QueryRowContext(ctx, role).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
+}
+
+// HasRoleBatch checks role membership for several users at once.
+// Users that don't exist are absent from the result.
+func (s *Storage) HasRoleBatch(ctx context.Context, userIDs []int64, role string) (map[int64]bool, error) {
+	const op = "storage.sqlite.HasRoleBatch"
+
+	res := make(map[int64]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+
+	args := make([]interface{}, 0, len(userIDs)+1)
+	args = append(args, role)
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	placeholders := strings.Repeat("?, ", len(userIDs)-1) + "?"
+
+	rows, err := s.conn(ctx).QueryContext(ctx, `
+		SELECT u.id,
+		       EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN roles r ON r.id = ur.role_id
+		              WHERE ur.user_id = u.id AND r.name = ?)
+		FROM users u
+		WHERE u.id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id      int64
+			hasRole bool
+		)
+		if err := rows.Scan(&id, &hasRole); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		res[id] = hasRole
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// HasPermission checks if any role of user grants permission.
+func (s *Storage) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	const op = "storage.sqlite.HasPermission"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE id = ?),
+		       EXISTS(SELECT 1
+		              FROM user_roles ur
+		                       JOIN role_permissions rp ON rp.role_id = ur.role_id
+		              WHERE ur.user_id = ? AND rp.permission IN (?, ?))`)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	var userExists, allowed bool
+
+	err = stmt.QueryRowContext(ctx, userID, userID, permission, models.PermissionAll).Scan(&userExists, &allowed)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !userExists {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return allowed, nil
+}
+
+// AssignRole grants role with given name to user.
+func (s *Storage) AssignRole(ctx context.Context, userID int64, role string) error {
+	return assignRole(ctx, s.conn(ctx), "storage.sqlite.AssignRole", userID, role)
+}
+
+// SaveUserWithRoles saves user and grants it roles in one transaction. If
+// some role doesn't exist, nothing is saved and ErrRoleNotFound is returned.
+func (s *Storage) SaveUserWithRoles(ctx context.Context, email string, passHash []byte, roles []string) (int64, error) {
+	const op = "storage.sqlite.SaveUserWithRoles"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	id, err := saveUser(ctx, tx, op, email, "", passHash)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, role := range roles {
+		if err := assignRole(ctx, tx, op, id, role); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func assignRole(ctx context.Context, q querier, op string, userID int64, role string) error {
+	var roleID int
+
+	err := q.QueryRowContext(ctx, "SELECT id FROM roles WHERE name = ?", role).Scan(&roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := q.PrepareContext(ctx, "INSERT OR IGNORE INTO user_roles(user_id, role_id) VALUES(?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Role returns role with its permissions by name.
+func (s *Storage) Role(ctx context.Context, name string) (models.Role, error) {
+	const op = "storage.sqlite.Role"
+
+	var role models.Role
+
+	err := s.conn(ctx).QueryRowContext(ctx, "SELECT id, name FROM roles WHERE name = ?", name).Scan(&role.ID, &role.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Role{}, fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+		}
+
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.conn(ctx).QueryContext(ctx, "SELECT permission FROM role_permissions WHERE role_id = ?", role.ID)
+	if err != nil {
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return models.Role{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		role.Permissions = append(role.Permissions, permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.Role{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return role, nil
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"sso/internal/domain/models"
+)
+
+// SaveSession records access token issued to user.
+func (s *Storage) SaveSession(ctx context.Context, session models.Session) error {
+	const op = "storage.sqlite.SaveSession"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "INSERT INTO sessions(jti, user_id, app_id, issued_at, expires_at, last_seen_ip) VALUES(?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, session.JTI, session.UserID, session.AppID, session.IssuedAt, session.ExpiresAt, session.LastSeenIP)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Sessions returns user's sessions whose tokens aren't revoked, newest
+// first. Expired sessions are included.
+func (s *Storage) Sessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	const op = "storage.sqlite.Sessions"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, `
+		SELECT jti, user_id, app_id, issued_at, expires_at, last_seen_ip FROM sessions
+		WHERE user_id = ? AND jti NOT IN (SELECT jti FROM revoked_tokens)
+		ORDER BY issued_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(
+			&session.JTI, &session.UserID, &session.AppID,
+			&session.IssuedAt, &session.ExpiresAt, &session.LastSeenIP,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"sso/internal/domain/models"
 	"sso/internal/storage"
@@ -16,7 +18,7 @@ type Storage struct {
 	db *sql.DB
 }
 
-func New(storagePath string) (*Storage, error) {
+func New(storagePath string, pool storage.PoolConfig) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
 	db, err := sql.Open("sqlite3", storagePath)
@@ -24,26 +26,62 @@ func New(storagePath string) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+
 	return &Storage{db: db}, nil
 }
 
+// Ping checks that database is reachable.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsTransient reports whether err is worth retrying: database is busy or
+// locked by another connection.
+func IsTransient(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
 func (s *Storage) Stop() error {
 	return s.db.Close()
 }
 
 // SaveUser saves user to db.
 func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (int64, error) {
-	const op = "storage.sqlite.SaveUser"
+	return saveUser(ctx, s.conn(ctx), "storage.sqlite.SaveUser", email, "", passHash)
+}
+
+// SaveUserWithUsername saves user with username to db.
+func (s *Storage) SaveUserWithUsername(ctx context.Context, email, username string, passHash []byte) (int64, error) {
+	return saveUser(ctx, s.conn(ctx), "storage.sqlite.SaveUserWithUsername", email, username, passHash)
+}
 
-	stmt, err := s.db.Prepare("INSERT INTO users(email, pass_hash) VALUES(?, ?)")
+func saveUser(ctx context.Context, q querier, op, email, username string, passHash []byte) (int64, error) {
+	stmt, err := q.PrepareContext(ctx, "INSERT INTO users(email, username, pass_hash) VALUES(?, NULLIF(?, ''), ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.ExecContext(ctx, email, passHash)
+	res, err := stmt.ExecContext(ctx, email, username, passHash)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			if strings.Contains(sqliteErr.Error(), "users.username") {
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrUsernameTaken)
+			}
+
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
 		}
 
@@ -62,15 +100,16 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	const op = "storage.sqlite.User"
 
-	stmt, err := s.db.Prepare("SELECT id, email, pass_hash FROM users WHERE email = ?")
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT id, email, COALESCE(username, ''), pass_hash, email_verified, token_version FROM users WHERE email = ?")
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
 	row := stmt.QueryRowContext(ctx, email)
 
 	var user models.User
-	err = row.Scan(&user.ID, &user.Email, &user.PassHash)
+	err = row.Scan(&user.ID, &user.Email, &user.Username, &user.PassHash, &user.EmailVerified, &user.TokenVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -82,10 +121,185 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 	return user, nil
 }
 
+// UserByID returns user by id.
+func (s *Storage) UserByID(ctx context.Context, userID int64) (models.User, error) {
+	const op = "storage.sqlite.UserByID"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT id, email, COALESCE(username, ''), pass_hash, email_verified, token_version FROM users WHERE id = ?")
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, userID)
+
+	var user models.User
+	err = row.Scan(&user.ID, &user.Email, &user.Username, &user.PassHash, &user.EmailVerified, &user.TokenVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// UserByUsername returns user by username.
+func (s *Storage) UserByUsername(ctx context.Context, username string) (models.User, error) {
+	const op = "storage.sqlite.UserByUsername"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT id, email, username, pass_hash, email_verified, token_version FROM users WHERE username = ?")
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, username)
+
+	var user models.User
+	err = row.Scan(&user.ID, &user.Email, &user.Username, &user.PassHash, &user.EmailVerified, &user.TokenVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// ListUsers returns users ordered by id. Password hashes are not selected.
+func (s *Storage) ListUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	const op = "storage.sqlite.ListUsers"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT id, email, COALESCE(username, ''), email_verified FROM users ORDER BY id LIMIT ? OFFSET ?")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.EmailVerified); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+// UpdatePasswordHash replaces password hash of user.
+func (s *Storage) UpdatePasswordHash(ctx context.Context, userID int64, passHash []byte) error {
+	const op = "storage.sqlite.UpdatePasswordHash"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "UPDATE users SET pass_hash = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, passHash, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// IncrementTokenVersion increments token version of user.
+func (s *Storage) IncrementTokenVersion(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.IncrementTokenVersion"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "UPDATE users SET token_version = token_version + 1 WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes user and rows referencing it. Foreign keys aren't
+// enforced by SQLite by default, so dependent rows are deleted explicitly.
+func (s *Storage) DeleteUser(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.DeleteUser"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"refresh_tokens", "sessions", "user_tokens", "user_roles", "idempotency_keys"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = ?", userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 //func (s *Storage) SavePermission(ctx context.Context, userID int64, permission models.Permission, appID string) error {
 //	const op = "storage.sqlite.SavePermission"
 //
-//	stmt, err := s.db.Prepare("INSERT INTO permissions(user_id, permission, app_id) VALUES(?, ?, ?)")
+//	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO permissions(user_id, permission, app_id) VALUES(?, ?, ?)")
 //	if err != nil {
 //		return fmt.Errorf("%s: %w", op, err)
 //	}
@@ -98,19 +312,78 @@ func (s *Storage) User(ctx context.Context, email string) (models.User, error) {
 //	return nil
 //}
 
-// App returns app by id.
+// SaveApp saves app to db; zero tokenTTL means no token TTL override.
+func (s *Storage) SaveApp(ctx context.Context, name string, secret string, tokenTTL time.Duration) (int, error) {
+	const op = "storage.sqlite.SaveApp"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "INSERT INTO apps(name, secret, token_ttl_seconds) VALUES(?, ?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, name, secret, int64(tokenTTL/time.Second))
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(id), nil
+}
+
+// UpsertApp creates app with given id or updates its name, secret and token
+// TTL.
+func (s *Storage) UpsertApp(ctx context.Context, app models.App) error {
+	const op = "storage.sqlite.UpsertApp"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, `
+		INSERT INTO apps(id, name, secret, token_ttl_seconds) VALUES(?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, secret = excluded.secret,
+			token_ttl_seconds = excluded.token_ttl_seconds`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, app.ID, app.Name, app.Secret, int64(app.TokenTTL/time.Second)); err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// App returns app by id together with its signing keys.
 func (s *Storage) App(ctx context.Context, id int) (models.App, error) {
 	const op = "storage.sqlite.App"
 
-	stmt, err := s.db.Prepare("SELECT id, name, secret FROM apps WHERE id = ?")
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT id, name, secret, secret_expires_at, token_ttl_seconds, private_key, public_key FROM apps WHERE id = ?")
 	if err != nil {
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
 	row := stmt.QueryRowContext(ctx, id)
 
-	var app models.App
-	err = row.Scan(&app.ID, &app.Name, &app.Secret)
+	var (
+		app             models.App
+		secretExpiresAt sql.NullTime
+		ttlSeconds      int64
+	)
+	err = row.Scan(&app.ID, &app.Name, &app.Secret, &secretExpiresAt, &ttlSeconds, &app.PrivateKey, &app.PublicKey)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
@@ -119,29 +392,215 @@ func (s *Storage) App(ctx context.Context, id int) (models.App, error) {
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	app.TokenTTL = time.Duration(ttlSeconds) * time.Second
+	app.SecretExpiresAt = secretExpiresAt.Time
+
+	app.Keys, err = s.appKeys(ctx, app.ID)
+	if err != nil {
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return app, nil
 }
 
+// IsAdmin checks if user has admin role.
 func (s *Storage) IsAdmin(ctx context.Context, userID int64) (bool, error) {
-	const op = "storage.sqlite.IsAdmin"
+	return s.HasRole(ctx, userID, models.RoleAdmin)
+}
 
-	stmt, err := s.db.Prepare("SELECT is_admin FROM users WHERE id = ?")
+// SaveRefreshToken saves refresh token to db.
+func (s *Storage) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	const op = "storage.sqlite.SaveRefreshToken"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "INSERT INTO refresh_tokens(token, user_id, app_id, expires_at) VALUES(?, ?, ?, ?)")
 	if err != nil {
-		return false, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, userID)
+	_, err = stmt.ExecContext(ctx, token.Token, token.UserID, token.AppID, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
 
-	var isAdmin bool
+// RefreshToken returns refresh token by its value.
+func (s *Storage) RefreshToken(ctx context.Context, token string) (models.RefreshToken, error) {
+	const op = "storage.sqlite.RefreshToken"
 
-	err = row.Scan(&isAdmin)
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT token, user_id, app_id, expires_at, revoked FROM refresh_tokens WHERE token = ?")
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, token)
+
+	var rt models.RefreshToken
+	err = row.Scan(&rt.Token, &rt.UserID, &rt.AppID, &rt.ExpiresAt, &rt.Revoked)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenNotFound)
 		}
 
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rt, nil
+}
+
+// RevokeRefreshTokens marks all refresh tokens of user as revoked.
+func (s *Storage) RevokeRefreshTokens(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.RevokeRefreshTokens"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeToken marks token with given jti as revoked.
+func (s *Storage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "storage.sqlite.RevokeToken"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "INSERT OR IGNORE INTO revoked_tokens(jti, expires_at) VALUES(?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsRevoked checks if token with given jti is revoked.
+func (s *Storage) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "storage.sqlite.IsRevoked"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)")
+	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
+
+	var revoked bool
+
+	err = stmt.QueryRowContext(ctx, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+// SaveUserToken saves one-time user token to db.
+func (s *Storage) SaveUserToken(ctx context.Context, token models.UserToken) error {
+	const op = "storage.sqlite.SaveUserToken"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "INSERT INTO user_tokens(token, user_id, purpose, expires_at) VALUES(?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, token.Token, token.UserID, token.Purpose, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserToken returns one-time user token by its value and purpose.
+func (s *Storage) UserToken(ctx context.Context, token string, purpose string) (models.UserToken, error) {
+	const op = "storage.sqlite.UserToken"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "SELECT token, user_id, purpose, expires_at FROM user_tokens WHERE token = ? AND purpose = ?")
+	if err != nil {
+		return models.UserToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, token, purpose)
+
+	var ut models.UserToken
+	err = row.Scan(&ut.Token, &ut.UserID, &ut.Purpose, &ut.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserToken{}, fmt.Errorf("%s: %w", op, storage.ErrUserTokenNotFound)
+		}
+
+		return models.UserToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ut, nil
+}
+
+// DeleteUserToken deletes one-time user token. If it's already gone,
+// returns ErrUserTokenNotFound.
+func (s *Storage) DeleteUserToken(ctx context.Context, token string) error {
+	const op = "storage.sqlite.DeleteUserToken"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "DELETE FROM user_tokens WHERE token = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserTokenNotFound)
+	}
+
+	return nil
+}
+
+// SetEmailVerified marks user email as verified.
+func (s *Storage) SetEmailVerified(ctx context.Context, userID int64) error {
+	const op = "storage.sqlite.SetEmailVerified"
+
+	stmt, err := s.conn(ctx).PrepareContext(ctx, "UPDATE users SET email_verified = TRUE WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
 
-	return isAdmin, nil
+	return nil
 }
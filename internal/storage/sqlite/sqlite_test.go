@@ -0,0 +1,631 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sso/internal/domain/models"
+	"sso/internal/storage"
+	"sso/internal/storage/migrate"
+	"sso/internal/storage/sqlite"
+)
+
+// newTestStorage returns storage backed by a fresh migrated db file.
+func newTestStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sso.db")
+
+	if _, err := migrate.Up(storage.DriverSQLite, path, "../../../migrations", migrate.DefaultTable); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	s, err := sqlite.New(path, storage.PoolConfig{MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Stop() })
+
+	return s
+}
+
+func TestSaveAppTokenTTL(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	withTTL, err := s.SaveApp(ctx, "mobile", "mobile-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	withoutTTL, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	for _, tc := range []struct {
+		id   int
+		want time.Duration
+	}{{withTTL, 15 * time.Minute}, {withoutTTL, 0}} {
+		app, err := s.App(ctx, tc.id)
+		if err != nil {
+			t.Fatalf("App(%d): %v", tc.id, err)
+		}
+		if app.TokenTTL != tc.want {
+			t.Errorf("App(%d).TokenTTL = %s, want %s", tc.id, app.TokenTTL, tc.want)
+		}
+	}
+}
+
+func TestUpsertAppTokenTTL(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	app := models.App{ID: 7, Name: "web", Secret: "secret", TokenTTL: 10 * time.Minute}
+	if err := s.UpsertApp(ctx, app); err != nil {
+		t.Fatalf("UpsertApp: %v", err)
+	}
+
+	got, err := s.App(ctx, app.ID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if got.TokenTTL != 10*time.Minute {
+		t.Errorf("TokenTTL after insert = %s, want 10m", got.TokenTTL)
+	}
+
+	// Dropping override from config must clear it on the next start.
+	app.TokenTTL = 0
+	if err := s.UpsertApp(ctx, app); err != nil {
+		t.Fatalf("UpsertApp: %v", err)
+	}
+
+	got, err = s.App(ctx, app.ID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if got.TokenTTL != 0 {
+		t.Errorf("TokenTTL after update = %s, want 0", got.TokenTTL)
+	}
+}
+
+func TestSaveUserWithRolesUnknownRoleSavesNothing(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	_, err := s.SaveUserWithRoles(ctx, "user@example.com", []byte("hash"), []string{models.RoleAdmin, "no-such-role"})
+	if !errors.Is(err, storage.ErrRoleNotFound) {
+		t.Fatalf("SaveUserWithRoles: got %v, want ErrRoleNotFound", err)
+	}
+
+	if _, err := s.User(ctx, "user@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Fatalf("User after failed SaveUserWithRoles: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestSaveUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	id, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err := s.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if user.ID != id || string(user.PassHash) != "hash" {
+		t.Errorf("User = %+v, want id %d and hash %q", user, id, "hash")
+	}
+
+	if _, err := s.SaveUser(ctx, "user@example.com", []byte("other")); !errors.Is(err, storage.ErrUserExists) {
+		t.Errorf("SaveUser with taken email: got %v, want ErrUserExists", err)
+	}
+	if _, err := s.User(ctx, "nobody@example.com"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("User of unknown email: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestApp(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	id, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	app, err := s.App(ctx, id)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if app.Name != "web" || app.Secret != "web-secret" {
+		t.Errorf("App = %+v, want web with its secret", app)
+	}
+
+	if _, err := s.App(ctx, id+1); !errors.Is(err, storage.ErrAppNotFound) {
+		t.Errorf("App of unknown id: got %v, want ErrAppNotFound", err)
+	}
+}
+
+func TestSaveAppKey(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	appID, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	for _, key := range []models.AppKey{
+		{ID: "k1", Secret: "k1-secret", Active: true},
+		{ID: "k2", Secret: "k2-secret", Active: true},
+	} {
+		if err := s.SaveAppKey(ctx, appID, key); err != nil {
+			t.Fatalf("SaveAppKey(%s): %v", key.ID, err)
+		}
+	}
+
+	if err := s.SaveAppKey(ctx, appID, models.AppKey{ID: "k1", Secret: "other"}); !errors.Is(err, storage.ErrAppKeyExists) {
+		t.Errorf("SaveAppKey with taken kid: got %v, want ErrAppKeyExists", err)
+	}
+
+	app, err := s.App(ctx, appID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	if len(app.Keys) != 2 {
+		t.Fatalf("app has %d keys, want 2", len(app.Keys))
+	}
+
+	// Saving active key deactivates the others, which stay for verification.
+	active, ok := app.ActiveKey()
+	if !ok || active.ID != "k2" {
+		t.Errorf("active key = %q, %t; want k2", active.ID, ok)
+	}
+	if k1, ok := app.Key("k1"); !ok || k1.Active || k1.Secret != "k1-secret" {
+		t.Errorf("key k1 = %+v, %t; want inactive with its secret", k1, ok)
+	}
+}
+
+func TestRotateAppKey(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	appID, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	first := time.Now().Add(time.Hour).Truncate(time.Second)
+	second := first.Add(time.Hour)
+
+	if err := s.RotateAppKey(ctx, appID, models.AppKey{ID: "k1", Secret: "k1-secret"}, first); err != nil {
+		t.Fatalf("RotateAppKey(k1): %v", err)
+	}
+	if err := s.RotateAppKey(ctx, appID, models.AppKey{ID: "k2", Secret: "k2-secret"}, second); err != nil {
+		t.Fatalf("RotateAppKey(k2): %v", err)
+	}
+
+	app, err := s.App(ctx, appID)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+
+	// Legacy secret keeps the expiry of the first rotation.
+	if !app.SecretExpiresAt.Equal(first) {
+		t.Errorf("secret expires at %s, want %s", app.SecretExpiresAt, first)
+	}
+	if active, ok := app.ActiveKey(); !ok || active.ID != "k2" || !active.ExpiresAt.IsZero() {
+		t.Errorf("active key = %+v, %t; want k2 without expiry", active, ok)
+	}
+	if k1, ok := app.Key("k1"); !ok || k1.Active || !k1.ExpiresAt.Equal(second) {
+		t.Errorf("key k1 = %+v, %t; want inactive expiring at %s", k1, ok, second)
+	}
+
+	if err := s.RotateAppKey(ctx, appID+1, models.AppKey{ID: "k3", Secret: "k3-secret"}, first); !errors.Is(err, storage.ErrAppNotFound) {
+		t.Errorf("RotateAppKey of unknown app: got %v, want ErrAppNotFound", err)
+	}
+}
+
+func TestIncrementTokenVersion(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	id, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.IncrementTokenVersion(ctx, id); err != nil {
+			t.Fatalf("IncrementTokenVersion: %v", err)
+		}
+	}
+
+	user, err := s.UserByID(ctx, id)
+	if err != nil {
+		t.Fatalf("UserByID: %v", err)
+	}
+	if user.TokenVersion != 2 {
+		t.Errorf("TokenVersion = %d, want 2", user.TokenVersion)
+	}
+
+	if err := s.IncrementTokenVersion(ctx, id+1); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("IncrementTokenVersion of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		// Revoking twice is not an error.
+		if err := s.RevokeToken(ctx, "jti-1", expiresAt); err != nil {
+			t.Fatalf("RevokeToken %d: %v", i, err)
+		}
+	}
+
+	for jti, want := range map[string]bool{"jti-1": true, "jti-2": false} {
+		got, err := s.IsRevoked(ctx, jti)
+		if err != nil {
+			t.Fatalf("IsRevoked(%q): %v", jti, err)
+		}
+		if got != want {
+			t.Errorf("IsRevoked(%q) = %t, want %t", jti, got, want)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	userID, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	appID, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for i, jti := range []string{"older", "newer", "revoked"} {
+		session := models.Session{
+			JTI:        jti,
+			UserID:     userID,
+			AppID:      appID,
+			IssuedAt:   now.Add(time.Duration(i) * time.Minute),
+			ExpiresAt:  now.Add(time.Hour),
+			LastSeenIP: "10.0.0.1",
+		}
+		if err := s.SaveSession(ctx, session); err != nil {
+			t.Fatalf("SaveSession(%s): %v", jti, err)
+		}
+	}
+	if err := s.RevokeToken(ctx, "revoked", now.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+
+	sessions, err := s.Sessions(ctx, userID)
+	if err != nil {
+		t.Fatalf("Sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].JTI != "newer" || sessions[1].JTI != "older" {
+		t.Fatalf("Sessions = %+v, want newer and older, newest first", sessions)
+	}
+	if got := sessions[0]; got.AppID != appID || got.LastSeenIP != "10.0.0.1" || !got.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("session = %+v, want saved fields", got)
+	}
+
+	if others, err := s.Sessions(ctx, userID+1); err != nil || len(others) != 0 {
+		t.Errorf("Sessions of other user = %v, %v; want none", others, err)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	var ids []int64
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		id, err := s.SaveUser(ctx, email, []byte("hash"))
+		if err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	tests := []struct {
+		limit, offset int
+		want          []int64
+	}{
+		{2, 0, ids[:2]},
+		{2, 2, ids[2:]},
+		{10, 0, ids},
+		{2, 3, nil},
+	}
+	for _, tt := range tests {
+		users, err := s.ListUsers(ctx, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("ListUsers(%d, %d): %v", tt.limit, tt.offset, err)
+		}
+		if len(users) != len(tt.want) {
+			t.Fatalf("ListUsers(%d, %d) returned %d users, want %d", tt.limit, tt.offset, len(users), len(tt.want))
+		}
+		for i, user := range users {
+			if user.ID != tt.want[i] || user.PassHash != nil {
+				t.Errorf("ListUsers(%d, %d)[%d] = id %d, hash %q, want id %d, no hash",
+					tt.limit, tt.offset, i, user.ID, user.PassHash, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	userID, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.AssignRole(ctx, userID, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	appID, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	expires := time.Now().Add(time.Hour)
+	if err := s.SaveSession(ctx, models.Session{JTI: "jti", UserID: userID, AppID: appID, IssuedAt: time.Now(), ExpiresAt: expires}); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+	if err := s.SaveIdempotencyKey(ctx, models.IdempotencyKey{Key: "key", Email: "user@example.com", UserID: userID, ExpiresAt: expires}); err != nil {
+		t.Fatalf("SaveIdempotencyKey: %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, userID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	if _, err := s.UserByID(ctx, userID); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("UserByID: got %v, want ErrUserNotFound", err)
+	}
+	if sessions, err := s.Sessions(ctx, userID); err != nil || len(sessions) != 0 {
+		t.Errorf("Sessions = %v, %v; want none", sessions, err)
+	}
+	if _, err := s.IdempotencyKey(ctx, "key"); !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+		t.Errorf("IdempotencyKey: got %v, want ErrIdempotencyKeyNotFound", err)
+	}
+	if _, err := s.SaveUser(ctx, "user@example.com", []byte("hash")); err != nil {
+		t.Errorf("SaveUser with email of deleted user: %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, userID+100); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("DeleteUser of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestHasRoleBatch(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	user, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	admin, err := s.SaveUser(ctx, "admin@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	got, err := s.HasRoleBatch(ctx, []int64{user, admin, admin + 1}, models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("HasRoleBatch: %v", err)
+	}
+	if len(got) != 2 || got[user] || !got[admin] {
+		t.Errorf("HasRoleBatch = %v, want %d: false, %d: true", got, user, admin)
+	}
+}
+
+func TestHasPermission(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	user, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	admin, err := s.SaveUser(ctx, "admin@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.AssignRole(ctx, admin, models.RoleAdmin); err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+
+	role, err := s.Role(ctx, models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Role: %v", err)
+	}
+	if len(role.Permissions) != 1 || role.Permissions[0] != models.PermissionAll {
+		t.Errorf("admin permissions = %v, want [%s]", role.Permissions, models.PermissionAll)
+	}
+	if _, err := s.Role(ctx, "nope"); !errors.Is(err, storage.ErrRoleNotFound) {
+		t.Errorf("Role of unknown role: got %v, want ErrRoleNotFound", err)
+	}
+
+	for id, want := range map[int64]bool{user: false, admin: true} {
+		got, err := s.HasPermission(ctx, id, "users.delete")
+		if err != nil {
+			t.Fatalf("HasPermission(%d): %v", id, err)
+		}
+		if got != want {
+			t.Errorf("HasPermission(%d) = %t, want %t", id, got, want)
+		}
+	}
+	if _, err := s.HasPermission(ctx, admin+1, "users.delete"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("HasPermission of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestUserTokens(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	userID, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	want := models.UserToken{
+		Token:     "token",
+		UserID:    userID,
+		Purpose:   models.TokenPurposePasswordReset,
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	if err := s.SaveUserToken(ctx, want); err != nil {
+		t.Fatalf("SaveUserToken: %v", err)
+	}
+
+	got, err := s.UserToken(ctx, "token", models.TokenPurposePasswordReset)
+	if err != nil {
+		t.Fatalf("UserToken: %v", err)
+	}
+	if got.UserID != want.UserID || !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("UserToken = %+v, want %+v", got, want)
+	}
+	if _, err := s.UserToken(ctx, "token", "other"); !errors.Is(err, storage.ErrUserTokenNotFound) {
+		t.Errorf("UserToken with other purpose: got %v, want ErrUserTokenNotFound", err)
+	}
+
+	if err := s.DeleteUserToken(ctx, "token"); err != nil {
+		t.Fatalf("DeleteUserToken: %v", err)
+	}
+	if _, err := s.UserToken(ctx, "token", models.TokenPurposePasswordReset); !errors.Is(err, storage.ErrUserTokenNotFound) {
+		t.Errorf("UserToken after delete: got %v, want ErrUserTokenNotFound", err)
+	}
+	if err := s.DeleteUserToken(ctx, "token"); !errors.Is(err, storage.ErrUserTokenNotFound) {
+		t.Errorf("DeleteUserToken twice: got %v, want ErrUserTokenNotFound", err)
+	}
+}
+
+func TestSetEmailVerified(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	userID, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.SetEmailVerified(ctx, userID); err != nil {
+		t.Fatalf("SetEmailVerified: %v", err)
+	}
+
+	user, err := s.User(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if !user.EmailVerified {
+		t.Error("EmailVerified = false after SetEmailVerified")
+	}
+
+	if err := s.SetEmailVerified(ctx, userID+1); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("SetEmailVerified of unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	userID, err := s.SaveUser(ctx, "user@example.com", []byte("hash"))
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	appID, err := s.SaveApp(ctx, "web", "web-secret", 0)
+	if err != nil {
+		t.Fatalf("SaveApp: %v", err)
+	}
+	want := models.RefreshToken{
+		Token:     "token",
+		UserID:    userID,
+		AppID:     appID,
+		ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+	if err := s.SaveRefreshToken(ctx, want); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+
+	got, err := s.RefreshToken(ctx, "token")
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if got.UserID != want.UserID || got.AppID != want.AppID || !got.ExpiresAt.Equal(want.ExpiresAt) || got.Revoked {
+		t.Errorf("RefreshToken = %+v, want %+v", got, want)
+	}
+	if _, err := s.RefreshToken(ctx, "other"); !errors.Is(err, storage.ErrRefreshTokenNotFound) {
+		t.Errorf("RefreshToken of unknown token: got %v, want ErrRefreshTokenNotFound", err)
+	}
+
+	if err := s.RevokeRefreshTokens(ctx, userID); err != nil {
+		t.Fatalf("RevokeRefreshTokens: %v", err)
+	}
+	if got, err := s.RefreshToken(ctx, "token"); err != nil || !got.Revoked {
+		t.Errorf("RefreshToken after revoke = %+v, %v; want revoked", got, err)
+	}
+}
+
+func TestSaveIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	key := models.IdempotencyKey{Key: "key-1", Email: "user@example.com", UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.SaveIdempotencyKey(ctx, key); err != nil {
+		t.Fatalf("SaveIdempotencyKey: %v", err)
+	}
+
+	other := models.IdempotencyKey{Key: "key-1", Email: "other@example.com", UserID: 2, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.SaveIdempotencyKey(ctx, other); !errors.Is(err, storage.ErrIdempotencyKeyExists) {
+		t.Errorf("SaveIdempotencyKey of taken key: got %v, want ErrIdempotencyKeyExists", err)
+	}
+	if got, err := s.IdempotencyKey(ctx, "key-1"); err != nil || got.UserID != key.UserID {
+		t.Errorf("IdempotencyKey = %+v, %v; want user %d", got, err, key.UserID)
+	}
+
+	expired := models.IdempotencyKey{Key: "key-2", Email: "user@example.com", UserID: 1, ExpiresAt: time.Now().Add(-time.Second)}
+	if err := s.SaveIdempotencyKey(ctx, expired); err != nil {
+		t.Fatalf("SaveIdempotencyKey: %v", err)
+	}
+	other.Key = "key-2"
+	if err := s.SaveIdempotencyKey(ctx, other); err != nil {
+		t.Errorf("SaveIdempotencyKey over expired key: %v", err)
+	}
+	if got, err := s.IdempotencyKey(ctx, "key-2"); err != nil || got.UserID != other.UserID || got.Email != other.Email {
+		t.Errorf("IdempotencyKey = %+v, %v; want %+v", got, err, other)
+	}
+
+	errFail := errors.New("fail")
+	err := s.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.SaveIdempotencyKey(ctx, models.IdempotencyKey{Key: "key-3", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			return err
+		}
+
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("WithTx: got %v, want errFail", err)
+	}
+	if _, err := s.IdempotencyKey(ctx, "key-3"); !errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+		t.Errorf("IdempotencyKey after rollback: got %v, want ErrIdempotencyKeyNotFound", err)
+	}
+}
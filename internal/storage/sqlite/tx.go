@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type txKey struct{}
+
+// querier is *sql.DB or *sql.Tx, so helpers run inside a transaction or not.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txn is transaction started by begin. Joined one is committed or rolled
+// back by WithTx that started it, so Commit and Rollback do nothing.
+type txn struct {
+	*sql.Tx
+	joined bool
+}
+
+func (t txn) Commit() error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Commit()
+}
+
+func (t txn) Rollback() error {
+	if t.joined {
+		return nil
+	}
+
+	return t.Tx.Rollback()
+}
+
+// WithTx runs fn in a transaction: storage calls made with ctx passed to fn
+// are committed if fn returns nil and rolled back otherwise. WithTx inside
+// fn joins the outer transaction.
+func (s *Storage) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "storage.sqlite.WithTx"
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx.Tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// begin starts transaction or joins the one ctx got from WithTx.
+func (s *Storage) begin(ctx context.Context) (txn, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return txn{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return txn{}, err
+	}
+
+	return txn{Tx: tx}, nil
+}
+
+// conn returns transaction ctx got from WithTx, or db outside of one.
+func (s *Storage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return s.db
+}
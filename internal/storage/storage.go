@@ -1,9 +1,37 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrUserExists   = errors.New("user already exists")
-	ErrUserNotFound = errors.New("not found")
-	ErrAppNotFound  = errors.New("app not found")
+	ErrUserExists    = errors.New("user already exists")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrUserNotFound  = errors.New("not found")
+	ErrAppNotFound   = errors.New("app not found")
+	ErrAppExists     = errors.New("app already exists")
+	ErrAppKeyExists  = errors.New("app key already exists")
+	ErrRoleNotFound  = errors.New("role not found")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrUserTokenNotFound    = errors.New("user token not found")
+
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already exists")
 )
+
+// Supported storage drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	// DriverMemory keeps data in process memory, for tests and local runs.
+	DriverMemory = "memory"
+)
+
+// PoolConfig tunes database connection pool. Zero values mean no limit.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
@@ -0,0 +1,2 @@
+ALTER TABLE apps DROP COLUMN public_key;
+ALTER TABLE apps DROP COLUMN private_key;
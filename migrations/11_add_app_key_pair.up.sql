@@ -0,0 +1,4 @@
+ALTER TABLE apps
+    ADD COLUMN private_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE apps
+    ADD COLUMN public_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE app_keys
+    ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE apps
+    ADD COLUMN secret_expires_at TIMESTAMP;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS sessions
+(
+    jti          TEXT PRIMARY KEY,
+    user_id      INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id       INTEGER   NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    issued_at    TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP NOT NULL,
+    last_seen_ip TEXT      NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti        TEXT PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
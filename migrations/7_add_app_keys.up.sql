@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS app_keys
+(
+    kid        TEXT PRIMARY KEY,
+    app_id     INTEGER   NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    secret     TEXT      NOT NULL,
+    active     BOOLEAN   NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_app_keys_app_id ON app_keys (app_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         INTEGER PRIMARY KEY,
+    type       TEXT      NOT NULL,
+    user_id    INTEGER,
+    email      TEXT      NOT NULL,
+    source_ip  TEXT      NOT NULL,
+    result     TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
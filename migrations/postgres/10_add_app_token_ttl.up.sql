@@ -0,0 +1,2 @@
+ALTER TABLE apps
+    ADD COLUMN token_ttl_seconds INTEGER NOT NULL DEFAULT 0;
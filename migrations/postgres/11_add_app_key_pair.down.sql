@@ -0,0 +1,3 @@
+ALTER TABLE apps
+    DROP COLUMN public_key,
+    DROP COLUMN private_key;
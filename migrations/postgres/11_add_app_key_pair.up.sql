@@ -0,0 +1,3 @@
+ALTER TABLE apps
+    ADD COLUMN private_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN public_key  TEXT NOT NULL DEFAULT '';
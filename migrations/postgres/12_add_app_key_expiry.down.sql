@@ -0,0 +1,2 @@
+ALTER TABLE apps DROP COLUMN secret_expires_at;
+ALTER TABLE app_keys DROP COLUMN expires_at;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_username;
+ALTER TABLE users DROP COLUMN username;
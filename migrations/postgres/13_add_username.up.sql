@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN username TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS apps;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS users
+(
+    id        BIGSERIAL PRIMARY KEY,
+    email     TEXT  NOT NULL UNIQUE,
+    pass_hash BYTEA NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS apps
+(
+    id     SERIAL PRIMARY KEY,
+    name   TEXT NOT NULL UNIQUE,
+    secret TEXT NOT NULL UNIQUE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens
+(
+    token      TEXT PRIMARY KEY,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    app_id     INTEGER     NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked    BOOLEAN     NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
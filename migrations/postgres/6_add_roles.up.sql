@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS roles
+(
+    id   SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id    INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission TEXT    NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id BIGINT  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name)
+VALUES ('admin');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, '*'
+FROM roles
+WHERE name = 'admin';
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id
+FROM users u,
+     roles r
+WHERE u.is_admin
+  AND r.name = 'admin';
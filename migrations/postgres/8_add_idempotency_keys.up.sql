@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    key        TEXT PRIMARY KEY,
+    email      TEXT        NOT NULL,
+    user_id    BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    type       TEXT        NOT NULL,
+    user_id    BIGINT,
+    email      TEXT        NOT NULL,
+    source_ip  TEXT        NOT NULL,
+    result     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);